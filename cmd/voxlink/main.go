@@ -2,25 +2,53 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
 
 	"github.com/gordonklaus/portaudio"
+	"github.com/pion/webrtc/v4"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 
+	"voxlink/internal/auth"
+	"voxlink/internal/config"
+	"voxlink/internal/metrics"
+	"voxlink/internal/origin"
+	"voxlink/internal/redisdir"
+	"voxlink/internal/roomstore"
 	"voxlink/internal/sfu"
 	"voxlink/internal/signaling"
+	"voxlink/internal/tlsutil"
 	"voxlink/internal/web"
 )
 
 func main() {
-	addr := flag.String("addr", ":8080", "HTTP listen address")
-	flag.Parse()
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(configCommand(os.Args[2:]))
+	}
 
-	logger, _ := zap.NewDevelopment()
+	cfg, err := config.Load(os.Args[0], os.Args[1:], os.Environ())
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	logger, err := newLogger(cfg.Log)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 	defer logger.Sync()
 	sugar := logger.Sugar()
 
@@ -29,33 +57,322 @@ func main() {
 	}
 	defer portaudio.Terminate()
 
-	sfuEngine := sfu.New()
+	directory, node, closeDirectory, err := roomDirectory(cfg.Cluster)
+	if err != nil {
+		sugar.Fatalw("room directory", "err", err)
+	}
+	defer closeDirectory()
+
+	sfuCfg := sfu.Config{
+		GracePeriod: cfg.SFU.GracePeriod.Duration,
+		GCInterval:  cfg.SFU.GCInterval.Duration,
+		Directory:   directory,
+		Node:        node,
+		CodeLength:  cfg.SFU.CodeLength,
+	}
+	if cfg.SFU.RoomsFile != "" {
+		sfuCfg.Store = roomstore.NewJSONFile(cfg.SFU.RoomsFile)
+	}
+	sfuEngine := sfu.NewWithConfig(sfuCfg)
 	defer sfuEngine.Close()
+	if n, err := sfuEngine.RestoreRooms(context.Background()); err != nil {
+		sugar.Fatalw("restore persistent rooms", "err", err)
+	} else if n > 0 {
+		sugar.Infow("restored persistent rooms", "count", n)
+	}
 
 	webrtcAPI := sfu.NewWebRTCAPI()
-	peerMgr := sfu.NewPeerManager(webrtcAPI)
+	peerMgr := sfu.NewPeerManager(webrtcAPI,
+		sfu.WithStereoAPI(sfu.NewStereoWebRTCAPI()),
+		sfu.WithICEServers(iceServers(cfg.ICE)),
+		sfu.WithDataRateLimit(cfg.SFU.DataRate, cfg.SFU.DataBurst),
+	)
 
-	sigHandler := signaling.NewHandler(sfuEngine, logger, signaling.WithPeerManager(peerMgr))
-	webHandler := web.NewHandler(sfuEngine, nil)
+	origins, err := origin.NewPolicy(cfg.Server.AllowedOrigins)
+	if err != nil {
+		sugar.Fatalw("allowed origins", "err", err)
+	}
+	if cfg.Server.Dev {
+		sugar.Warn("dev mode: accepting requests from any origin")
+		origins = origin.AllowAll()
+	}
+
+	sigOpts := []signaling.HandlerOption{
+		signaling.WithPeerManager(peerMgr),
+		signaling.WithOriginPolicy(origins),
+		signaling.WithStatsInterval(cfg.Server.StatsInterval.Duration),
+	}
+	if cfg.Auth.JoinJWKSFile != "" {
+		ks, err := auth.LoadJWKS(cfg.Auth.JoinJWKSFile)
+		if err != nil {
+			sugar.Fatalw("join token config", "err", err)
+		}
+		verifier := auth.NewJWTVerifier(ks, auth.WithIssuer(cfg.Auth.JoinIssuer), auth.WithAudience(cfg.Auth.JoinAudience))
+		sigOpts = append(sigOpts, signaling.WithJoinVerifier(verifier))
+	}
+	if cfg.Cluster.RelaySecret != "" {
+		sigOpts = append(sigOpts, signaling.WithRelaySecret(cfg.Cluster.RelaySecret))
+	}
+	if l := cfg.Server.Limits; l.Enabled {
+		limits := signaling.DefaultLimits()
+		limits.MaxRoomsPerIP = l.MaxRoomsPerIP
+		limits.BanThreshold = l.BanThreshold
+		limits.BanWindow = l.BanWindow.Duration
+		limits.BanDuration = l.BanDuration.Duration
+		limits.TrustedProxies, _ = l.Proxies() // checked by Validate
+		sigOpts = append(sigOpts, signaling.WithLimits(limits))
+	}
+	if cfg.Cluster.Cascade {
+		sigOpts = append(sigOpts, signaling.WithCascade())
+	}
+	sigHandler := signaling.NewHandler(sfuEngine, logger, sigOpts...)
+	webOpts := []web.Option{web.WithStatsProvider(sigHandler), web.WithAdmin(sigHandler), web.WithOriginPolicy(origins)}
+	authenticator, err := buildAuthenticator(cfg.Auth)
+	if err != nil {
+		sugar.Fatalw("auth config", "err", err)
+	}
+	if authenticator != nil {
+		webOpts = append(webOpts, web.WithAuthenticator(authenticator))
+	} else {
+		sugar.Warn("no API credentials configured: /api is unauthenticated and /api/admin is disabled")
+	}
+	webHandler := web.NewHandler(sfuEngine, nil, webOpts...)
 
 	mux := http.NewServeMux()
 	mux.Handle("/ws", sigHandler)
+	mux.Handle("/metrics", metrics.Handler())
 	mux.Handle("/", webHandler)
 
-	server := &http.Server{Addr: *addr, Handler: mux}
+	server := &http.Server{Addr: cfg.Server.Addr, Handler: mux}
 
-	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
 
+	certs, err := loadCertificates(cfg.Server.TLS)
+	if err != nil {
+		sugar.Fatalw("tls config", "err", err)
+	}
+	scheme := "http"
+	var redirectServer *http.Server
+	if certs != nil {
+		scheme = "https"
+		server.TLSConfig = certs.TLSConfig()
+		go certs.Watch(ctx, tlsutil.DefaultWatchInterval, func(err error) {
+			sugar.Warnw("certificate reload failed", "err", err)
+		})
+		if cfg.Server.TLS.HTTPRedirect != "" {
+			redirectServer = &http.Server{Addr: cfg.Server.TLS.HTTPRedirect, Handler: tlsutil.RedirectHandler(cfg.Server.Addr)}
+			go func() {
+				if err := redirectServer.ListenAndServe(); err != http.ErrServerClosed {
+					sugar.Fatalw("redirect server error", "err", err)
+				}
+			}()
+		}
+	}
+
 	go func() {
-		sugar.Infow("VoxLink starting", "addr", *addr)
-		fmt.Fprintf(os.Stderr, "\n  Open http://localhost%s in your browser\n\n", *addr)
-		if err := server.ListenAndServe(); err != http.ErrServerClosed {
+		sugar.Infow("VoxLink starting", "addr", cfg.Server.Addr, "tls", certs != nil)
+		fmt.Fprintf(os.Stderr, "\n  Open %s://localhost%s in your browser\n\n", scheme, cfg.Server.Addr)
+		var err error
+		if certs != nil {
+			err = server.ListenAndServeTLS("", "")
+		} else {
+			err = server.ListenAndServe()
+		}
+		if err != http.ErrServerClosed {
 			sugar.Fatalw("server error", "err", err)
 		}
 	}()
 
 	<-ctx.Done()
-	sugar.Info("shutting down...")
-	server.Shutdown(context.Background())
+	stop() // a second signal terminates immediately
+	drain := cfg.Server.Shutdown
+	sugar.Infow("shutting down...", "drainTimeout", drain.DrainTimeout.Duration)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), drain.DrainTimeout.Duration)
+	defer cancel()
+
+	// Drain WebSocket clients first: they are hijacked connections that
+	// http.Server.Shutdown does not wait for.
+	err = sigHandler.Shutdown(shutdownCtx, signaling.ShutdownPayload{
+		Reason:       "server shutting down",
+		ReconnectURL: drain.ReconnectURL,
+		RetryAfterMs: drain.RetryAfter.Milliseconds(),
+	})
+	if err != nil {
+		sugar.Warnw("signaling drain incomplete", "err", err)
+	}
+	if redirectServer != nil {
+		redirectServer.Shutdown(shutdownCtx)
+	}
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		sugar.Warnw("http shutdown incomplete", "err", err)
+	}
+}
+
+// loadCertificates returns the configured TLS certificate source, or nil to
+// serve plain HTTP. In self-signed mode it creates or reuses a local CA and
+// issues a certificate for this machine's names plus the configured hosts.
+func loadCertificates(cfg config.TLSConfig) (*tlsutil.CertReloader, error) {
+	switch {
+	case cfg.Cert != "":
+		return tlsutil.NewCertReloader(cfg.Cert, cfg.Key)
+	case cfg.SelfSigned:
+		dir := cfg.Dir
+		if dir == "" {
+			configDir, err := os.UserConfigDir()
+			if err != nil {
+				return nil, fmt.Errorf("locate tls dir: %w", err)
+			}
+			dir = filepath.Join(configDir, "voxlink", "tls")
+		}
+		hosts := append(tlsutil.DefaultHosts(), cfg.Hosts...)
+		certFile, keyFile, err := tlsutil.EnsureSelfSigned(dir, hosts)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(os.Stderr, "\n  Using a self-signed certificate. To avoid browser warnings, trust\n  the local CA on each device: %s\n", filepath.Join(dir, tlsutil.CAFile))
+		return tlsutil.NewCertReloader(certFile, keyFile)
+	default:
+		return nil, nil
+	}
+}
+
+// buildAuthenticator combines the configured API keys and JWT key set into a
+// single authenticator. It returns nil if no credentials are configured.
+func buildAuthenticator(cfg config.AuthConfig) (auth.Authenticator, error) {
+	var chain []auth.Authenticator
+
+	var keys []auth.APIKey
+	if cfg.AdminToken != "" {
+		keys = append(keys, auth.APIKey{Name: "admin-token", Key: cfg.AdminToken, Scopes: auth.AllScopes})
+	}
+	if cfg.APIKeysFile != "" {
+		fileKeys, err := auth.LoadAPIKeys(cfg.APIKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, fileKeys...)
+	}
+	if len(keys) > 0 {
+		static, err := auth.NewStaticKeys(keys)
+		if err != nil {
+			return nil, err
+		}
+		chain = append(chain, static)
+	}
+
+	if cfg.JWKSFile != "" {
+		ks, err := auth.LoadJWKS(cfg.JWKSFile)
+		if err != nil {
+			return nil, err
+		}
+		verifier := auth.NewJWTVerifier(ks, auth.WithIssuer(cfg.JWTIssuer), auth.WithAudience(cfg.JWTAudience))
+		chain = append(chain, auth.NewJWTAuthenticator(verifier))
+	}
+
+	if len(chain) == 0 {
+		return nil, nil
+	}
+	return auth.Chain(chain...), nil
+}
+
+// roomDirectory builds the room directory and this node's identity in it. The
+// returned func releases the directory's resources.
+func roomDirectory(cfg config.ClusterConfig) (sfu.RoomDirectory, sfu.Node, func(), error) {
+	node := sfu.Node{ID: cfg.NodeID, URL: cfg.PublicURL}
+	if node.ID == "" {
+		host, err := os.Hostname()
+		if err != nil {
+			return nil, node, nil, fmt.Errorf("node id: %w", err)
+		}
+		node.ID = host
+	}
+	switch cfg.Directory {
+	case "redis":
+		d := redisdir.New(cfg.RedisAddr,
+			redisdir.WithPassword(cfg.RedisPassword),
+			redisdir.WithDB(cfg.RedisDB),
+			redisdir.WithKeyPrefix(cfg.KeyPrefix),
+			redisdir.WithTTL(cfg.OwnershipTTL.Duration),
+		)
+		return d, node, func() { d.Close() }, nil
+	default:
+		return sfu.NewMemoryDirectory(), node, func() {}, nil
+	}
+}
+
+// iceServers converts the ICE settings to the WebRTC server list.
+func iceServers(cfg config.ICEConfig) []webrtc.ICEServer {
+	var servers []webrtc.ICEServer
+	if len(cfg.STUNServers) > 0 {
+		servers = append(servers, webrtc.ICEServer{URLs: cfg.STUNServers})
+	}
+	if len(cfg.TURNServers) > 0 {
+		servers = append(servers, webrtc.ICEServer{
+			URLs:       cfg.TURNServers,
+			Username:   cfg.TURNUsername,
+			Credential: cfg.TURNCredential,
+		})
+	}
+	return servers
+}
+
+// newLogger builds the zap logger and points the slog default, used by the
+// sfu and audio packages, at the same level and format.
+func newLogger(cfg config.LogConfig) (*zap.Logger, error) {
+	level, err := zapcore.ParseLevel(cfg.Level)
+	if err != nil {
+		return nil, err
+	}
+	zcfg := zap.NewDevelopmentConfig()
+	var handler slog.Handler
+	if cfg.Format == "json" {
+		zcfg = zap.NewProductionConfig()
+		handler = slog.NewJSONHandler(os.Stderr, &slog.HandlerOptions{Level: slogLevel(level)})
+	} else {
+		handler = slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slogLevel(level)})
+	}
+	zcfg.Level = zap.NewAtomicLevelAt(level)
+	slog.SetDefault(slog.New(handler))
+	return zcfg.Build()
+}
+
+func slogLevel(l zapcore.Level) slog.Level {
+	switch l {
+	case zapcore.DebugLevel:
+		return slog.LevelDebug
+	case zapcore.WarnLevel:
+		return slog.LevelWarn
+	case zapcore.ErrorLevel:
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// configCommand implements "voxlink config print [yaml|toml|json] [flags]",
+// which prints the effective configuration with secrets redacted.
+func configCommand(args []string) int {
+	if len(args) == 0 || args[0] != "print" {
+		fmt.Fprintln(os.Stderr, "usage: voxlink config print [yaml|toml|json] [flags]")
+		return 2
+	}
+	args = args[1:]
+	format := "yaml"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		format, args = args[0], args[1:]
+	}
+	cfg, err := config.Load("voxlink config print", args, os.Environ())
+	if errors.Is(err, flag.ErrHelp) {
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	if err := cfg.Print(os.Stdout, format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	return 0
 }
@@ -0,0 +1,339 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"voxlink/internal/codec"
+)
+
+// AECConfig holds the echo canceller settings.
+type AECConfig struct {
+	Tail     time.Duration // echo tail modelled by the adaptive filter, after the bulk delay
+	MaxDelay time.Duration // longest speaker-to-microphone delay searched for
+	StepSize float64       // NLMS adaptation rate, between 0 and 2
+}
+
+// DefaultAECConfig returns the VoxLink defaults.
+func DefaultAECConfig() AECConfig {
+	return AECConfig{Tail: 20 * time.Millisecond, MaxDelay: 400 * time.Millisecond, StepSize: 0.5}
+}
+
+const (
+	aecDecimation    = 8    // delay search runs on signals averaged over this many samples
+	aecSearchFrames  = 4    // captured frames correlated per delay search
+	aecSearchEvery   = 5    // frames between delay searches
+	aecMinCorr       = 0.35 // normalized correlation accepted as an echo path
+	aecGeigel        = 0.5  // near/far peak ratio above which the near end is talking
+	aecHangover      = 6    // frames adaptation stays frozen after double talk
+	aecRefBacklog    = 3    // reference frames queued before the oldest are skipped
+	aecActiveLevel   = 100  // RMS below which a signal counts as silence
+	aecRegularize    = 100  // per-tap power added to the NLMS normalization
+	aecDivergeFactor = 4    // output/input energy ratio that resets the filter
+)
+
+// EchoCanceller removes the far-end audio that the speakers play back from
+// captured frames. Playback feeds it each mixed frame with PushReference;
+// the capture pipeline calls Process on each captured frame.
+//
+// A cross-correlation search finds the bulk delay between the two streams
+// and an NLMS adaptive filter models the echo path after that delay. Both
+// pause while the near end is talking (Geigel double-talk detection) so the
+// local voice is not cancelled.
+//
+// Stereo captures share the delay estimate and double-talk state, taken
+// from both channels together, and model each channel's echo path with its
+// own filter.
+//
+// Process consumes exactly one reference frame per captured frame, which
+// keeps the streams aligned as long as both run at the same rate; when the
+// alignment does shift, the next delay search follows it.
+type EchoCanceller struct {
+	refs    *Ring[[codec.FrameSize]int16]
+	enabled atomic.Bool
+	delay   atomic.Int64 // bulk delay in samples; -1 until found
+
+	taps    int
+	margin  int // taps before the estimated delay, to absorb small errors
+	maxLag  int
+	mu      float64
+	far     []float64    // reference history, newest sample last
+	near    []float64    // recent captured samples, channels averaged, for the delay search
+	weights [2][]float64 // adaptive filter per capture channel
+
+	frames    int
+	candidate int // last delay search result; -1 if none
+	hangover  int
+}
+
+// NewEchoCanceller creates an echo canceller; it starts enabled.
+func NewEchoCanceller(cfg AECConfig) *EchoCanceller {
+	taps := max(samplesIn(cfg.Tail), 64)
+	maxLag := samplesIn(cfg.MaxDelay)
+	maxLag -= maxLag % aecDecimation
+	window := aecSearchFrames * codec.FrameSize
+	history := maxLag + max(taps+codec.FrameSize, window)
+	history += (aecDecimation - history%aecDecimation) % aecDecimation
+
+	e := &EchoCanceller{
+		refs:      NewRing[[codec.FrameSize]int16](2 * aecRefBacklog),
+		taps:      taps,
+		margin:    taps / 8,
+		maxLag:    maxLag,
+		mu:        cfg.StepSize,
+		far:       make([]float64, history),
+		near:      make([]float64, window),
+		weights:   [2][]float64{make([]float64, taps), make([]float64, taps)},
+		candidate: -1,
+	}
+	e.enabled.Store(true)
+	e.delay.Store(-1)
+	return e
+}
+
+func samplesIn(d time.Duration) int {
+	return int(d * codec.SampleRate / time.Second)
+}
+
+// SetEnabled turns cancellation on or off. While off, Process leaves frames
+// untouched but keeps following the reference.
+func (e *EchoCanceller) SetEnabled(enabled bool) {
+	e.enabled.Store(enabled)
+}
+
+// Delay returns the estimated speaker-to-microphone delay, if one has been
+// found.
+func (e *EchoCanceller) Delay() (time.Duration, bool) {
+	d := e.delay.Load()
+	if d < 0 {
+		return 0, false
+	}
+	return time.Duration(d) * time.Second / codec.SampleRate, true
+}
+
+// PushReference queues a frame sent to the speakers. It is wait-free and may
+// be called from the playback callback.
+func (e *EchoCanceller) PushReference(frame [codec.FrameSize]int16) {
+	e.refs.Write(frame)
+}
+
+// Process removes echo from a captured frame in place. It must be called
+// from a single goroutine, once per captured frame.
+func (e *EchoCanceller) Process(frame *[codec.FrameSize]int16) {
+	e.process(frame)
+}
+
+// ProcessStereo removes echo from a captured stereo frame in place. The same
+// canceller must not be given both mono and stereo frames.
+func (e *EchoCanceller) ProcessStereo(frame *StereoFrame) {
+	e.process(&frame[0], &frame[1])
+}
+
+func (e *EchoCanceller) process(channels ...*[codec.FrameSize]int16) {
+	e.pushFar()
+	copy(e.near, e.near[codec.FrameSize:])
+	tail := e.near[len(e.near)-codec.FrameSize:]
+	clear(tail)
+	for _, frame := range channels {
+		for i, s := range frame {
+			tail[i] += float64(s) / float64(len(channels))
+		}
+	}
+
+	e.frames++
+	if e.frames%aecSearchEvery == 0 {
+		e.searchDelay()
+	}
+	if !e.enabled.Load() || e.delay.Load() < 0 {
+		return
+	}
+
+	start := max(int(e.delay.Load())-e.margin, 0) // delay of the newest tap
+	// Tap window of sample i: far[first+i : first+i+taps].
+	first := len(e.far) - codec.FrameSize - start - e.taps + 1
+
+	var farPeak, nearPeak float64
+	for _, v := range e.far[first : first+codec.FrameSize+e.taps-1] {
+		farPeak = max(farPeak, math.Abs(v))
+	}
+	for _, frame := range channels {
+		for _, s := range frame {
+			nearPeak = max(nearPeak, math.Abs(float64(s)))
+		}
+	}
+	if nearPeak > aecGeigel*farPeak {
+		e.hangover = aecHangover
+	} else if e.hangover > 0 {
+		e.hangover--
+	}
+	adapt := e.hangover == 0 && farPeak > aecActiveLevel
+
+	for c, frame := range channels {
+		e.cancel(frame, e.weights[c], first, adapt)
+	}
+}
+
+// pushFar appends the next reference frame, or silence if playback has not
+// produced one, to the reference history.
+func (e *EchoCanceller) pushFar() {
+	for e.refs.Len() > aecRefBacklog {
+		e.refs.Read()
+	}
+	var ref [codec.FrameSize]int16
+	if e.refs.Len() > 0 {
+		ref, _ = e.refs.Read()
+	}
+	copy(e.far, e.far[codec.FrameSize:])
+	tail := e.far[len(e.far)-codec.FrameSize:]
+	for i, s := range ref {
+		tail[i] = float64(s)
+	}
+}
+
+// searchDelay cross-correlates the recent captured audio with the reference
+// history and adopts the best lag once two searches agree on it.
+func (e *EchoCanceller) searchDelay() {
+	if rms(e.near) < aecActiveLevel || rms(e.far[len(e.far)-len(e.near)-e.maxLag:]) < aecActiveLevel {
+		return
+	}
+	near := decimate(e.near)
+	far := decimate(e.far)
+
+	var nearEnergy float64
+	for _, v := range near {
+		nearEnergy += v * v
+	}
+	// Lag q aligns near[j] with far[base+j-q].
+	base := len(far) - len(near)
+	maxQ := e.maxLag / aecDecimation
+
+	var farEnergy float64
+	for _, v := range far[base-maxQ : base-maxQ+len(near)] {
+		farEnergy += v * v
+	}
+	best, bestQ := 0.0, -1
+	for q := maxQ; q >= 0; q-- {
+		seg := far[base-q : base-q+len(near)]
+		if q < maxQ {
+			// Slide the energy window one decimated sample later.
+			out := far[base-q-1]
+			in := seg[len(seg)-1]
+			farEnergy += in*in - out*out
+		}
+		if farEnergy <= 0 {
+			continue
+		}
+		var dot float64
+		for j, v := range near {
+			dot += v * seg[j]
+		}
+		if c := math.Abs(dot) / math.Sqrt(nearEnergy*farEnergy); c > best {
+			best, bestQ = c, q
+		}
+	}
+	if best < aecMinCorr {
+		return
+	}
+
+	lag := bestQ * aecDecimation
+	agreed := e.candidate >= 0 && abs(lag-e.candidate) <= 2*aecDecimation
+	e.candidate = lag
+	if !agreed {
+		return
+	}
+	if d := int(e.delay.Load()); d < 0 || abs(lag-d) > e.margin/2 {
+		e.delay.Store(int64(lag))
+		for _, w := range e.weights {
+			clear(w)
+		}
+	}
+}
+
+// cancel subtracts the echo estimate of the filter weights from frame, with
+// the tap window of sample i starting at far[first+i], adapting the filter
+// if adapt is set.
+func (e *EchoCanceller) cancel(frame *[codec.FrameSize]int16, weights []float64, first int, adapt bool) {
+	var nearEnergy float64
+	for _, s := range frame {
+		nearEnergy += float64(s) * float64(s)
+	}
+
+	var out [codec.FrameSize]float64
+	var outEnergy, power float64
+	for _, v := range e.far[first : first+e.taps] {
+		power += v * v
+	}
+	reg := aecRegularize * float64(e.taps)
+	for i := range frame {
+		x := e.far[first+i : first+i+e.taps]
+		if i > 0 {
+			in, old := x[e.taps-1], e.far[first+i-1]
+			power += in*in - old*old
+		}
+		var y float64
+		for k, w := range weights {
+			y += w * x[k]
+		}
+		err := float64(frame[i]) - y
+		out[i] = err
+		outEnergy += err * err
+		if adapt {
+			g := e.mu * err / (max(power, 0) + reg)
+			for k := range weights {
+				weights[k] += g * x[k]
+			}
+		}
+	}
+
+	if outEnergy > aecDivergeFactor*nearEnergy {
+		// The filter diverged; start over rather than amplify.
+		clear(weights)
+		return
+	}
+	for i, v := range out {
+		frame[i] = clampInt16(v)
+	}
+}
+
+// decimate averages consecutive groups of aecDecimation samples.
+func decimate(in []float64) []float64 {
+	out := make([]float64, len(in)/aecDecimation)
+	for i := range out {
+		var sum float64
+		for _, v := range in[i*aecDecimation : (i+1)*aecDecimation] {
+			sum += v
+		}
+		out[i] = sum / aecDecimation
+	}
+	return out
+}
+
+func rms(x []float64) float64 {
+	if len(x) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, v := range x {
+		sum += v * v
+	}
+	return math.Sqrt(sum / float64(len(x)))
+}
+
+func clampInt16(v float64) int16 {
+	switch {
+	case v > math.MaxInt16:
+		return math.MaxInt16
+	case v < math.MinInt16:
+		return math.MinInt16
+	default:
+		return int16(math.Round(v))
+	}
+}
+
+func abs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
@@ -0,0 +1,197 @@
+package audio
+
+import (
+	"flag"
+	"math"
+	"math/rand/v2"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"voxlink/internal/audio/wav"
+	"voxlink/internal/codec"
+)
+
+var update = flag.Bool("update", false, "regenerate the testdata WAV fixtures")
+
+// The echo fixtures are 2.5 s at 48 kHz: far.wav is played to the speakers,
+// mic.wav is captured and holds far.wav through a 120 ms echo path plus, from
+// 1.5 s to 2 s, the local talker in near.wav.
+const (
+	echoDelay     = 120 * time.Millisecond
+	fixtureLen    = 5 * codec.SampleRate / 2
+	nearTalkStart = 3 * codec.SampleRate / 2
+	nearTalkEnd   = 2 * codec.SampleRate
+)
+
+func TestEchoCanceller_Fixtures(t *testing.T) {
+	if *update {
+		writeEchoFixtures(t)
+	}
+	far := readFixture(t, "far.wav")
+	near := readFixture(t, "near.wav")
+	mic := readFixture(t, "mic.wav")
+
+	e := NewEchoCanceller(DefaultAECConfig())
+	out := runEchoCanceller(e, far, mic)
+
+	if d, ok := e.Delay(); !ok || (d-echoDelay).Abs() > time.Millisecond {
+		t.Errorf("delay: got %v (found %v), want %v", d, ok, echoDelay)
+	}
+
+	// Echo only, once the filter has had a second to converge.
+	if erle := erle(mic, out, codec.SampleRate, nearTalkStart); erle < 20 {
+		t.Errorf("ERLE before double talk: %.1f dB, want >= 20", erle)
+	}
+	// The local talker must come through while the far end is talking too.
+	residual := make([]int16, len(out))
+	for i := range out {
+		residual[i] = out[i] - near[i]
+	}
+	if snr := erle(near, residual, nearTalkStart, nearTalkEnd); snr < 10 {
+		t.Errorf("near speech to distortion during double talk: %.1f dB, want >= 10", snr)
+	}
+	// Double talk must not have wrecked the filter.
+	if erle := erle(mic, out, nearTalkEnd+codec.SampleRate/10, fixtureLen); erle < 15 {
+		t.Errorf("ERLE after double talk: %.1f dB, want >= 15", erle)
+	}
+}
+
+func TestEchoCanceller_Disabled(t *testing.T) {
+	far := readFixture(t, "far.wav")
+	mic := readFixture(t, "mic.wav")
+
+	e := NewEchoCanceller(DefaultAECConfig())
+	e.SetEnabled(false)
+	out := runEchoCanceller(e, far, mic)
+	for i := range out {
+		if out[i] != mic[i] {
+			t.Fatalf("sample %d changed while disabled", i)
+		}
+	}
+	// The delay is still tracked, so enabling takes effect without a search.
+	if _, ok := e.Delay(); !ok {
+		t.Error("delay not estimated while disabled")
+	}
+}
+
+func TestEchoCanceller_NoReference(t *testing.T) {
+	mic := readFixture(t, "mic.wav")
+
+	e := NewEchoCanceller(DefaultAECConfig())
+	for i := 0; i+codec.FrameSize <= len(mic); i += codec.FrameSize {
+		frame := [codec.FrameSize]int16(mic[i:])
+		e.Process(&frame)
+		if frame != [codec.FrameSize]int16(mic[i:]) {
+			t.Fatalf("frame at %d changed without a reference", i)
+		}
+	}
+}
+
+// runEchoCanceller plays far as the reference and processes mic frame by
+// frame, as Playback and the capture pipeline would.
+func runEchoCanceller(e *EchoCanceller, far, mic []int16) []int16 {
+	out := make([]int16, 0, len(mic))
+	for i := 0; i+codec.FrameSize <= len(mic); i += codec.FrameSize {
+		e.PushReference([codec.FrameSize]int16(far[i:]))
+		frame := [codec.FrameSize]int16(mic[i:])
+		e.Process(&frame)
+		out = append(out, frame[:]...)
+	}
+	return out
+}
+
+// erle returns the ratio of the energy of before to after over [from, to),
+// in dB.
+func erle(before, after []int16, from, to int) float64 {
+	var b, a float64
+	for i := from; i < to; i++ {
+		b += float64(before[i]) * float64(before[i])
+		a += float64(after[i]) * float64(after[i])
+	}
+	return 10 * math.Log10(b/max(a, 1))
+}
+
+func readFixture(t *testing.T, name string) []int16 {
+	t.Helper()
+	f, err := os.Open(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	samples, rate, err := wav.Read(f)
+	if err != nil {
+		t.Fatalf("%s: %v", name, err)
+	}
+	if rate != codec.SampleRate || len(samples) != fixtureLen {
+		t.Fatalf("%s: %d samples at %d Hz", name, len(samples), rate)
+	}
+	return samples
+}
+
+// writeEchoFixtures synthesizes the fixtures: two speech-like talkers, a
+// room echo path and a little microphone noise.
+func writeEchoFixtures(t *testing.T) {
+	rng := rand.New(rand.NewPCG(43, 1))
+	far := synthTalker(rng, 3000, 130, 0, fixtureLen)
+	near := synthTalker(rng, 2500, 210, nearTalkStart, nearTalkEnd)
+
+	// Direct path at the bulk delay, then decaying reflections.
+	path := make([]float64, 400)
+	path[0] = 0.25
+	for i := 1; i < len(path); i++ {
+		path[i] = 0.015 * rng.NormFloat64() * math.Exp(-float64(i)/80)
+	}
+	delay := int(echoDelay * codec.SampleRate / time.Second)
+
+	mic := make([]float64, fixtureLen)
+	for n := range mic {
+		var echo float64
+		for k, h := range path {
+			if i := n - delay - k; i >= 0 {
+				echo += h * far[i]
+			}
+		}
+		mic[n] = echo + near[n] + 10*rng.NormFloat64()
+	}
+
+	for name, signal := range map[string][]float64{"far.wav": far, "near.wav": near, "mic.wav": mic} {
+		samples := make([]int16, len(signal))
+		for i, v := range signal {
+			samples[i] = clampInt16(v)
+		}
+		f, err := os.Create(filepath.Join("testdata", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := wav.Write(f, samples, codec.SampleRate); err != nil {
+			t.Fatal(err)
+		}
+		if err := f.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// synthTalker returns a voiced buzz with a wandering pitch plus breath noise,
+// shaped into 4 Hz syllables and band-limited, active in [from, to).
+func synthTalker(rng *rand.Rand, level, pitch float64, from, to int) []float64 {
+	out := make([]float64, fixtureLen)
+	var phase, lp1, lp2 float64
+	for n := from; n < to; n++ {
+		t := float64(n-from) / codec.SampleRate
+		f0 := pitch * (1 + 0.15*math.Sin(2*math.Pi*0.7*t))
+		phase += f0 / codec.SampleRate
+		phase -= math.Floor(phase)
+		voiced := 2*phase - 1
+		x := 0.6*voiced + 0.4*rng.NormFloat64()
+
+		// Two one-pole low-passes stand in for the vocal tract.
+		lp1 += 0.35 * (x - lp1)
+		lp2 += 0.35 * (lp1 - lp2)
+		envelope := 0.55 + 0.45*math.Sin(2*math.Pi*4*t)
+		out[n] = level * 2 * lp2 * envelope
+	}
+	return out
+}
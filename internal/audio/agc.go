@@ -0,0 +1,164 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+
+	"voxlink/internal/codec"
+)
+
+// AGCConfig holds the automatic gain control settings.
+type AGCConfig struct {
+	TargetLevel  float64       // RMS level speech is brought to, in dBFS
+	MaxGain      float64       // most gain ever applied, in dB
+	Attack       time.Duration // time constant for reducing gain
+	Release      time.Duration // time constant for raising gain
+	GateLevel    float64       // frames quieter than this, in dBFS, are not speech
+	VADThreshold float32       // RNNoise voice probability below which a frame is not speech
+}
+
+// DefaultAGCConfig returns the VoxLink defaults.
+func DefaultAGCConfig() AGCConfig {
+	return AGCConfig{
+		TargetLevel:  -18,
+		MaxGain:      24,
+		Attack:       20 * time.Millisecond,
+		Release:      400 * time.Millisecond,
+		GateLevel:    -55,
+		VADThreshold: 0.5,
+	}
+}
+
+const frameDuration = time.Second * codec.FrameSize / codec.SampleRate
+
+// AGC brings captured speech to a common loudness. It measures each frame's
+// RMS level and moves its gain toward the target, quickly when the speaker
+// gets louder (attack) and slowly when they get quieter (release). Frames the
+// noise gate or the VAD reject as non-speech hold the gain, so pauses are not
+// amplified toward the target. The gain applied to a frame never lets its
+// peak clip.
+type AGC struct {
+	enabled atomic.Bool
+	target  atomic.Uint64 // float64 bits, dBFS
+	maxGain atomic.Uint64 // float64 bits, dB
+
+	attack  float64 // per-frame smoothing coefficients
+	release float64
+	gate    float64
+	vad     float32
+	gain    float64 // current gain, in dB
+}
+
+// NewAGC creates an automatic gain control stage; it starts enabled.
+func NewAGC(cfg AGCConfig) *AGC {
+	a := &AGC{
+		attack:  smoothing(cfg.Attack),
+		release: smoothing(cfg.Release),
+		gate:    cfg.GateLevel,
+		vad:     cfg.VADThreshold,
+	}
+	a.enabled.Store(true)
+	a.SetLevel(cfg.TargetLevel, cfg.MaxGain)
+	return a
+}
+
+// smoothing returns the fraction of the remaining distance a one-pole
+// smoother with time constant tc covers in one frame.
+func smoothing(tc time.Duration) float64 {
+	if tc <= 0 {
+		return 1
+	}
+	return 1 - math.Exp(-float64(frameDuration)/float64(tc))
+}
+
+// SetEnabled turns gain control on or off.
+func (a *AGC) SetEnabled(enabled bool) {
+	a.enabled.Store(enabled)
+}
+
+// SetLevel changes the target level (dBFS) and the maximum gain (dB). It may
+// be called while the pipeline runs.
+func (a *AGC) SetLevel(targetDBFS, maxGainDB float64) {
+	a.target.Store(math.Float64bits(targetDBFS))
+	a.maxGain.Store(math.Float64bits(maxGainDB))
+}
+
+// Gain returns the gain currently applied, in dB. Only the goroutine calling
+// Process may call it.
+func (a *AGC) Gain() float64 {
+	return a.gain
+}
+
+// Process applies gain to a frame in place. vad is the frame's voice
+// probability; pass 1 when no VAD is available. It must be called from a
+// single goroutine.
+func (a *AGC) Process(frame *[codec.FrameSize]int16, vad float32) {
+	a.process(vad, frame)
+}
+
+// ProcessStereo applies gain to a stereo frame in place. Both channels get
+// the same gain, measured on both, so the stereo image does not shift.
+func (a *AGC) ProcessStereo(frame *StereoFrame, vad float32) {
+	a.process(vad, &frame[0], &frame[1])
+}
+
+func (a *AGC) process(vad float32, channels ...*[codec.FrameSize]int16) {
+	if !a.enabled.Load() {
+		return
+	}
+	target := math.Float64frombits(a.target.Load())
+	maxGain := math.Float64frombits(a.maxGain.Load())
+
+	var sum, peak float64
+	for _, frame := range channels {
+		for _, s := range frame {
+			v := float64(s)
+			sum += v * v
+			peak = max(peak, math.Abs(v))
+		}
+	}
+	level := dBFS(math.Sqrt(sum / float64(codec.FrameSize*len(channels))))
+
+	prev := a.gain
+	if level > a.gate && vad >= a.vad {
+		want := min(target-level, maxGain)
+		if want < a.gain {
+			a.gain += (want - a.gain) * a.attack
+		} else {
+			a.gain += (want - a.gain) * a.release
+		}
+	}
+	a.gain = min(a.gain, maxGain)
+	if peak > 0 {
+		// Never push the frame's peak past full scale, not even while
+		// ramping down from the previous gain.
+		ceiling := -dBFS(peak)
+		a.gain = min(a.gain, ceiling)
+		prev = min(prev, ceiling)
+	}
+
+	// Ramp from the previous gain so gain changes do not click.
+	from, to := dbToLinear(prev), dbToLinear(a.gain)
+	if from == 1 && to == 1 {
+		return
+	}
+	step := (to - from) / codec.FrameSize
+	for _, frame := range channels {
+		for i, s := range frame {
+			frame[i] = clampInt16(float64(s) * (from + step*float64(i+1)))
+		}
+	}
+}
+
+// dBFS converts an int16 amplitude to decibels relative to full scale.
+func dBFS(amplitude float64) float64 {
+	if amplitude <= 0 {
+		return math.Inf(-1)
+	}
+	return 20 * math.Log10(amplitude/math.MaxInt16)
+}
+
+func dbToLinear(db float64) float64 {
+	return math.Pow(10, db/20)
+}
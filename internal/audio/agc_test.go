@@ -0,0 +1,134 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"voxlink/internal/codec"
+)
+
+// toneFrames feeds n frames of a 440 Hz tone at the given level through a
+// and returns the level of the last output frame, in dBFS.
+func toneFrames(a *AGC, levelDBFS float64, vad float32, n int) float64 {
+	amp := math.Sqrt2 * math.MaxInt16 * dbToLinear(levelDBFS)
+	var out float64
+	for f := range n {
+		var frame [codec.FrameSize]int16
+		for i := range frame {
+			t := float64(f*codec.FrameSize+i) / codec.SampleRate
+			frame[i] = int16(amp * math.Sin(2*math.Pi*440*t))
+		}
+		a.Process(&frame, vad)
+		out = frameLevel(&frame)
+	}
+	return out
+}
+
+func frameLevel(frame *[codec.FrameSize]int16) float64 {
+	var sum float64
+	for _, s := range frame {
+		sum += float64(s) * float64(s)
+	}
+	return dBFS(math.Sqrt(sum / codec.FrameSize))
+}
+
+func TestAGC_ReachesTarget(t *testing.T) {
+	for _, in := range []float64{-35, -25, -6} {
+		a := NewAGC(DefaultAGCConfig())
+		if out := toneFrames(a, in, 1, 150); math.Abs(out-(-18)) > 1 {
+			t.Errorf("input %v dBFS: output %.1f dBFS, want -18", in, out)
+		}
+	}
+}
+
+func TestAGC_AttackFasterThanRelease(t *testing.T) {
+	a := NewAGC(DefaultAGCConfig())
+	toneFrames(a, -18, 1, 50)
+
+	// A 12 dB jump is mostly absorbed within 100 ms...
+	if out := toneFrames(a, -6, 1, 5); out > -15 {
+		t.Errorf("100 ms after getting louder: %.1f dBFS", out)
+	}
+	toneFrames(a, -6, 1, 100)
+	// ...while a 12 dB drop is made up over several hundred.
+	if out := toneFrames(a, -30, 1, 5); out > -24 {
+		t.Errorf("100 ms after getting quieter: %.1f dBFS, released too fast", out)
+	}
+}
+
+func TestAGC_MaxGain(t *testing.T) {
+	a := NewAGC(DefaultAGCConfig())
+	out := toneFrames(a, -50, 1, 300)
+	if math.Abs(out-(-50+24)) > 0.5 {
+		t.Fatalf("output %.1f dBFS, want -26 (24 dB max gain)", out)
+	}
+
+	a.SetLevel(-18, 6)
+	if out := toneFrames(a, -50, 1, 50); math.Abs(out-(-44)) > 0.5 {
+		t.Fatalf("after SetLevel: output %.1f dBFS, want -44", out)
+	}
+}
+
+func TestAGC_HoldsGainOutsideSpeech(t *testing.T) {
+	a := NewAGC(DefaultAGCConfig())
+	toneFrames(a, -18, 1, 50)
+
+	// Background the VAD rejects is not raised toward the target...
+	toneFrames(a, -40, 0, 200)
+	if g := a.Gain(); math.Abs(g) > 0.5 {
+		t.Errorf("gain after non-speech frames: %.1f dB, want 0", g)
+	}
+	// ...nor is anything below the gate, whatever the VAD says.
+	toneFrames(a, -60, 1, 200)
+	if g := a.Gain(); math.Abs(g) > 0.5 {
+		t.Errorf("gain after gated frames: %.1f dB, want 0", g)
+	}
+}
+
+func TestAGC_NoClipping(t *testing.T) {
+	a := NewAGC(DefaultAGCConfig())
+	toneFrames(a, -40, 1, 300) // gain at its maximum
+
+	// A sudden full-scale frame must not be pushed into clipping.
+	var frame [codec.FrameSize]int16
+	for i := range frame {
+		frame[i] = int16(30000 * math.Sin(2*math.Pi*440*float64(i)/codec.SampleRate))
+	}
+	a.Process(&frame, 1)
+	for i, s := range frame {
+		if s == math.MaxInt16 || s == math.MinInt16 {
+			t.Fatalf("sample %d clipped", i)
+		}
+	}
+}
+
+func TestAGC_Disabled(t *testing.T) {
+	a := NewAGC(DefaultAGCConfig())
+	a.SetEnabled(false)
+	if out := toneFrames(a, -35, 1, 100); math.Abs(out-(-35)) > 0.1 {
+		t.Fatalf("disabled: output %.1f dBFS, want -35", out)
+	}
+}
+
+func TestAGC_StereoLinked(t *testing.T) {
+	a := NewAGC(DefaultAGCConfig())
+
+	// A quiet tone panned hard left: both channels get the same gain.
+	for f := range 200 {
+		var frame StereoFrame
+		for i := range codec.FrameSize {
+			t := float64(f*codec.FrameSize+i) / codec.SampleRate
+			frame[0][i] = int16(800 * math.Sin(2*math.Pi*440*t))
+			frame[1][i] = frame[0][i] / 4
+		}
+		a.ProcessStereo(&frame, 1)
+		if f == 199 {
+			if d := frameLevel(&frame[0]) - frameLevel(&frame[1]); math.Abs(d-20*math.Log10(4)) > 0.1 {
+				t.Fatalf("channel level difference %.2f dB, want 12.04", d)
+			}
+		}
+	}
+	if a.Gain() < 6 {
+		t.Fatalf("gain %.1f dB, want the quiet input raised", a.Gain())
+	}
+}
@@ -9,26 +9,81 @@ import (
 	"voxlink/internal/codec"
 )
 
+// DeviceConfig selects how an audio device is opened. The pipeline always
+// works in codec.SampleRate frames of codec.FrameSize samples; a device
+// running at another rate or buffer size is converted at the edge.
+type DeviceConfig struct {
+	SampleRate      int // 0 uses the device's default rate
+	FramesPerBuffer int // 0 lets PortAudio pick the buffer size
+}
+
+// deviceChunk is the most device frames the capture callback converts at a
+// time when PortAudio picks the buffer size.
+const deviceChunk = 1024
+
 // Capture reads audio from the microphone via PortAudio into a ring buffer.
 type Capture struct {
 	stream *portaudio.Stream
 	buf    *RingBuf
+	stereo *StereoRingBuf // set instead of buf for a stereo capture
+	cfg    DeviceConfig
 	logger *slog.Logger
+
+	// Used by the PortAudio callback only, and sized by prepare so that it
+	// does not allocate.
+	resamplers [2]*Resampler // per channel; nil when the device runs at codec.SampleRate
+	chunk      int           // device frames converted at a time
+	planes     [2][]int16    // deinterleaved device samples of a stereo chunk
+	scratch    [2][]int16    // resampled chunk
+	frame      StereoFrame   // mono captures use the first channel only
+	filled     int
 }
 
 func NewCapture(buf *RingBuf, logger *slog.Logger) (*Capture, error) {
-	return &Capture{buf: buf, logger: logger}, nil
+	return NewCaptureWithConfig(buf, DeviceConfig{}, logger)
+}
+
+// NewCaptureWithConfig creates a capture with custom device settings.
+func NewCaptureWithConfig(buf *RingBuf, cfg DeviceConfig, logger *slog.Logger) (*Capture, error) {
+	return &Capture{buf: buf, cfg: cfg, logger: logger}, nil
+}
+
+// NewStereoCapture creates a capture that opens the input device with two
+// channels and writes stereo frames to buf.
+func NewStereoCapture(buf *StereoRingBuf, cfg DeviceConfig, logger *slog.Logger) (*Capture, error) {
+	return &Capture{stereo: buf, cfg: cfg, logger: logger}, nil
+}
+
+// channels returns the number of channels the capture records.
+func (c *Capture) channels() int {
+	if c.stereo != nil {
+		return 2
+	}
+	return 1
 }
 
 func (c *Capture) Start() error {
-	stream, err := portaudio.OpenDefaultStream(
-		1, 0, float64(codec.SampleRate), codec.FrameSize,
-		func(in []int16) {
-			var frame [codec.FrameSize]int16
-			copy(frame[:], in)
-			c.buf.Write(frame)
-		},
-	)
+	dev, err := portaudio.DefaultInputDevice()
+	if err != nil {
+		return fmt.Errorf("find input device: %w", err)
+	}
+	params := portaudio.LowLatencyParameters(dev, nil)
+	params.Input.Channels = c.channels()
+	rate, err := deviceRate(c.cfg, dev)
+	if err != nil {
+		return err
+	}
+	params.SampleRate = float64(rate)
+	params.FramesPerBuffer = c.cfg.FramesPerBuffer
+
+	if err := c.prepare(rate); err != nil {
+		return err
+	}
+	if rate != codec.SampleRate {
+		c.logger.Info("resampling capture", "device", dev.Name, "rate", rate)
+	}
+
+	stream, err := portaudio.OpenStream(params, c.callback)
 	if err != nil {
 		return fmt.Errorf("open audio stream: %w", err)
 	}
@@ -36,6 +91,80 @@ func (c *Capture) Start() error {
 	return stream.Start()
 }
 
+// prepare sets up the conversion from a device running at rate Hz and
+// allocates the callback's buffers for it.
+func (c *Capture) prepare(rate int) error {
+	c.chunk = c.cfg.FramesPerBuffer
+	if c.chunk <= 0 {
+		c.chunk = deviceChunk
+	}
+	c.resamplers = [2]*Resampler{}
+	c.planes, c.scratch = [2][]int16{}, [2][]int16{}
+	for ch := range c.channels() {
+		if c.stereo != nil {
+			c.planes[ch] = make([]int16, c.chunk)
+		}
+		if rate == codec.SampleRate {
+			continue
+		}
+		r, err := NewResampler(rate, codec.SampleRate)
+		if err != nil {
+			return err
+		}
+		r.Grow(c.chunk)
+		c.resamplers[ch] = r
+		c.scratch[ch] = make([]int16, 0, r.OutputSize(c.chunk))
+	}
+	c.filled = 0
+	return nil
+}
+
+// callback converts the device's buffer to codec.SampleRate, a chunk at a
+// time, and writes each completed frame to the ring buffer. Stereo buffers
+// arrive interleaved.
+func (c *Capture) callback(in []int16) {
+	chans := c.channels()
+	for len(in) >= chans {
+		n := min(len(in)/chans, c.chunk)
+		planes := [2][]int16{in[:n]}
+		if c.stereo != nil {
+			planes = [2][]int16{c.planes[0][:n], c.planes[1][:n]}
+			for i := range n {
+				planes[0][i], planes[1][i] = in[2*i], in[2*i+1]
+			}
+		}
+		in = in[n*chans:]
+		for ch, r := range c.resamplers {
+			if r != nil {
+				planes[ch] = r.Process(planes[ch], c.scratch[ch][:0])
+			}
+		}
+		c.write(planes)
+	}
+}
+
+// write adds converted samples to the frame in progress and writes each
+// completed frame to the ring buffer.
+func (c *Capture) write(planes [2][]int16) {
+	// The channels' resamplers run in step, so the planes are equally long.
+	for off := 0; off < len(planes[0]); {
+		n := copy(c.frame[0][c.filled:], planes[0][off:])
+		if c.stereo != nil {
+			copy(c.frame[1][c.filled:], planes[1][off:off+n])
+		}
+		c.filled += n
+		off += n
+		if c.filled == codec.FrameSize {
+			if c.stereo != nil {
+				c.stereo.Write(c.frame)
+			} else {
+				c.buf.Write(c.frame[0])
+			}
+			c.filled = 0
+		}
+	}
+}
+
 func (c *Capture) Stop() error {
 	if c.stream != nil {
 		c.stream.Stop()
@@ -43,3 +172,15 @@ func (c *Capture) Stop() error {
 	}
 	return nil
 }
+
+// deviceRate returns the sample rate to open dev at.
+func deviceRate(cfg DeviceConfig, dev *portaudio.DeviceInfo) (int, error) {
+	rate := cfg.SampleRate
+	if rate == 0 {
+		rate = int(dev.DefaultSampleRate)
+	}
+	if rate <= 0 {
+		return 0, fmt.Errorf("audio device %q reports no sample rate", dev.Name)
+	}
+	return rate, nil
+}
@@ -0,0 +1,45 @@
+package audio
+
+import (
+	"voxlink/internal/codec"
+	"voxlink/internal/config"
+)
+
+// PipelineConfigFrom returns the pipeline settings of a VoxLink
+// configuration, encoding as enc says. Settings the configuration does not
+// expose keep their defaults. a must have passed config validation.
+func PipelineConfigFrom(a config.AudioConfig, enc config.CodecConfig) PipelineConfig {
+	cfg := DefaultPipelineConfig()
+	cfg.Denoise = a.Denoise
+	cfg.EchoCancel = a.EchoCancel
+	cfg.AutoGain = a.AGC.Enabled
+	cfg.AGC.TargetLevel = a.AGC.TargetLevel
+	cfg.AGC.MaxGain = a.AGC.MaxGain
+	cfg.AGC.Attack = a.AGC.Attack.Duration
+	cfg.AGC.Release = a.AGC.Release.Duration
+	cfg.Transmit.Mode, _ = ParseTransmitMode(a.Transmit.Mode)
+	cfg.Transmit.VADThreshold = float32(a.Transmit.VADThreshold)
+	cfg.Transmit.Hangover = a.Transmit.Hangover.Duration
+	cfg.Transmit.PreRoll = a.Transmit.PreRoll.Duration
+	cfg.Sidetone = a.Sidetone.Enabled
+	cfg.SidetoneLevel = a.Sidetone.Level
+	cfg.Encoder = codec.DefaultEncoderConfig()
+	cfg.Encoder.Bitrate = enc.Bitrate
+	cfg.Encoder.DTX = enc.DTX
+	return cfg
+}
+
+// DeviceConfigFrom returns the settings for opening the capture and playback
+// devices of a VoxLink configuration.
+func DeviceConfigFrom(a config.AudioConfig) DeviceConfig {
+	return DeviceConfig{SampleRate: a.SampleRate, FramesPerBuffer: a.FramesPerBuffer}
+}
+
+// MixerConfigFrom returns the mixer settings of a VoxLink configuration. a
+// must have passed config validation.
+func MixerConfigFrom(a config.AudioConfig) MixerConfig {
+	cfg := DefaultMixerConfig()
+	cfg.Normalize = a.Normalize
+	cfg.Spatial, _ = ParseSpatialMode(a.Spatial)
+	return cfg
+}
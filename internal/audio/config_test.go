@@ -0,0 +1,43 @@
+package audio
+
+import (
+	"testing"
+	"time"
+
+	"voxlink/internal/config"
+)
+
+func TestConfigFrom_DefaultsMatch(t *testing.T) {
+	cfg := config.Default()
+	if got, want := PipelineConfigFrom(cfg.Audio, cfg.Codec), DefaultPipelineConfig(); got != want {
+		t.Errorf("pipeline:\ngot  %+v\nwant %+v", got, want)
+	}
+	if got, want := MixerConfigFrom(cfg.Audio), DefaultMixerConfig(); got != want {
+		t.Errorf("mixer:\ngot  %+v\nwant %+v", got, want)
+	}
+}
+
+func TestConfigFrom(t *testing.T) {
+	cfg := config.Default()
+	cfg.Codec.Bitrate = 32000
+	cfg.Audio.SampleRate = 48000
+	cfg.Audio.Spatial = "binaural"
+	cfg.Audio.AGC.MaxGain = 12
+	cfg.Audio.Transmit.Mode = "vad"
+	cfg.Audio.Transmit.Hangover = config.Duration{Duration: time.Second}
+	cfg.Audio.Sidetone.Enabled = true
+
+	p := PipelineConfigFrom(cfg.Audio, cfg.Codec)
+	if p.Encoder.Bitrate != 32000 || p.AGC.MaxGain != 12 || !p.Sidetone {
+		t.Fatalf("got %+v", p)
+	}
+	if p.Transmit.Mode != TransmitVoice || p.Transmit.Hangover != time.Second {
+		t.Fatalf("transmit: got %+v", p.Transmit)
+	}
+	if m := MixerConfigFrom(cfg.Audio); m.Spatial != SpatialBinaural {
+		t.Fatalf("spatial: got %v", m.Spatial)
+	}
+	if d := DeviceConfigFrom(cfg.Audio); d.SampleRate != 48000 || d.FramesPerBuffer != 0 {
+		t.Fatalf("device: got %+v", d)
+	}
+}
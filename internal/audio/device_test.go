@@ -0,0 +1,186 @@
+package audio
+
+import (
+	"log/slog"
+	"testing"
+
+	"voxlink/internal/codec"
+)
+
+func TestCapture_Callback_Resamples(t *testing.T) {
+	buf := NewRingBuf(64)
+	c, _ := NewCaptureWithConfig(buf, DeviceConfig{SampleRate: 44100}, slog.Default())
+	c.prepare(44100)
+
+	// One second of 44.1 kHz audio in 10 ms device buffers.
+	in := sine(1000, 44100, 441, 8000)
+	for range 100 {
+		c.callback(in)
+	}
+	if got := buf.Len(); got != codec.SampleRate/codec.FrameSize {
+		t.Fatalf("got %d frames, want %d", got, codec.SampleRate/codec.FrameSize)
+	}
+	if c.filled != 0 {
+		t.Fatalf("%d samples left over", c.filled)
+	}
+}
+
+func TestCapture_Callback_OddBuffers(t *testing.T) {
+	buf := NewRingBuf(8)
+	c, _ := NewCaptureWithConfig(buf, DeviceConfig{}, slog.Default())
+	c.prepare(codec.SampleRate)
+
+	// 48 kHz device with 256-sample buffers: frames straddle callbacks.
+	var want []int16
+	for i := range 8 {
+		in := make([]int16, 256)
+		for j := range in {
+			in[j] = int16(i*256 + j)
+		}
+		want = append(want, in...)
+		c.callback(in)
+	}
+	if buf.Len() != 2 {
+		t.Fatalf("got %d frames, want 2", buf.Len())
+	}
+	for f := range 2 {
+		frame, _ := buf.Read()
+		for i, v := range frame {
+			if v != want[f*codec.FrameSize+i] {
+				t.Fatalf("frame %d sample %d: got %d, want %d", f, i, v, want[f*codec.FrameSize+i])
+			}
+		}
+	}
+}
+
+func TestCapture_Callback_NoAllocs(t *testing.T) {
+	buf := NewStereoRingBuf(8)
+	c, _ := NewStereoCapture(buf, DeviceConfig{SampleRate: 44100, FramesPerBuffer: 256}, slog.Default())
+	c.prepare(44100)
+
+	// Buffers larger than the configured size are converted in chunks.
+	in := make([]int16, 2*1000)
+	if n := testing.AllocsPerRun(100, func() { c.callback(in) }); n != 0 {
+		t.Fatalf("callback allocates %v times", n)
+	}
+	if buf.Len() == 0 {
+		t.Fatal("no frame captured")
+	}
+}
+
+func TestPlayback_Callback_Resamples(t *testing.T) {
+	m := NewMixer()
+	m.AddStream("peer-1")
+	p, _ := NewPlaybackWithConfig(m, DeviceConfig{SampleRate: 16000}, slog.Default())
+	p.prepare(16000)
+	e := NewEchoCanceller(DefaultAECConfig())
+	p.SetEchoCanceller(e)
+
+	// One second of 16 kHz output in 512-sample device buffers takes 50
+	// mixed frames, give or take the one converted ahead.
+	out := make([]int16, 512)
+	for range 16000 / 512 {
+		p.callback(out)
+	}
+	stats := e.refs.Stats()
+	if mixed := stats.Len + int(stats.Overruns); mixed < 49 || mixed > 51 {
+		t.Fatalf("mixed %d frames for one second", mixed)
+	}
+	if len(p.pending) >= codec.FrameSize/3 {
+		t.Fatalf("%d samples pending, more than one frame's worth", len(p.pending))
+	}
+}
+
+func TestPlayback_Callback_NoAllocs(t *testing.T) {
+	m := NewMixer()
+	m.AddStream("peer-1")
+	p, _ := NewStereoPlayback(m, DeviceConfig{SampleRate: 44100}, slog.Default())
+	p.prepare(44100)
+
+	out := make([]int16, 2*1000)
+	if n := testing.AllocsPerRun(100, func() { p.callback(out) }); n != 0 {
+		t.Fatalf("callback allocates %v times", n)
+	}
+}
+
+func TestCapture_Callback_Stereo(t *testing.T) {
+	buf := NewStereoRingBuf(8)
+	c, _ := NewStereoCapture(buf, DeviceConfig{}, slog.Default())
+	c.prepare(codec.SampleRate)
+
+	// Interleaved: left counts up, right counts down.
+	in := make([]int16, 2*codec.FrameSize)
+	for i := range codec.FrameSize {
+		in[2*i] = int16(i)
+		in[2*i+1] = int16(-i)
+	}
+	c.callback(in[:700])
+	c.callback(in[700:])
+
+	frame, ok := buf.Read()
+	if !ok {
+		t.Fatal("no frame captured")
+	}
+	for i := range codec.FrameSize {
+		if frame[0][i] != int16(i) || frame[1][i] != int16(-i) {
+			t.Fatalf("sample %d: got L=%d R=%d", i, frame[0][i], frame[1][i])
+		}
+	}
+}
+
+func TestPlayback_Callback_Stereo(t *testing.T) {
+	m := NewMixer()
+	m.AddStream("peer-1")
+	p, _ := NewStereoPlayback(m, DeviceConfig{}, slog.Default())
+	p.prepare(codec.SampleRate)
+	e := NewEchoCanceller(DefaultAECConfig())
+	p.SetEchoCanceller(e)
+
+	var frame StereoFrame
+	for i := range codec.FrameSize {
+		frame[0][i] = 2000
+		frame[1][i] = -1000
+	}
+	m.PushStereoFrame("peer-1", frame)
+
+	out := make([]int16, 2*256)
+	p.callback(out)
+	if out[0] != 2000 || out[1] != -1000 {
+		t.Fatalf("got L=%d R=%d, want interleaved 2000, -1000", out[0], out[1])
+	}
+	// The echo reference is the mono downmix.
+	ref, _ := e.refs.Read()
+	if ref[0] != 500 {
+		t.Fatalf("echo reference %d, want 500", ref[0])
+	}
+}
+
+func TestPlayback_Callback_Sidetone(t *testing.T) {
+	m := NewMixer()
+	m.AddStream("peer-1")
+	p, _ := NewPlaybackWithConfig(m, DeviceConfig{}, slog.Default())
+	p.prepare(codec.SampleRate)
+	e := NewEchoCanceller(DefaultAECConfig())
+	p.SetEchoCanceller(e)
+	s := NewSidetone(0)
+	s.SetEnabled(true)
+	p.SetSidetone(s)
+
+	var far [codec.FrameSize]int16
+	for i := range far {
+		far[i] = 1000
+	}
+	m.PushFrame("peer-1", far)
+	s.gain = 1 // skip the fade-in
+	s.push(&far)
+
+	out := make([]int16, codec.FrameSize)
+	p.callback(out)
+	if out[0] != 2000 {
+		t.Fatalf("got %d, want the mix plus the sidetone", out[0])
+	}
+	// Only the far end is an echo to cancel.
+	if ref, _ := e.refs.Read(); ref[0] != 1000 {
+		t.Fatalf("echo reference %d, want 1000", ref[0])
+	}
+}
@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"math"
+	"time"
+
+	"voxlink/internal/codec"
+)
+
+const (
+	limiterLookahead = codec.SampleRate / 1000 // samples ahead of a peak the gain starts falling (1 ms)
+	limiterTail      = limiterLookahead - 1    // look-ahead values carried into the next frame
+)
+
+// limiter is a look-ahead peak limiter for the float mixing bus. For every
+// sample it works out the gain that would keep it under the ceiling, takes
+// the minimum over the next limiterLookahead samples and smooths that with a
+// moving average of the same length, so the gain ramps down before a peak
+// arrives instead of jumping at it. After the peak the gain recovers with the
+// release time constant.
+//
+// The look-ahead window ends at the end of the frame being mixed, so the
+// limiter adds no latency; a peak in the first samples of a frame can
+// shorten the ramp, but never lets a sample exceed the ceiling.
+//
+// A stereo bus is limited with one gain for both channels, driven by the
+// louder of the two, so the stereo image does not shift.
+type limiter struct {
+	ceiling float64 // largest output magnitude, in int16 units
+	release float64 // per-sample recovery coefficient
+	gain    float64
+	tail    [limiterTail]float64 // look-ahead minima of the previous frame's last samples
+}
+
+func newLimiter(ceilingDBFS float64, release time.Duration) *limiter {
+	l := &limiter{
+		ceiling: math.MaxInt16 * dbToLinear(ceilingDBFS),
+		release: 1 - math.Exp(-float64(time.Second)/(float64(release)*codec.SampleRate)),
+		gain:    1,
+	}
+	for i := range l.tail {
+		l.tail[i] = 1
+	}
+	return l
+}
+
+// process limits bus into out.
+func (l *limiter) process(bus *[codec.FrameSize]float64, out *[codec.FrameSize]int16) {
+	var peak [codec.FrameSize]float64
+	for i, v := range bus {
+		peak[i] = math.Abs(v)
+	}
+	gain := l.gains(&peak)
+	for i, v := range bus {
+		out[i] = clampInt16(v * gain[i])
+	}
+}
+
+// processStereo limits a stereo bus into out.
+func (l *limiter) processStereo(bus *[2][codec.FrameSize]float64, out *StereoFrame) {
+	var peak [codec.FrameSize]float64
+	for i := range peak {
+		peak[i] = max(math.Abs(bus[0][i]), math.Abs(bus[1][i]))
+	}
+	gain := l.gains(&peak)
+	for c := range bus {
+		for i, v := range bus[c] {
+			out[c][i] = clampInt16(v * gain[i])
+		}
+	}
+}
+
+// gains returns the gain for each sample of a frame with the given
+// magnitudes.
+func (l *limiter) gains(peak *[codec.FrameSize]float64) [codec.FrameSize]float64 {
+	var want [codec.FrameSize]float64
+	for i, a := range peak {
+		want[i] = 1
+		if a > l.ceiling {
+			want[i] = l.ceiling / a
+		}
+	}
+
+	// ahead[i] is the smallest gain wanted in the next limiterLookahead
+	// samples, preceded by the previous frame's values.
+	var ahead [limiterTail + codec.FrameSize]float64
+	copy(ahead[:], l.tail[:])
+	for i := range want {
+		m := want[i]
+		for _, w := range want[i+1 : min(i+limiterLookahead, codec.FrameSize)] {
+			m = min(m, w)
+		}
+		ahead[limiterTail+i] = m
+	}
+
+	var sum float64
+	for _, v := range l.tail {
+		sum += v
+	}
+	var gain [codec.FrameSize]float64
+	for i := range want {
+		sum += ahead[limiterTail+i]
+		target := sum / limiterLookahead
+		sum -= ahead[i]
+
+		if target < l.gain {
+			l.gain = target
+		} else {
+			l.gain += (target - l.gain) * l.release
+		}
+		l.gain = min(l.gain, want[i])
+		gain[i] = l.gain
+	}
+
+	copy(l.tail[:], ahead[codec.FrameSize:])
+	return gain
+}
@@ -0,0 +1,6 @@
+package audio
+
+import "voxlink/internal/metrics"
+
+var ringBufDrops = metrics.NewCounter("voxlink_ringbuf_drops_total",
+	"Audio frames dropped because a ring buffer was full.")
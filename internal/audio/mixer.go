@@ -1,27 +1,95 @@
 package audio
 
 import (
+	"cmp"
+	"math"
+	"slices"
 	"sync"
+	"time"
 
 	"voxlink/internal/codec"
 )
 
 // Mixer combines N audio streams into one output frame with per-user volume.
+// Streams are summed on a float bus and a look-ahead limiter brings peaks
+// under the ceiling, so several loud talkers are turned down smoothly instead
+// of being clipped.
+//
+// Streams may be mono or stereo, and the mix read out either way: Mix folds
+// stereo streams down to mono, MixStereo places mono streams around the
+// listener (see SpatialMode) and keeps the image of stereo ones. A mixer
+// should be read out one way only, since the limiter keeps state between
+// frames.
+//
+// Unless given a position with SetPosition, streams are seated around a
+// virtual round table in the order they were added.
 type Mixer struct {
-	mu      sync.Mutex
-	streams map[string]*mixerStream
+	mu        sync.Mutex
+	streams   map[string]*mixerStream
+	limiter   *limiter
+	normalize bool
+	target    float64 // normalization target, dBFS
+	maxNorm   float64 // normalization gain limit, dB
+	levelRate float64 // per-frame coefficient of the stream level estimates
+	spatial   SpatialMode
+	seats     int // streams added so far, for the table order
 }
 
 type mixerStream struct {
 	volume float64
-	frame  [codec.FrameSize]int16
+	frame  StereoFrame // mono frames use the first channel only
+	stereo bool
 	hasNew bool
+	level  float64 // long-term speech level, dBFS
+	norm   float64 // normalization gain applied to the last frame, linear
+
+	spatial spatializer
+}
+
+// MixerConfig holds the mixer settings.
+type MixerConfig struct {
+	Ceiling float64       // limiter ceiling, in dBFS
+	Release time.Duration // how fast the limiter lets go after a peak
+
+	Normalize       bool          // level each stream toward NormalizeTarget; see SetNormalize
+	NormalizeTarget float64       // long-term stream level aimed for, in dBFS
+	NormalizeMax    float64       // most gain or attenuation normalization applies, in dB
+	NormalizeWindow time.Duration // time constant of the stream level estimates
+
+	Spatial SpatialMode // how MixStereo places mono streams; see SetSpatial
 }
 
+// DefaultMixerConfig returns the VoxLink defaults.
+func DefaultMixerConfig() MixerConfig {
+	return MixerConfig{
+		Ceiling:         -1,
+		Release:         50 * time.Millisecond,
+		NormalizeTarget: -20,
+		NormalizeMax:    12,
+		NormalizeWindow: 2 * time.Second,
+		Spatial:         SpatialPan,
+	}
+}
+
+// normalizeGate is the frame level, in dBFS, below which a stream counts as
+// silent and its level estimate is left alone.
+const normalizeGate = -50
+
 // NewMixer creates an empty mixer.
 func NewMixer() *Mixer {
+	return NewMixerWithConfig(DefaultMixerConfig())
+}
+
+// NewMixerWithConfig creates an empty mixer with custom settings.
+func NewMixerWithConfig(cfg MixerConfig) *Mixer {
 	return &Mixer{
-		streams: make(map[string]*mixerStream),
+		streams:   make(map[string]*mixerStream),
+		limiter:   newLimiter(cfg.Ceiling, cfg.Release),
+		normalize: cfg.Normalize,
+		target:    cfg.NormalizeTarget,
+		maxNorm:   cfg.NormalizeMax,
+		levelRate: smoothing(cfg.NormalizeWindow),
+		spatial:   cfg.Spatial,
 	}
 }
 
@@ -29,7 +97,14 @@ func NewMixer() *Mixer {
 func (m *Mixer) AddStream(peerID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
-	m.streams[peerID] = &mixerStream{volume: 1.0}
+	m.streams[peerID] = &mixerStream{
+		volume:  1.0,
+		level:   m.target,
+		norm:    1,
+		spatial: spatializer{seat: m.seats},
+	}
+	m.seats++
+	m.seatStreams()
 }
 
 // RemoveStream unregisters a peer's audio stream.
@@ -37,6 +112,7 @@ func (m *Mixer) RemoveStream(peerID string) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	delete(m.streams, peerID)
+	m.seatStreams()
 }
 
 // SetVolume sets the volume for a peer (0.0 = mute, 1.0 = full).
@@ -48,43 +124,179 @@ func (m *Mixer) SetVolume(peerID string, volume float64) {
 	}
 }
 
+// SetNormalize turns automatic per-stream normalization on or off. When on,
+// each stream is slowly brought toward a common long-term level before the
+// volume is applied, so quiet and loud peers sound alike.
+func (m *Mixer) SetNormalize(enabled bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.normalize = enabled
+}
+
+// SetSpatial selects how the stereo mix places mono streams.
+func (m *Mixer) SetSpatial(mode SpatialMode) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.spatial = mode
+}
+
+// SetPosition places a peer's stream at azimuth degrees clockwise from
+// straight ahead and at distance from the listener, relative to a seat at
+// the virtual table. The stream leaves its seat, and the others close up.
+func (m *Mixer) SetPosition(peerID string, azimuth, distance float64) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.streams[peerID]; ok {
+		s.spatial.pos = Position{Azimuth: azimuth, Distance: distance}
+		s.spatial.placed = true
+		m.seatStreams()
+	}
+}
+
+// Position returns the position of a peer's stream.
+func (m *Mixer) Position(peerID string) (Position, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.streams[peerID]
+	if !ok {
+		return Position{}, false
+	}
+	return s.spatial.pos, true
+}
+
+// seatStreams spreads the streams without a position of their own around
+// the virtual table, in the order they were added.
+func (m *Mixer) seatStreams() {
+	var seated []*mixerStream
+	for _, s := range m.streams {
+		if !s.spatial.placed {
+			seated = append(seated, s)
+		}
+	}
+	slices.SortFunc(seated, func(a, b *mixerStream) int {
+		return cmp.Compare(a.spatial.seat, b.spatial.seat)
+	})
+	for k, s := range seated {
+		s.spatial.pos = Position{Azimuth: tableAzimuth(k, len(seated)), Distance: 1}
+	}
+}
+
 // PushFrame pushes a decoded PCM frame for a peer. Replaces any unpicked frame.
 func (m *Mixer) PushFrame(peerID string, frame [codec.FrameSize]int16) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.streams[peerID]; ok {
+		s.frame[0] = frame
+		s.stereo = false
+		s.hasNew = true
+	}
+}
+
+// PushStereoFrame pushes a decoded stereo PCM frame for a peer. Replaces any
+// unpicked frame.
+func (m *Mixer) PushStereoFrame(peerID string, frame StereoFrame) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	if s, ok := m.streams[peerID]; ok {
 		s.frame = frame
+		s.stereo = true
 		s.hasNew = true
 	}
 }
 
-// Mix combines all pending frames into one output frame, applying volume and clipping.
-// Frames that were not pushed since the last Mix call contribute silence.
+// Mix combines all pending frames into one output frame, applying volume and
+// limiting. Frames that were not pushed since the last Mix call contribute
+// silence.
 func (m *Mixer) Mix() [codec.FrameSize]int16 {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
-	var out [codec.FrameSize]int16
-	for i := 0; i < codec.FrameSize; i++ {
-		var sum int32
-		for _, s := range m.streams {
-			if s.hasNew {
-				sum += int32(float64(s.frame[i]) * s.volume)
-			}
+	var bus [codec.FrameSize]float64
+	for _, s := range m.streams {
+		from, step, ok := m.streamGain(s)
+		if !ok {
+			continue
 		}
-		// Clip to int16 range.
-		if sum > 32767 {
-			sum = 32767
-		} else if sum < -32768 {
-			sum = -32768
+		for i := range bus {
+			v := float64(s.frame[0][i])
+			if s.stereo {
+				v = (v + float64(s.frame[1][i])) / 2
+			}
+			bus[i] += v * (from + step*float64(i+1))
 		}
-		out[i] = int16(sum)
 	}
 
-	// Clear hasNew flags.
+	var out [codec.FrameSize]int16
+	m.limiter.process(&bus, &out)
+	return out
+}
+
+// MixStereo is Mix with stereo output. Mono streams are placed at their
+// positions; stereo streams keep their own image.
+func (m *Mixer) MixStereo() StereoFrame {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var bus [2][codec.FrameSize]float64
 	for _, s := range m.streams {
-		s.hasNew = false
+		from, step, ok := m.streamGain(s)
+		if !ok {
+			continue
+		}
+		if !s.stereo {
+			var in [codec.FrameSize]float64
+			for i, v := range s.frame[0] {
+				in[i] = float64(v) * (from + step*float64(i+1))
+			}
+			s.spatial.render(&in, &bus, m.spatial)
+			continue
+		}
+		for i := range codec.FrameSize {
+			g := from + step*float64(i+1)
+			bus[0][i] += float64(s.frame[0][i]) * g
+			bus[1][i] += float64(s.frame[1][i]) * g
+		}
 	}
 
+	var out StereoFrame
+	m.limiter.processStereo(&bus, &out)
 	return out
 }
+
+// streamGain takes the stream's pending frame, if it has one, and returns
+// the gain for its first sample and the per-sample step after it: the gain
+// ramps from the previous frame's to the new one so changes do not click.
+func (m *Mixer) streamGain(s *mixerStream) (from, step float64, ok bool) {
+	if !s.hasNew {
+		return 0, 0, false
+	}
+	s.hasNew = false
+
+	from, to := s.volume, s.volume
+	if m.normalize {
+		from = s.volume * s.norm
+		s.norm = m.normGain(s)
+		to = s.volume * s.norm
+	}
+	return from, (to - from) / codec.FrameSize, true
+}
+
+// normGain updates the stream's level estimate with its pending frame and
+// returns the gain that brings it to the normalization target.
+func (m *Mixer) normGain(s *mixerStream) float64 {
+	channels := s.frame[:1]
+	if s.stereo {
+		channels = s.frame[:]
+	}
+	var sum float64
+	for _, frame := range channels {
+		for _, v := range frame {
+			sum += float64(v) * float64(v)
+		}
+	}
+	if level := dBFS(math.Sqrt(sum / float64(codec.FrameSize*len(channels)))); level > normalizeGate {
+		s.level += (level - s.level) * m.levelRate
+	}
+	gain := max(min(m.target-s.level, m.maxNorm), -m.maxNorm)
+	return dbToLinear(gain)
+}
@@ -1,6 +1,8 @@
 package audio
 
 import (
+	"fmt"
+	"math"
 	"testing"
 
 	"voxlink/internal/codec"
@@ -41,7 +43,63 @@ func TestMixer_TwoStreams(t *testing.T) {
 	}
 }
 
-func TestMixer_Clipping(t *testing.T) {
+func TestMixer_Stereo(t *testing.T) {
+	cfg := DefaultMixerConfig()
+	cfg.Spatial = SpatialOff
+	m := NewMixerWithConfig(cfg)
+	m.AddStream("mono")
+	m.AddStream("stereo")
+
+	var mono [codec.FrameSize]int16
+	var stereo StereoFrame
+	for i := range mono {
+		mono[i] = 1000
+		stereo[0][i] = 4000
+	}
+	m.PushFrame("mono", mono)
+	m.PushStereoFrame("stereo", stereo)
+
+	// Mono streams sit in the center; stereo streams keep their image.
+	out := m.MixStereo()
+	if out[0][0] != 5000 || out[1][0] != 1000 {
+		t.Fatalf("got L=%d R=%d, want L=5000 R=1000", out[0][0], out[1][0])
+	}
+
+	// A mono mix folds stereo streams down.
+	m.PushFrame("mono", mono)
+	m.PushStereoFrame("stereo", stereo)
+	if down := m.Mix(); down[0] != 3000 {
+		t.Fatalf("downmix: got %d, want 3000", down[0])
+	}
+}
+
+func TestMixer_StereoLimitingLinked(t *testing.T) {
+	m := NewMixer()
+	m.AddStream("peer-1")
+	m.AddStream("peer-2")
+
+	// A clipping left channel must turn the right one down by as much.
+	var frame StereoFrame
+	for i := range codec.FrameSize {
+		frame[0][i] = 30000
+		frame[1][i] = 10000
+	}
+	m.PushStereoFrame("peer-1", frame)
+	m.PushStereoFrame("peer-2", frame)
+	out := m.MixStereo()
+	last := codec.FrameSize - 1
+	if out[0][last] != ceiling {
+		t.Fatalf("left: got %d, want %d", out[0][last], ceiling)
+	}
+	if ratio := float64(out[0][last]) / float64(out[1][last]); math.Abs(ratio-3) > 0.01 {
+		t.Fatalf("left/right ratio %.3f, want 3", ratio)
+	}
+}
+
+// ceiling is the default limiter ceiling, -1 dBFS, in int16 units.
+var ceiling = int16(math.Round(math.MaxInt16 * dbToLinear(-1)))
+
+func TestMixer_Limiting(t *testing.T) {
 	m := NewMixer()
 	m.AddStream("peer-1")
 	m.AddStream("peer-2")
@@ -55,13 +113,15 @@ func TestMixer_Clipping(t *testing.T) {
 	m.PushFrame("peer-2", f2)
 
 	out := m.Mix()
-	// 30000 + 20000 = 50000 > 32767, should clip.
-	if out[0] != 32767 {
-		t.Fatalf("got %d, want 32767 (clipped)", out[0])
+	// 30000 + 20000 = 50000 > 32767, should be limited to the ceiling.
+	for i, v := range out {
+		if v != ceiling {
+			t.Fatalf("sample %d: got %d, want %d (limited)", i, v, ceiling)
+		}
 	}
 }
 
-func TestMixer_NegativeClipping(t *testing.T) {
+func TestMixer_NegativeLimiting(t *testing.T) {
 	m := NewMixer()
 	m.AddStream("peer-1")
 	m.AddStream("peer-2")
@@ -75,8 +135,136 @@ func TestMixer_NegativeClipping(t *testing.T) {
 	m.PushFrame("peer-2", f2)
 
 	out := m.Mix()
-	if out[0] != -32768 {
-		t.Fatalf("got %d, want -32768 (clipped)", out[0])
+	if out[0] != -ceiling {
+		t.Fatalf("got %d, want %d (limited)", out[0], -ceiling)
+	}
+}
+
+// TestMixer_EightLoudSources mixes eight talkers at -3 dBFS each, nearly
+// 15 dB over full scale combined, and checks the output is the bus scaled by
+// a smoothly varying gain: no sample clipped or flattened at the ceiling.
+func TestMixer_EightLoudSources(t *testing.T) {
+	const sources, frames = 8, 50
+	m := NewMixer()
+	for p := range sources {
+		m.AddStream(fmt.Sprint("peer-", p))
+	}
+
+	amp := math.MaxInt16 * dbToLinear(-3)
+	var prevGain float64
+	for f := range frames {
+		var bus [codec.FrameSize]float64
+		for p := range sources {
+			var frame [codec.FrameSize]int16
+			freq := 110 * float64(p+2) * (1 + 0.01*float64(p))
+			for i := range frame {
+				n := float64(f*codec.FrameSize + i)
+				// Syllable-like bursts, out of step between talkers, faded
+				// in over the first frame.
+				env := 0.6 + 0.4*math.Sin(2*math.Pi*(4*n/codec.SampleRate+float64(p)/sources))
+				env *= min(n/codec.FrameSize, 1)
+				frame[i] = int16(amp * env * math.Sin(2*math.Pi*freq*n/codec.SampleRate))
+				bus[i] += float64(frame[i])
+			}
+			m.PushFrame(fmt.Sprint("peer-", p), frame)
+		}
+
+		out := m.Mix()
+		for i, v := range out {
+			if v > ceiling || v < -ceiling {
+				t.Fatalf("frame %d sample %d: %d exceeds the ceiling", f, i, v)
+			}
+			if math.Abs(bus[i]) < 2000 {
+				continue // the gain of small samples is lost in rounding
+			}
+			gain := float64(v) / bus[i]
+			if gain <= 0 || gain > 1 {
+				t.Fatalf("frame %d sample %d: gain %.3f", f, i, gain)
+			}
+			// Hard clipping this mix moves the gain by up to 0.22 between
+			// neighbouring samples; the limiter's gain moves far more slowly.
+			if prevGain != 0 && math.Abs(gain-prevGain) > 0.05 {
+				t.Fatalf("frame %d sample %d: gain jumped from %.3f to %.3f", f, i, prevGain, gain)
+			}
+			prevGain = gain
+		}
+	}
+}
+
+func TestMixer_LimiterRecovers(t *testing.T) {
+	m := NewMixer()
+	m.AddStream("peer-1")
+	m.AddStream("peer-2")
+
+	var loud, quiet [codec.FrameSize]int16
+	for i := range loud {
+		loud[i] = 30000
+		quiet[i] = 5000
+	}
+	m.PushFrame("peer-1", loud)
+	m.PushFrame("peer-2", loud)
+	m.Mix()
+
+	// Well under the ceiling the gain returns to unity a few release times
+	// later.
+	for range 40 {
+		m.PushFrame("peer-1", quiet)
+		m.Mix()
+	}
+	m.PushFrame("peer-1", quiet)
+	if out := m.Mix(); out[0] != 5000 {
+		t.Fatalf("got %d, want 5000 after release", out[0])
+	}
+}
+
+func TestMixer_Normalize(t *testing.T) {
+	cfg := DefaultMixerConfig()
+	cfg.Normalize = true
+	m := NewMixerWithConfig(cfg)
+	m.AddStream("quiet")
+	m.AddStream("loud")
+
+	level := func(peer string, amp float64) float64 {
+		var frame [codec.FrameSize]int16
+		for i := range frame {
+			frame[i] = int16(amp * math.Sin(2*math.Pi*300*float64(i)/codec.SampleRate))
+		}
+		m.PushFrame(peer, frame)
+		out := m.Mix()
+		return frameLevel(&out)
+	}
+
+	// Alternate the talkers for 10 s each so the levels settle.
+	var quiet, loud float64
+	for range 500 {
+		quiet = level("quiet", 1000) // about -33 dBFS
+		loud = level("loud", 10000)  // about -13 dBFS
+	}
+	if math.Abs(quiet-(-20)) > 1.5 || math.Abs(loud-(-20)) > 1.5 {
+		t.Fatalf("levels: quiet %.1f dBFS, loud %.1f dBFS, want both near -20", quiet, loud)
+	}
+
+	// Normalization is bounded: a distant talker is raised by at most 12 dB...
+	m.AddStream("distant")
+	var distant float64
+	for range 500 {
+		distant = level("distant", 300) // about -43 dBFS
+	}
+	if math.Abs(distant-(-43.8+12)) > 0.5 {
+		t.Fatalf("distant: %.1f dBFS, want 12 dB over the input", distant)
+	}
+	// ...and frames under the gate do not move the level at all.
+	m.AddStream("hiss")
+	for range 500 {
+		if got := level("hiss", 100); math.Abs(got-(-53.3)) > 0.5 {
+			t.Fatalf("hiss: %.1f dBFS, want the input level", got)
+		}
+	}
+
+	m.SetNormalize(false)
+	level("quiet", 1000)
+	if got := level("quiet", 1000); math.Abs(got-(-33.3)) > 0.5 {
+		t.Fatalf("normalization off: %.1f dBFS, want the input level", got)
 	}
 }
 
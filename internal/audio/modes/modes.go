@@ -0,0 +1,45 @@
+// Package modes names the audio pipeline's transmit and spatial modes. It
+// has no cgo dependencies, so the configuration and the web API can check
+// mode names without linking the native audio libraries.
+package modes
+
+import "slices"
+
+// Transmit mode names, in the order of audio.TransmitMode.
+const (
+	TransmitContinuous = "continuous" // send every frame
+	TransmitPushToTalk = "ptt"        // send while the talk key is held
+	TransmitVoice      = "vad"        // send while the VAD detects speech
+)
+
+// Spatial mode names, in the order of audio.SpatialMode.
+const (
+	SpatialOff      = "off"      // every stream in the center
+	SpatialPan      = "pan"      // panned, for speakers or headphones
+	SpatialBinaural = "binaural" // rendered binaurally, for headphones
+)
+
+var (
+	transmit = []string{TransmitContinuous, TransmitPushToTalk, TransmitVoice}
+	spatial  = []string{SpatialOff, SpatialPan, SpatialBinaural}
+)
+
+// Transmit returns the transmit mode names.
+func Transmit() []string {
+	return slices.Clone(transmit)
+}
+
+// Spatial returns the spatial mode names.
+func Spatial() []string {
+	return slices.Clone(spatial)
+}
+
+// IsTransmit reports whether name is a transmit mode.
+func IsTransmit(name string) bool {
+	return slices.Contains(transmit, name)
+}
+
+// IsSpatial reports whether name is a spatial mode.
+func IsSpatial(name string) bool {
+	return slices.Contains(spatial, name)
+}
@@ -8,96 +8,271 @@ import (
 	"voxlink/internal/codec"
 )
 
-// Pipeline orchestrates: RingBuf -> RNNoise (2x480) -> Opus Encode -> callback.
+// Pipeline orchestrates: RingBuf -> AEC -> RNNoise (2x480) -> AGC -> Opus Encode ->
+// transmit gate -> callback, with the denoised frames also fed to the
+// sidetone. RNNoise's voice probability drives gain control, the transmit
+// gate and onVAD; with denoising off, an energy detector stands in for it.
+//
+// A stereo pipeline reads a StereoRingBuf and denoises each channel with its
+// own RNNoise state; echo cancellation and gain control treat the channels
+// together, and they are encoded as one stereo Opus stream.
 type Pipeline struct {
-	ringBuf  *RingBuf
-	denoiser *rnnoise.Denoiser
-	encoder  *codec.Encoder
-	logger   *slog.Logger
-	denoise  bool
+	ringBuf   *RingBuf
+	stereo    *StereoRingBuf // set instead of ringBuf for a stereo pipeline
+	echo      *EchoCanceller
+	denoisers [2]*rnnoise.Denoiser // per channel
+	agc       *AGC
+	sidetone  *Sidetone
+	gate      *transmitGate
+	energy    *energyVAD // voice probability when RNNoise gives none
+	encoder   *codec.Encoder
+	logger    *slog.Logger
+	denoise   bool
+}
+
+// PipelineConfig holds the tunable pipeline settings.
+type PipelineConfig struct {
+	Denoise    bool // initial denoise state; see SetDenoise
+	EchoCancel bool // initial echo cancellation state; see SetEchoCancel
+	Echo       AECConfig
+	AutoGain   bool // initial gain control state; see SetAutoGain
+	AGC        AGCConfig
+	Transmit   TransmitConfig
+
+	Sidetone      bool    // initial sidetone state; see SetSidetone
+	SidetoneLevel float64 // sidetone level relative to the capture, in dB
+	Encoder       codec.EncoderConfig
+}
+
+// DefaultPipelineConfig returns the VoxLink defaults.
+func DefaultPipelineConfig() PipelineConfig {
+	return PipelineConfig{
+		Denoise:    true,
+		EchoCancel: true,
+		Echo:       DefaultAECConfig(),
+		AutoGain:   true,
+		AGC:        DefaultAGCConfig(),
+		Transmit:   DefaultTransmitConfig(),
+
+		SidetoneLevel: -20,
+		Encoder:       codec.DefaultEncoderConfig(),
+	}
 }
 
 func NewPipeline(ringBuf *RingBuf, logger *slog.Logger) *Pipeline {
-	p := &Pipeline{ringBuf: ringBuf, logger: logger, denoise: true}
+	return NewPipelineWithConfig(ringBuf, DefaultPipelineConfig(), logger)
+}
+
+// NewPipelineWithConfig creates a pipeline with custom settings.
+func NewPipelineWithConfig(ringBuf *RingBuf, cfg PipelineConfig, logger *slog.Logger) *Pipeline {
+	cfg.Encoder.Channels = 1
+	return newPipeline(&Pipeline{ringBuf: ringBuf}, cfg, logger)
+}
 
-	enc, err := codec.NewEncoder()
+// NewStereoPipeline creates a pipeline for stereo frames. cfg.Encoder is
+// used as given except for its channel count; see
+// codec.DefaultStereoEncoderConfig for settings suited to music.
+func NewStereoPipeline(ringBuf *StereoRingBuf, cfg PipelineConfig, logger *slog.Logger) *Pipeline {
+	cfg.Encoder.Channels = 2
+	return newPipeline(&Pipeline{stereo: ringBuf}, cfg, logger)
+}
+
+func newPipeline(p *Pipeline, cfg PipelineConfig, logger *slog.Logger) *Pipeline {
+	p.logger = logger
+	p.denoise = cfg.Denoise
+	p.echo = NewEchoCanceller(cfg.Echo)
+	p.echo.SetEnabled(cfg.EchoCancel)
+	p.agc = NewAGC(cfg.AGC)
+	p.agc.SetEnabled(cfg.AutoGain)
+	p.gate = newTransmitGate(cfg.Transmit)
+	p.energy = newEnergyVAD()
+	p.sidetone = NewSidetone(cfg.SidetoneLevel)
+	p.sidetone.SetEnabled(cfg.Sidetone)
+
+	enc, err := codec.NewEncoderWithConfig(cfg.Encoder)
 	if err != nil {
 		logger.Error("opus encoder init failed", "err", err)
 		return p
 	}
 	p.encoder = enc
 
-	d, err := rnnoise.New()
-	if err != nil {
-		logger.Warn("RNNoise init failed, denoising disabled", "err", err)
-		p.denoise = false
-	} else {
-		p.denoiser = d
+	for ch := range enc.Channels() {
+		d, err := rnnoise.New()
+		if err != nil {
+			logger.Warn("RNNoise init failed, denoising disabled", "err", err)
+			p.closeDenoisers()
+			p.denoise = false
+			break
+		}
+		p.denoisers[ch] = d
 	}
 
 	return p
 }
 
 func (p *Pipeline) SetDenoise(enabled bool) {
-	p.denoise = enabled && p.denoiser != nil
+	p.denoise = enabled && p.denoisers[0] != nil
+}
+
+// SetEchoCancel turns acoustic echo cancellation on or off. It may be called
+// while the pipeline runs.
+func (p *Pipeline) SetEchoCancel(enabled bool) {
+	p.echo.SetEnabled(enabled)
+}
+
+// EchoCanceller returns the pipeline's echo canceller, to be given the
+// far-end reference with Playback.SetEchoCanceller.
+func (p *Pipeline) EchoCanceller() *EchoCanceller {
+	return p.echo
+}
+
+// SetAutoGain turns automatic gain control on or off. It may be called while
+// the pipeline runs.
+func (p *Pipeline) SetAutoGain(enabled bool) {
+	p.agc.SetEnabled(enabled)
+}
+
+// SetAutoGainLevel changes the gain control's target level (dBFS) and maximum
+// gain (dB). It may be called while the pipeline runs.
+func (p *Pipeline) SetAutoGainLevel(targetDBFS, maxGainDB float64) {
+	p.agc.SetLevel(targetDBFS, maxGainDB)
+}
+
+// SetSidetone turns the sidetone on or off. It may be called while the
+// pipeline runs.
+func (p *Pipeline) SetSidetone(enabled bool) {
+	p.sidetone.SetEnabled(enabled)
+}
+
+// SetSidetoneLevel changes the sidetone level, in dB relative to the
+// capture. It may be called while the pipeline runs.
+func (p *Pipeline) SetSidetoneLevel(db float64) {
+	p.sidetone.SetLevel(db)
+}
+
+// Sidetone returns the pipeline's sidetone, to be given to
+// Playback.SetSidetone.
+func (p *Pipeline) Sidetone() *Sidetone {
+	return p.sidetone
+}
+
+// SetMute stops (or resumes) sending captured audio, whatever the transmit
+// mode.
+func (p *Pipeline) SetMute(muted bool) {
+	p.gate.muted.Store(muted)
+}
+
+// SetTransmitMode selects when captured audio is sent. It may be called while
+// the pipeline runs.
+func (p *Pipeline) SetTransmitMode(mode TransmitMode) {
+	p.gate.mode.Store(int32(mode))
+}
+
+// SetPushToTalk reports the talk key being pressed or released; audio is
+// sent while it is held in TransmitPushToTalk mode.
+func (p *Pipeline) SetPushToTalk(pressed bool) {
+	p.gate.talking.Store(pressed)
 }
 
-// Run reads frames from the ring buffer, denoises, encodes, and calls onPacket.
+// Run reads frames from the ring buffer, processes them, and calls onPacket.
 // Blocks until ctx is cancelled.
 func (p *Pipeline) Run(ctx context.Context, onPacket func([]byte), onVAD func(float32)) {
-	var (
-		floatIn  [rnnoise.FrameSize]float32
-		floatOut [rnnoise.FrameSize]float32
-	)
-
+	var notify <-chan struct{}
+	if p.stereo != nil {
+		notify = p.stereo.Notify()
+	} else {
+		notify = p.ringBuf.Notify()
+	}
 	for {
 		// Wait for a frame or context cancellation — no busy loop.
 		select {
 		case <-ctx.Done():
 			return
-		case <-p.ringBuf.Notify():
+		case <-notify:
 		}
 
-		frame, ok := p.ringBuf.Read()
-		if !ok {
+		// One signal may stand for several frames.
+		if p.stereo != nil {
+			for range p.stereo.Len() {
+				frame, _ := p.stereo.Read()
+				p.process(onPacket, onVAD, &frame[0], &frame[1])
+			}
 			continue
 		}
+		for range p.ringBuf.Len() {
+			frame, _ := p.ringBuf.Read()
+			p.process(onPacket, onVAD, &frame)
+		}
+	}
+}
 
-		var pcm [codec.FrameSize]int16
-
-		if p.denoise && p.denoiser != nil {
-			rnnoise.Int16ToFloat32(frame[:480], floatIn[:])
-			vad1, _ := p.denoiser.ProcessFrame(floatOut[:], floatIn[:])
-			rnnoise.Float32ToInt16(floatOut[:], pcm[:480])
+// process cancels echo in, denoises, levels and encodes one frame, given as
+// one array per channel, and sends it if the transmit gate is open.
+func (p *Pipeline) process(onPacket func([]byte), onVAD func(float32), channels ...*[codec.FrameSize]int16) {
+	var vad float32
 
-			rnnoise.Int16ToFloat32(frame[480:], floatIn[:])
-			vad2, _ := p.denoiser.ProcessFrame(floatOut[:], floatIn[:])
-			rnnoise.Float32ToInt16(floatOut[:], pcm[480:])
+	p.echo.process(channels...)
 
-			if onVAD != nil {
-				onVAD((vad1 + vad2) / 2.0)
-			}
-		} else {
-			pcm = frame
-			if onVAD != nil {
-				onVAD(1.0)
-			}
+	if p.denoise && p.denoisers[0] != nil {
+		// Speech on either channel counts.
+		for ch, frame := range channels {
+			vad = max(vad, p.denoiseFrame(p.denoisers[ch], frame))
 		}
+	} else {
+		vad = p.energy.process(channels...)
+	}
+	if p.sidetone.Enabled() {
+		p.sidetone.push(channels...)
+	}
+	if onVAD != nil {
+		onVAD(vad)
+	}
 
-		if p.encoder == nil {
-			continue
-		}
-		encoded, err := p.encoder.Encode(pcm[:])
-		if err != nil {
-			p.logger.Error("opus encode failed", "err", err)
-			continue
+	p.agc.process(vad, channels...)
+
+	if p.encoder == nil {
+		return
+	}
+	var pcm [2 * codec.FrameSize]int16
+	for ch, frame := range channels {
+		for i, s := range frame {
+			pcm[i*len(channels)+ch] = s
 		}
-		onPacket(encoded)
 	}
+	encoded, err := p.encoder.Encode(pcm[:codec.FrameSize*len(channels)])
+	if err != nil {
+		p.logger.Error("opus encode failed", "err", err)
+		return
+	}
+	p.gate.admit(encoded, vad, onPacket)
+}
+
+// denoiseFrame denoises frame in place as two RNNoise frames and returns its
+// voice probability.
+func (p *Pipeline) denoiseFrame(d *rnnoise.Denoiser, frame *[codec.FrameSize]int16) float32 {
+	var (
+		floatIn  [rnnoise.FrameSize]float32
+		floatOut [rnnoise.FrameSize]float32
+	)
+	rnnoise.Int16ToFloat32(frame[:480], floatIn[:])
+	vad1, _ := d.ProcessFrame(floatOut[:], floatIn[:])
+	rnnoise.Float32ToInt16(floatOut[:], frame[:480])
+
+	rnnoise.Int16ToFloat32(frame[480:], floatIn[:])
+	vad2, _ := d.ProcessFrame(floatOut[:], floatIn[:])
+	rnnoise.Float32ToInt16(floatOut[:], frame[480:])
+	return (vad1 + vad2) / 2.0
 }
 
 func (p *Pipeline) Close() {
-	if p.denoiser != nil {
-		p.denoiser.Close()
+	p.closeDenoisers()
+}
+
+func (p *Pipeline) closeDenoisers() {
+	for ch, d := range p.denoisers {
+		if d != nil {
+			d.Close()
+			p.denoisers[ch] = nil
+		}
 	}
 }
@@ -11,23 +11,79 @@ import (
 
 // Playback writes mixed audio to the speaker via PortAudio.
 type Playback struct {
-	stream *portaudio.Stream
-	mixer  *Mixer
-	logger *slog.Logger
+	stream   *portaudio.Stream
+	mixer    *Mixer
+	echo     *EchoCanceller
+	sidetone *Sidetone
+	stereo   bool
+	cfg      DeviceConfig
+	logger   *slog.Logger
+
+	// Used by the PortAudio callback only, and sized by prepare so that it
+	// does not allocate.
+	resamplers [2]*Resampler // per channel; nil when the device runs at codec.SampleRate
+	scratch    [2][]int16    // resampled channels of a stereo frame
+	converted  []int16       // one frame at the device rate, interleaved
+	pending    []int16       // the part of converted not yet handed to the device
 }
 
 func NewPlayback(mixer *Mixer, logger *slog.Logger) (*Playback, error) {
-	return &Playback{mixer: mixer, logger: logger}, nil
+	return NewPlaybackWithConfig(mixer, DeviceConfig{}, logger)
+}
+
+// NewPlaybackWithConfig creates a playback with custom device settings.
+func NewPlaybackWithConfig(mixer *Mixer, cfg DeviceConfig, logger *slog.Logger) (*Playback, error) {
+	return &Playback{mixer: mixer, cfg: cfg, logger: logger}, nil
+}
+
+// NewStereoPlayback creates a playback that opens the output device with two
+// channels and plays the mixer's stereo mix.
+func NewStereoPlayback(mixer *Mixer, cfg DeviceConfig, logger *slog.Logger) (*Playback, error) {
+	return &Playback{mixer: mixer, stereo: true, cfg: cfg, logger: logger}, nil
+}
+
+// channels returns the number of channels the playback plays.
+func (p *Playback) channels() int {
+	if p.stereo {
+		return 2
+	}
+	return 1
+}
+
+// SetEchoCanceller makes playback feed each mixed frame to e as the echo
+// reference, downmixed to mono. Call it before Start.
+func (p *Playback) SetEchoCanceller(e *EchoCanceller) {
+	p.echo = e
+}
+
+// SetSidetone makes playback mix the local capture from s into its output
+// while s is enabled. Call it before Start.
+func (p *Playback) SetSidetone(s *Sidetone) {
+	p.sidetone = s
 }
 
 func (p *Playback) Start() error {
-	stream, err := portaudio.OpenDefaultStream(
-		0, 1, float64(codec.SampleRate), codec.FrameSize,
-		func(out []int16) {
-			frame := p.mixer.Mix()
-			copy(out, frame[:])
-		},
-	)
+	dev, err := portaudio.DefaultOutputDevice()
+	if err != nil {
+		return fmt.Errorf("find output device: %w", err)
+	}
+	params := portaudio.LowLatencyParameters(nil, dev)
+	params.Output.Channels = p.channels()
+	rate, err := deviceRate(p.cfg, dev)
+	if err != nil {
+		return err
+	}
+	params.SampleRate = float64(rate)
+	params.FramesPerBuffer = p.cfg.FramesPerBuffer
+
+	if err := p.prepare(rate); err != nil {
+		return err
+	}
+	if rate != codec.SampleRate {
+		p.logger.Info("resampling playback", "device", dev.Name, "rate", rate)
+	}
+
+	stream, err := portaudio.OpenStream(params, p.callback)
 	if err != nil {
 		return fmt.Errorf("open audio stream: %w", err)
 	}
@@ -35,6 +91,79 @@ func (p *Playback) Start() error {
 	return stream.Start()
 }
 
+// prepare sets up the conversion to a device running at rate Hz and
+// allocates the callback's buffers for it.
+func (p *Playback) prepare(rate int) error {
+	p.resamplers = [2]*Resampler{}
+	p.scratch = [2][]int16{}
+	n := codec.FrameSize // samples per channel of a converted frame
+	for ch := range p.channels() {
+		if rate == codec.SampleRate {
+			continue
+		}
+		r, err := NewResampler(codec.SampleRate, rate)
+		if err != nil {
+			return err
+		}
+		r.Grow(codec.FrameSize)
+		p.resamplers[ch] = r
+		n = r.OutputSize(codec.FrameSize)
+		if p.stereo {
+			p.scratch[ch] = make([]int16, 0, n)
+		}
+	}
+	p.converted = make([]int16, 0, n*p.channels())
+	p.pending = p.converted
+	return nil
+}
+
+// callback mixes as many frames as the device's buffer needs and converts
+// them to the device rate; what is left over starts the next buffer. Stereo
+// buffers are interleaved.
+func (p *Playback) callback(out []int16) {
+	for len(out) > 0 {
+		if len(p.pending) == 0 {
+			p.pending = p.convert(p.converted[:0])
+		}
+		n := copy(out, p.pending)
+		out, p.pending = out[n:], p.pending[n:]
+	}
+}
+
+// convert mixes the next frame and appends it to dst at the device rate.
+func (p *Playback) convert(dst []int16) []int16 {
+	if !p.stereo {
+		frame := p.mixer.Mix()
+		if p.echo != nil {
+			p.echo.PushReference(frame)
+		}
+		if p.sidetone != nil {
+			p.sidetone.mixInto(&frame)
+		}
+		if p.resamplers[0] != nil {
+			return p.resamplers[0].Process(frame[:], dst)
+		}
+		return append(dst, frame[:]...)
+	}
+
+	frame := p.mixer.MixStereo()
+	if p.echo != nil {
+		p.echo.PushReference(frame.downmix())
+	}
+	if p.sidetone != nil {
+		p.sidetone.mixIntoStereo(&frame)
+	}
+	left, right := frame[0][:], frame[1][:]
+	if p.resamplers[0] != nil {
+		left = p.resamplers[0].Process(left, p.scratch[0][:0])
+		right = p.resamplers[1].Process(right, p.scratch[1][:0])
+	}
+	for i := range left {
+		dst = append(dst, left[i], right[i])
+	}
+	return dst
+}
+
 func (p *Playback) Stop() error {
 	if p.stream != nil {
 		p.stream.Stop()
@@ -0,0 +1,153 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+)
+
+const (
+	resampleZeroCrossings = 16   // sinc lobes on each side of the filter center
+	resampleBeta          = 8.6  // Kaiser window shape, about 90 dB stopband
+	resampleRolloff       = 0.94 // passband edge as a fraction of the lower Nyquist
+	resampleMaxPhases     = 4096 // largest reduced output rate handled
+)
+
+// Resampler converts a stream of 16-bit samples from one sample rate to
+// another with a polyphase windowed-sinc filter: the rate ratio is reduced to
+// up/down, and each output sample is the dot product of the input around its
+// position with one of up precomputed filter phases. The filter passes 94% of
+// the lower of the two Nyquist frequencies and stops what would alias.
+//
+// A Resampler keeps the tail of its input between calls, so a stream can be
+// fed in chunks of any size. It is not safe for concurrent use.
+type Resampler struct {
+	up, down int
+	taps     int
+	phases   [][]float64 // phases[p][k] weighs input[i+k] for output at i+center+p/up
+	buf      []float64   // input not yet fully consumed, oldest first
+	pos      int         // next output position in buf, in units of 1/up input samples
+}
+
+// NewResampler creates a resampler from inRate to outRate Hz.
+func NewResampler(inRate, outRate int) (*Resampler, error) {
+	if inRate <= 0 || outRate <= 0 {
+		return nil, fmt.Errorf("audio: invalid sample rates %d -> %d", inRate, outRate)
+	}
+	g := gcd(inRate, outRate)
+	up, down := outRate/g, inRate/g
+	if up > resampleMaxPhases {
+		return nil, fmt.Errorf("audio: cannot resample %d -> %d Hz", inRate, outRate)
+	}
+
+	// Cutoff relative to the input Nyquist; downsampling also widens the
+	// filter so it spans as many lobes of the narrower sinc.
+	cutoff := resampleRolloff * min(1, float64(up)/float64(down))
+	taps := 2 * int(math.Ceil(resampleZeroCrossings/cutoff))
+	center := taps/2 - 1
+
+	r := &Resampler{up: up, down: down, taps: taps, phases: make([][]float64, up)}
+	for p := range up {
+		h := make([]float64, taps)
+		var sum float64
+		for k := range h {
+			t := float64(center-k) + float64(p)/float64(up) // distance from the output, in input samples
+			h[k] = cutoff * sinc(cutoff*t) * kaiser(t/(float64(taps)/2), resampleBeta)
+			sum += h[k]
+		}
+		// Unity gain at DC for every phase.
+		for k := range h {
+			h[k] /= sum
+		}
+		r.phases[p] = h
+	}
+	r.Reset()
+	return r, nil
+}
+
+// Reset forgets buffered input, as at the start of a new stream.
+func (r *Resampler) Reset() {
+	r.buf = make([]float64, r.taps-1, 4*r.taps)
+	r.pos = 0
+}
+
+// Ratio returns the output samples produced per input sample.
+func (r *Resampler) Ratio() float64 {
+	return float64(r.up) / float64(r.down)
+}
+
+// Grow makes room for Process to take n input samples at a time without
+// allocating, as on a real-time audio thread.
+func (r *Resampler) Grow(n int) {
+	if need := r.taps - 1 + n; cap(r.buf) < need {
+		buf := make([]float64, len(r.buf), need)
+		copy(buf, r.buf)
+		r.buf = buf
+	}
+}
+
+// OutputSize returns the most output samples Process produces from n input
+// samples.
+func (r *Resampler) OutputSize(n int) int {
+	return (n*r.up+r.down-1)/r.down + 1
+}
+
+// Process resamples in and appends the result to out. Each call produces
+// the output samples whose filter window is complete; the rest follow with
+// later input. The stream is delayed by half the filter length.
+func (r *Resampler) Process(in []int16, out []int16) []int16 {
+	for _, s := range in {
+		r.buf = append(r.buf, float64(s))
+	}
+	for {
+		i, p := r.pos/r.up, r.pos%r.up
+		if i+r.taps > len(r.buf) {
+			break
+		}
+		var y float64
+		for k, h := range r.phases[p] {
+			y += h * r.buf[i+k]
+		}
+		out = append(out, clampInt16(y))
+		r.pos += r.down
+	}
+
+	// Drop input no later output can reach.
+	if used := r.pos / r.up; used > 0 {
+		n := copy(r.buf, r.buf[used:])
+		r.buf = r.buf[:n]
+		r.pos -= used * r.up
+	}
+	return out
+}
+
+func sinc(x float64) float64 {
+	if x == 0 {
+		return 1
+	}
+	return math.Sin(math.Pi*x) / (math.Pi * x)
+}
+
+// kaiser is the Kaiser window at x in [-1, 1].
+func kaiser(x, beta float64) float64 {
+	if x <= -1 || x >= 1 {
+		return 0
+	}
+	return besselI0(beta*math.Sqrt(1-x*x)) / besselI0(beta)
+}
+
+// besselI0 is the zeroth-order modified Bessel function of the first kind.
+func besselI0(x float64) float64 {
+	sum, term := 1.0, 1.0
+	for k := 1; term > 1e-12*sum; k++ {
+		term *= (x / (2 * float64(k))) * (x / (2 * float64(k)))
+		sum += term
+	}
+	return sum
+}
+
+func gcd(a, b int) int {
+	for b != 0 {
+		a, b = b, a%b
+	}
+	return a
+}
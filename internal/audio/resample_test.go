@@ -0,0 +1,108 @@
+package audio
+
+import (
+	"math"
+	"slices"
+	"testing"
+)
+
+func sine(freq float64, rate, n int, amp float64) []int16 {
+	out := make([]int16, n)
+	for i := range out {
+		out[i] = int16(amp * math.Sin(2*math.Pi*freq*float64(i)/float64(rate)))
+	}
+	return out
+}
+
+// toneSNR fits a sine of the given frequency to x by least squares and
+// returns the ratio of its energy to what is left over, in dB.
+func toneSNR(x []int16, freq float64, rate int) float64 {
+	var ss, sc, cc, xs, xc float64
+	for i, v := range x {
+		s, c := math.Sincos(2 * math.Pi * freq * float64(i) / float64(rate))
+		ss, sc, cc = ss+s*s, sc+s*c, cc+c*c
+		xs, xc = xs+float64(v)*s, xc+float64(v)*c
+	}
+	det := ss*cc - sc*sc
+	a, b := (xs*cc-xc*sc)/det, (xc*ss-xs*sc)/det
+
+	var signal, noise float64
+	for i, v := range x {
+		s, c := math.Sincos(2 * math.Pi * freq * float64(i) / float64(rate))
+		fit := a*s + b*c
+		signal += fit * fit
+		noise += (float64(v) - fit) * (float64(v) - fit)
+	}
+	return 10 * math.Log10(signal/noise)
+}
+
+func TestResampler_Tone(t *testing.T) {
+	for _, rates := range [][2]int{{44100, 48000}, {48000, 44100}, {16000, 48000}, {48000, 16000}, {48000, 48000}} {
+		in, out := rates[0], rates[1]
+		r, err := NewResampler(in, out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		y := r.Process(sine(1000, in, in, 16000), nil)
+
+		// One second in, one second out: the filter delay is leading silence.
+		if math.Abs(float64(len(y)-out)) > 1 {
+			t.Errorf("%d -> %d: %d samples, want %d", in, out, len(y), out)
+		}
+		// Skip the filter's start-up.
+		if snr := toneSNR(y[out/10:], 1000, out); snr < 70 {
+			t.Errorf("%d -> %d: SNR %.1f dB, want >= 70", in, out, snr)
+		}
+	}
+}
+
+func TestResampler_AntiAliasing(t *testing.T) {
+	r, err := NewResampler(48000, 16000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10 kHz is above the 8 kHz output Nyquist and would alias to 6 kHz.
+	y := r.Process(sine(10000, 48000, 48000, 16000), nil)
+	var peak float64
+	for _, v := range y[1600:] {
+		peak = max(peak, math.Abs(float64(v)))
+	}
+	if atten := 20 * math.Log10(16000/max(peak, 1)); atten < 60 {
+		t.Fatalf("alias attenuated by %.1f dB, want >= 60", atten)
+	}
+}
+
+func TestResampler_Chunks(t *testing.T) {
+	x := sine(440, 44100, 10000, 12000)
+	whole, _ := NewResampler(44100, 48000)
+	want := whole.Process(x, nil)
+
+	chunked, _ := NewResampler(44100, 48000)
+	var got []int16
+	for i, n := 0, 1; i < len(x); i, n = i+n, n*3%997+1 {
+		got = chunked.Process(x[i:min(i+n, len(x))], got)
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("chunked output differs: %d vs %d samples", len(got), len(want))
+	}
+}
+
+func TestNewResampler_Invalid(t *testing.T) {
+	for _, rates := range [][2]int{{0, 48000}, {48000, -1}, {48000, 44057}} {
+		if _, err := NewResampler(rates[0], rates[1]); err == nil {
+			t.Errorf("%d -> %d: no error", rates[0], rates[1])
+		}
+	}
+}
+
+func TestResampler_Grow(t *testing.T) {
+	for _, rates := range [][2]int{{44100, 48000}, {48000, 44100}, {16000, 48000}, {48000, 8000}} {
+		r, _ := NewResampler(rates[0], rates[1])
+		r.Grow(480)
+		in := sine(440, rates[0], 480, 12000)
+		out := make([]int16, 0, r.OutputSize(len(in)))
+		if n := testing.AllocsPerRun(100, func() { out = r.Process(in, out[:0]) }); n != 0 {
+			t.Fatalf("%d -> %d: Process allocates %v times", rates[0], rates[1], n)
+		}
+	}
+}
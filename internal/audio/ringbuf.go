@@ -1,76 +1,189 @@
 package audio
 
 import (
-	"sync"
+	"sync/atomic"
 
 	"voxlink/internal/codec"
 )
 
-// RingBuf is a mutex-protected SPSC ring buffer for audio frames.
-// One goroutine writes (PortAudio callback), one goroutine reads (processing pipeline).
-// On overflow, the oldest frame is dropped. Uses a notify channel so the
-// reader can block instead of busy-looping.
-type RingBuf struct {
-	mu     sync.Mutex
-	frames [][codec.FrameSize]int16
-	cap    int
-	wIdx   int
-	rIdx   int
-	count  int
+// cacheLine separates the producer's and the consumer's indices so they do
+// not share a cache line.
+const cacheLine = 64
+
+// Ring is a single-producer/single-consumer ring buffer. One goroutine writes
+// (the PortAudio callback), one goroutine reads (the processing pipeline).
+// Neither takes a lock or allocates: Write is wait-free, and Read only
+// retries when a concurrent Write drops the item it was about to read. Both
+// signal the Notify channel with a non-blocking send, which may briefly take
+// the channel's internal lock but never waits for the consumer, so Write is
+// safe to call from a real-time audio thread.
+//
+// The producer owns the head index; the consumer and the producer both
+// advance the tail index, with compare-and-swap. When the buffer is full,
+// Write drops the oldest item and counts an overrun, so the buffered items
+// are always the most recent ones. The slots hold one item more than the
+// capacity, so Write never overwrites the slot Read is copying; should the
+// producer lap the whole buffer during a single Read, Write drops the new
+// item instead.
+type Ring[T any] struct {
+	slots []T // size+1 slots
+	size  uint64
+
+	head      atomic.Uint64 // next position to write; stored by the producer only
+	highWater atomic.Uint64 // stored by the producer only
+	overruns  atomic.Uint64
+	_         [cacheLine - 24]byte
+
+	tail      atomic.Uint64 // next position to read
+	reading   atomic.Uint64 // position Read is copying, plus one; 0 if none
+	underruns atomic.Uint64
+	_         [cacheLine - 24]byte
+
 	notify chan struct{} // signaled on each write; capacity 1 to avoid blocking writer
 }
 
+// RingBuf is the ring buffer of captured PCM frames.
+type RingBuf = Ring[[codec.FrameSize]int16]
+
 // NewRingBuf creates a ring buffer with the given capacity (number of frames).
 func NewRingBuf(capacity int) *RingBuf {
-	return &RingBuf{
-		frames: make([][codec.FrameSize]int16, capacity),
-		cap:    capacity,
+	return NewRing[[codec.FrameSize]int16](capacity)
+}
+
+// StereoFrame is a stereo PCM frame, one array per channel: left, right.
+type StereoFrame [2][codec.FrameSize]int16
+
+// StereoRingBuf is the ring buffer of captured stereo PCM frames.
+type StereoRingBuf = Ring[StereoFrame]
+
+// NewStereoRingBuf creates a stereo ring buffer with the given capacity
+// (number of frames).
+func NewStereoRingBuf(capacity int) *StereoRingBuf {
+	return NewRing[StereoFrame](capacity)
+}
+
+// downmix averages the channels of f into one.
+func (f *StereoFrame) downmix() [codec.FrameSize]int16 {
+	var out [codec.FrameSize]int16
+	for i := range out {
+		out[i] = int16((int32(f[0][i]) + int32(f[1][i])) / 2)
+	}
+	return out
+}
+
+// NewRing creates a ring buffer holding up to capacity items.
+func NewRing[T any](capacity int) *Ring[T] {
+	if capacity < 1 {
+		panic("audio: ring buffer capacity must be positive")
+	}
+	return &Ring[T]{
+		slots:  make([]T, capacity+1),
+		size:   uint64(capacity),
 		notify: make(chan struct{}, 1),
 	}
 }
 
-// Write adds a frame to the buffer. If the buffer is full, the oldest frame is dropped.
-// Returns true if the write succeeded without dropping, false if a frame was dropped.
-func (rb *RingBuf) Write(data [codec.FrameSize]int16) bool {
-	rb.mu.Lock()
-	dropped := false
-	if rb.count >= rb.cap {
-		// Drop oldest: advance read index.
-		rb.rIdx = (rb.rIdx + 1) % rb.cap
-		rb.count--
-		dropped = true
+// Write adds an item to the buffer. If the buffer is full, the oldest item is
+// dropped and counted as an overrun. Returns false if an item was dropped.
+// Only the producer may call Write.
+func (r *Ring[T]) Write(v T) bool {
+	head := r.head.Load()
+	ok := true
+	for {
+		tail := r.tail.Load()
+		if head-tail < r.size {
+			break
+		}
+		if r.tail.CompareAndSwap(tail, tail+1) {
+			r.overrun()
+			ok = false
+			break
+		}
+		// Read took an item meanwhile, so there is room now.
 	}
-	rb.frames[rb.wIdx] = data
-	rb.wIdx = (rb.wIdx + 1) % rb.cap
-	rb.count++
-	rb.mu.Unlock()
+	n := uint64(len(r.slots))
+	if rd := r.reading.Load(); rd != 0 && (rd-1)%n == head%n {
+		r.overrun()
+		r.signal()
+		return false
+	}
+	r.slots[head%n] = v
+	r.head.Store(head + 1)
+	if m := head + 1 - r.tail.Load(); m > r.highWater.Load() {
+		r.highWater.Store(m)
+	}
+	r.signal()
+	return ok
+}
 
-	// Non-blocking signal to reader.
-	select {
-	case rb.notify <- struct{}{}:
-	default:
+func (r *Ring[T]) overrun() {
+	r.overruns.Add(1)
+	ringBufDrops.Inc()
+}
+
+// Read retrieves the oldest item from the buffer. Returns the item and true,
+// or the zero value and false if empty, which counts as an underrun. Only the
+// consumer may call Read.
+func (r *Ring[T]) Read() (T, bool) {
+	for {
+		tail := r.tail.Load()
+		if tail == r.head.Load() {
+			r.underruns.Add(1)
+			var zero T
+			return zero, false
+		}
+		r.reading.Store(tail + 1)
+		if !r.tail.CompareAndSwap(tail, tail+1) {
+			r.reading.Store(0) // Write dropped the item; try the next one
+			continue
+		}
+		v := r.slots[tail%uint64(len(r.slots))]
+		r.reading.Store(0)
+		return v, true
 	}
-	return !dropped
 }
 
-// Read retrieves the oldest frame from the buffer.
-// Returns the frame and true, or a zero frame and false if empty.
-func (rb *RingBuf) Read() ([codec.FrameSize]int16, bool) {
-	rb.mu.Lock()
-	defer rb.mu.Unlock()
+// Len returns the number of buffered items. The consumer may read that many
+// without an underrun.
+func (r *Ring[T]) Len() int {
+	tail := r.tail.Load()
+	return int(r.head.Load() - tail)
+}
 
-	if rb.count == 0 {
-		return [codec.FrameSize]int16{}, false
+// Cap returns the capacity of the buffer.
+func (r *Ring[T]) Cap() int { return int(r.size) }
+
+// Notify returns a channel that receives a signal when an item is written.
+// Use this to avoid busy-looping in the consumer. One signal may stand for
+// several writes, so drain Len items per signal.
+func (r *Ring[T]) Notify() <-chan struct{} {
+	return r.notify
+}
+
+func (r *Ring[T]) signal() {
+	select {
+	case r.notify <- struct{}{}:
+	default:
 	}
+}
 
-	data := rb.frames[rb.rIdx]
-	rb.rIdx = (rb.rIdx + 1) % rb.cap
-	rb.count--
-	return data, true
+// RingStats are the counters of a ring buffer.
+type RingStats struct {
+	Len       int
+	Cap       int
+	HighWater int    // most items ever buffered at once
+	Overruns  uint64 // items dropped because the buffer was full
+	Underruns uint64 // reads from an empty buffer
 }
 
-// Notify returns a channel that receives a signal when a frame is written.
-// Use this to avoid busy-looping in the consumer.
-func (rb *RingBuf) Notify() <-chan struct{} {
-	return rb.notify
+// Stats returns a snapshot of the buffer's counters. It may be called from
+// any goroutine.
+func (r *Ring[T]) Stats() RingStats {
+	return RingStats{
+		Len:       r.Len(),
+		Cap:       r.Cap(),
+		HighWater: int(r.highWater.Load()),
+		Overruns:  r.overruns.Load(),
+		Underruns: r.underruns.Load(),
+	}
 }
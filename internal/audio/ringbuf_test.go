@@ -64,12 +64,49 @@ func TestRingBuf_Overflow_DropsOldest(t *testing.T) {
 	}
 }
 
+func TestRing_Stats(t *testing.T) {
+	r := NewRing[int](3)
+	r.Read()
+	for i := range 5 {
+		r.Write(i)
+	}
+	r.Read()
+	r.Write(5)
+
+	want := RingStats{Len: 3, Cap: 3, HighWater: 3, Overruns: 2, Underruns: 1}
+	if got := r.Stats(); got != want {
+		t.Fatalf("got %+v, want %+v", got, want)
+	}
+	for _, want := range []int{3, 4, 5} {
+		if got, _ := r.Read(); got != want {
+			t.Fatalf("read %d, want %d", got, want)
+		}
+	}
+}
+
+func TestRing_Wraparound(t *testing.T) {
+	r := NewRing[int](3)
+	for i := range 100 {
+		r.Write(i)
+		r.Write(-i)
+		if a, _ := r.Read(); a != i {
+			t.Fatalf("round %d: read %d", i, a)
+		}
+		if b, _ := r.Read(); b != -i {
+			t.Fatalf("round %d: read %d", i, b)
+		}
+	}
+	if got := r.Stats(); got.Overruns != 0 || got.HighWater != 2 {
+		t.Fatalf("got %+v", got)
+	}
+}
+
 func TestRingBuf_ConcurrentWriteRead(t *testing.T) {
 	rb := NewRingBuf(4)
 	const numFrames = 10000
 
 	var wg sync.WaitGroup
-	wg.Add(2)
+	wg.Add(1)
 
 	// Producer
 	go func() {
@@ -77,25 +114,43 @@ func TestRingBuf_ConcurrentWriteRead(t *testing.T) {
 		for i := 0; i < numFrames; i++ {
 			var frame [codec.FrameSize]int16
 			frame[0] = int16(i % 32000)
+			frame[codec.FrameSize-1] = frame[0]
 			rb.Write(frame)
 		}
 	}()
 
-	// Consumer
-	readCount := 0
-	go func() {
-		defer wg.Done()
-		for i := 0; i < numFrames*2; i++ { // try more reads than writes
-			if _, ok := rb.Read(); ok {
+	// Consumer: read until the producer is done and the buffer is drained.
+	done := make(chan struct{})
+	go func() { wg.Wait(); close(done) }()
+	readCount, last := 0, int16(-1)
+	for {
+		select {
+		case <-done:
+			for range rb.Len() {
+				rb.Read()
 				readCount++
 			}
+			stats := rb.Stats()
+			if readCount+int(stats.Overruns) != numFrames {
+				t.Fatalf("read %d + dropped %d != produced %d", readCount, stats.Overruns, numFrames)
+			}
+			t.Logf("produced %d, consumed %d (dropped %d)", numFrames, readCount, stats.Overruns)
+			return
+		case <-rb.Notify():
+		}
+		for range rb.Len() {
+			frame, ok := rb.Read()
+			if !ok {
+				t.Fatal("Len promised a frame")
+			}
+			if frame[0] != frame[codec.FrameSize-1] {
+				t.Fatalf("torn frame: %d ... %d", frame[0], frame[codec.FrameSize-1])
+			}
+			if frame[0] <= last {
+				t.Fatalf("frame %d after %d", frame[0], last)
+			}
+			last = frame[0]
+			readCount++
 		}
-	}()
-
-	wg.Wait()
-	// We should have read at least some frames (not all — some may be dropped).
-	if readCount == 0 {
-		t.Fatal("consumer read 0 frames")
 	}
-	t.Logf("produced %d, consumed %d (dropped %d)", numFrames, readCount, numFrames-readCount)
 }
@@ -0,0 +1,117 @@
+package audio
+
+import (
+	"math"
+	"sync/atomic"
+
+	"voxlink/internal/codec"
+)
+
+const (
+	sidetoneQueue   = 4 // frames the ring holds
+	sidetoneBacklog = 1 // frames queued before the oldest are skipped, to bound latency
+)
+
+// Sidetone feeds the processed local capture back to the speakers, so users
+// with closed headphones hear their own voice. The pipeline hands it each
+// frame after echo cancellation and denoising; Playback mixes the newest
+// into its output at the sidetone level, adding only the frame or two the
+// two callbacks are apart.
+//
+// The sidetone is not part of the echo reference: it is meant for
+// headphones, and on speakers it would feed back.
+type Sidetone struct {
+	frames  *Ring[sidetoneFrame]
+	enabled atomic.Bool
+	level   atomic.Uint64 // float64 bits, dB
+
+	gain float64 // linear gain applied to the last frame; playback only
+}
+
+type sidetoneFrame struct {
+	pcm    StereoFrame // mono frames use the first channel only
+	stereo bool
+}
+
+// NewSidetone creates a sidetone path at levelDB relative to the capture;
+// it starts disabled.
+func NewSidetone(levelDB float64) *Sidetone {
+	s := &Sidetone{frames: NewRing[sidetoneFrame](sidetoneQueue)}
+	s.SetLevel(levelDB)
+	return s
+}
+
+// SetEnabled turns the sidetone on or off. It may be called at any time.
+func (s *Sidetone) SetEnabled(enabled bool) {
+	s.enabled.Store(enabled)
+}
+
+// Enabled reports whether the sidetone is on.
+func (s *Sidetone) Enabled() bool {
+	return s.enabled.Load()
+}
+
+// SetLevel sets the sidetone level in dB relative to the capture, normally
+// well below 0. It may be called at any time.
+func (s *Sidetone) SetLevel(db float64) {
+	s.level.Store(math.Float64bits(db))
+}
+
+// push queues a processed capture frame, given as one array per channel.
+// Only the pipeline goroutine may call it.
+func (s *Sidetone) push(channels ...*[codec.FrameSize]int16) {
+	var f sidetoneFrame
+	for ch, frame := range channels {
+		f.pcm[ch] = *frame
+	}
+	f.stereo = len(channels) == 2
+	s.frames.Write(f)
+}
+
+// next returns the newest queued frame, if any, with the gains for its
+// first and last samples. Only the playback callback may call it.
+func (s *Sidetone) next() (f sidetoneFrame, from, step float64, ok bool) {
+	for s.frames.Len() > sidetoneBacklog {
+		s.frames.Read()
+	}
+	f, ok = s.frames.Read()
+	if !ok || !s.enabled.Load() {
+		s.gain = 0
+		return f, 0, 0, false
+	}
+	from = s.gain
+	s.gain = dbToLinear(math.Float64frombits(s.level.Load()))
+	return f, from, (s.gain - from) / codec.FrameSize, true
+}
+
+// mixInto adds the sidetone to a mono output frame.
+func (s *Sidetone) mixInto(out *[codec.FrameSize]int16) {
+	f, from, step, ok := s.next()
+	if !ok {
+		return
+	}
+	in := f.pcm[0]
+	if f.stereo {
+		in = f.pcm.downmix()
+	}
+	for i, v := range in {
+		out[i] = clampInt16(float64(out[i]) + float64(v)*(from+step*float64(i+1)))
+	}
+}
+
+// mixIntoStereo adds the sidetone to a stereo output frame; a mono capture
+// is heard in the center.
+func (s *Sidetone) mixIntoStereo(out *StereoFrame) {
+	f, from, step, ok := s.next()
+	if !ok {
+		return
+	}
+	if !f.stereo {
+		f.pcm[1] = f.pcm[0]
+	}
+	for ch := range out {
+		for i, v := range f.pcm[ch] {
+			out[ch][i] = clampInt16(float64(out[ch][i]) + float64(v)*(from+step*float64(i+1)))
+		}
+	}
+}
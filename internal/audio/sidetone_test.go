@@ -0,0 +1,98 @@
+package audio
+
+import (
+	"math"
+	"testing"
+
+	"voxlink/internal/codec"
+)
+
+func constFrame(v int16) *[codec.FrameSize]int16 {
+	var f [codec.FrameSize]int16
+	for i := range f {
+		f[i] = v
+	}
+	return &f
+}
+
+func TestSidetone_Level(t *testing.T) {
+	s := NewSidetone(-20)
+	s.SetEnabled(true)
+
+	// The first frame fades in, later ones play at the level.
+	for f := range 2 {
+		s.push(constFrame(10000))
+		out := constFrame(500)
+		s.mixInto(out)
+		if f == 0 && out[0] > 510 {
+			t.Fatalf("first sample %d, want a fade-in", out[0])
+		}
+		if out[codec.FrameSize-1] != 1500 {
+			t.Fatalf("frame %d: got %d, want 500 + 1000", f, out[codec.FrameSize-1])
+		}
+	}
+
+	s.SetLevel(-40)
+	s.push(constFrame(10000))
+	s.push(constFrame(10000))
+	out := constFrame(0)
+	s.mixInto(out)
+	if out[codec.FrameSize-1] != 100 {
+		t.Fatalf("after SetLevel: got %d, want 100", out[codec.FrameSize-1])
+	}
+}
+
+func TestSidetone_Disabled(t *testing.T) {
+	s := NewSidetone(-20)
+	s.push(constFrame(10000))
+	out := constFrame(0)
+	s.mixInto(out)
+	if out[codec.FrameSize-1] != 0 {
+		t.Fatalf("disabled sidetone mixed in %d", out[codec.FrameSize-1])
+	}
+}
+
+func TestSidetone_SkipsBacklog(t *testing.T) {
+	s := NewSidetone(0)
+	s.SetEnabled(true)
+	for _, v := range []int16{1000, 2000, 3000} {
+		s.push(constFrame(v))
+	}
+	// The newest frame plays; older ones would only add latency.
+	out := constFrame(0)
+	s.mixInto(out)
+	if out[codec.FrameSize-1] != 3000 {
+		t.Fatalf("got %d, want the newest frame, 3000", out[codec.FrameSize-1])
+	}
+	if s.frames.Len() != 0 {
+		t.Fatalf("%d frames left queued", s.frames.Len())
+	}
+}
+
+func TestSidetone_Stereo(t *testing.T) {
+	s := NewSidetone(0)
+	s.SetEnabled(true)
+
+	// A mono capture is heard in the center of a stereo output.
+	s.push(constFrame(1000))
+	var out StereoFrame
+	s.mixIntoStereo(&out)
+	last := codec.FrameSize - 1
+	if out[0][last] != 1000 || out[1][last] != 1000 {
+		t.Fatalf("mono capture: got L=%d R=%d", out[0][last], out[1][last])
+	}
+
+	// A stereo capture keeps its channels, and is downmixed for mono output.
+	s.push(constFrame(2000), constFrame(-2000))
+	out = StereoFrame{}
+	s.mixIntoStereo(&out)
+	if out[0][last] != 2000 || out[1][last] != -2000 {
+		t.Fatalf("stereo capture: got L=%d R=%d", out[0][last], out[1][last])
+	}
+	s.push(constFrame(2000), constFrame(-1000))
+	mono := constFrame(0)
+	s.mixInto(mono)
+	if math.Abs(float64(mono[last])-500) > 1 {
+		t.Fatalf("downmix: got %d, want 500", mono[last])
+	}
+}
@@ -0,0 +1,221 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+
+	"voxlink/internal/audio/modes"
+	"voxlink/internal/codec"
+)
+
+// SpatialMode selects how the stereo mix places mono streams around the
+// listener.
+type SpatialMode int32
+
+const (
+	SpatialOff      SpatialMode = iota // every stream in the center
+	SpatialPan                         // constant-power panning and distance attenuation
+	SpatialBinaural                    // spherical-head HRTF model, for headphones
+)
+
+var spatialModeNames = [...]string{
+	SpatialOff:      modes.SpatialOff,
+	SpatialPan:      modes.SpatialPan,
+	SpatialBinaural: modes.SpatialBinaural,
+}
+
+func (m SpatialMode) String() string {
+	if m < 0 || int(m) >= len(spatialModeNames) {
+		return fmt.Sprintf("SpatialMode(%d)", int32(m))
+	}
+	return spatialModeNames[m]
+}
+
+// ParseSpatialMode parses a mode name: "off", "pan" or "binaural".
+func ParseSpatialMode(s string) (SpatialMode, error) {
+	for m, name := range spatialModeNames {
+		if s == name {
+			return SpatialMode(m), nil
+		}
+	}
+	return 0, fmt.Errorf("audio: unknown spatial mode %q", s)
+}
+
+// Position places a stream relative to the listener.
+type Position struct {
+	Azimuth  float64 // degrees clockwise from straight ahead: -90 is left, 90 right, 180 behind
+	Distance float64 // 1 is a seat at the virtual table; farther streams are quieter
+}
+
+const (
+	headRadius     = 0.0875 // meters
+	speedOfSound   = 343.0  // meters per second
+	shadowMinAlpha = 0.1    // high-frequency gain of the head shadow at its deepest
+	shadowMinAngle = 150.0  // angle from the ear, in degrees, where the shadow is deepest
+	spatialHistory = 64     // input samples kept for the ear delays; the largest is 32
+)
+
+// spatializer renders one mono stream into the stereo bus at its position.
+//
+// Panning uses the constant-power law, scaled so a centered stream plays at
+// full level in both channels as with SpatialOff. Binaural rendering follows
+// Brown and Duda's spherical-head model of the HRTF: each ear hears the
+// stream delayed by the path around the head (the interaural time
+// difference) and through a one-pole, one-zero head-shadow filter that dulls
+// the far ear (the interaural level difference). It has no pinna cues, so a
+// source behind sounds much like its mirror image in front.
+//
+// Position changes ramp the gains and ear delays over one frame so they do
+// not click.
+type spatializer struct {
+	pos    Position
+	placed bool // set with SetPosition rather than seated at the table
+	seat   int  // join order, for the table layout
+
+	primed  bool       // gains and delays hold a previous frame's values
+	gains   [2]float64 // per-channel gains of the last frame
+	delays  [2]float64 // per-ear delays of the last frame, in samples
+	shadow  [2]headShadow
+	history [spatialHistory]float64 // last input samples of the previous frame
+}
+
+// target returns the channel gains, ear delays and head-shadow coefficients
+// for the stream's position in mode.
+func (sp *spatializer) target(mode SpatialMode) (gains, delays, alphas [2]float64) {
+	if mode == SpatialOff {
+		return [2]float64{1, 1}, delays, [2]float64{1, 1}
+	}
+	g := 1 / max(sp.pos.Distance, 1)
+	az := wrapDegrees(sp.pos.Azimuth)
+	if mode == SpatialBinaural {
+		for ear, earAz := range [2]float64{-90, 90} {
+			theta := math.Abs(wrapDegrees(az - earAz))
+			gains[ear] = g
+			delays[ear] = earDelay(theta)
+			alphas[ear] = shadowAlpha(theta)
+		}
+		return gains, delays, alphas
+	}
+
+	// Panning cannot tell front from back; fold the rear onto the front.
+	if az > 90 {
+		az = 180 - az
+	} else if az < -90 {
+		az = -180 - az
+	}
+	theta := (az + 90) / 180 * math.Pi / 2
+	gains = [2]float64{math.Sqrt2 * math.Cos(theta) * g, math.Sqrt2 * math.Sin(theta) * g}
+	return gains, delays, [2]float64{1, 1}
+}
+
+// render adds in, the stream's frame with its volume applied, to bus.
+func (sp *spatializer) render(in *[codec.FrameSize]float64, bus *[2][codec.FrameSize]float64, mode SpatialMode) {
+	gains, delays, alphas := sp.target(mode)
+	if !sp.primed {
+		sp.gains, sp.delays, sp.primed = gains, delays, true
+	}
+
+	for ch := range bus {
+		gStep := (gains[ch] - sp.gains[ch]) / codec.FrameSize
+		if mode != SpatialBinaural {
+			for i, v := range in {
+				bus[ch][i] += v * (sp.gains[ch] + gStep*float64(i+1))
+			}
+			continue
+		}
+		dStep := (delays[ch] - sp.delays[ch]) / codec.FrameSize
+		shadow := &sp.shadow[ch]
+		shadow.set(alphas[ch])
+		for i := range in {
+			x := sp.delayed(in, i, sp.delays[ch]+dStep*float64(i+1))
+			bus[ch][i] += shadow.process(x) * (sp.gains[ch] + gStep*float64(i+1))
+		}
+	}
+
+	sp.gains, sp.delays = gains, delays
+	copy(sp.history[:], in[codec.FrameSize-spatialHistory:])
+}
+
+// delayed returns the input d samples before in[i], reaching back into the
+// previous frame; fractional delays are interpolated linearly.
+func (sp *spatializer) delayed(in *[codec.FrameSize]float64, i int, d float64) float64 {
+	pos := float64(i) - d
+	j := int(math.Floor(pos))
+	frac := pos - float64(j)
+	if frac == 0 {
+		return sp.sample(in, j)
+	}
+	return (1-frac)*sp.sample(in, j) + frac*sp.sample(in, j+1)
+}
+
+func (sp *spatializer) sample(in *[codec.FrameSize]float64, j int) float64 {
+	if j >= 0 {
+		return in[j]
+	}
+	return sp.history[spatialHistory+j]
+}
+
+// earDelay returns the delay, in samples, of sound arriving at an ear from
+// theta degrees off the ear's axis: the straight-line path for sources on
+// the ear's side of the head, plus the arc around it for the others.
+func earDelay(theta float64) float64 {
+	rad := theta * math.Pi / 180
+	var d float64
+	if rad < math.Pi/2 {
+		d = 1 - math.Cos(rad)
+	} else {
+		d = 1 + rad - math.Pi/2
+	}
+	return d * headRadius / speedOfSound * codec.SampleRate
+}
+
+// shadowAlpha returns the high-frequency gain of the head shadow for a
+// source theta degrees off the ear's axis: 2 (+6 dB) facing the ear, falling
+// to shadowMinAlpha at shadowMinAngle and recovering slightly directly
+// opposite, where sound bends around the head from all sides.
+func shadowAlpha(theta float64) float64 {
+	return (1 + shadowMinAlpha/2) + (1-shadowMinAlpha/2)*math.Cos(theta/shadowMinAngle*math.Pi)
+}
+
+// headShadow is the head-shadow filter of one ear: a one-pole, one-zero
+// shelf with unity gain at DC and alpha at high frequencies, the corner set
+// by the head's size.
+type headShadow struct {
+	b0, b1, a1 float64
+	x1, y1     float64
+}
+
+// set designs the filter for alpha with the bilinear transform of
+// H(s) = (alpha*s + beta) / (s + beta), beta = 2c/a.
+func (h *headShadow) set(alpha float64) {
+	beta := 2 * speedOfSound / headRadius
+	k := 2.0 * codec.SampleRate
+	h.b0 = (beta + alpha*k) / (beta + k)
+	h.b1 = (beta - alpha*k) / (beta + k)
+	h.a1 = (beta - k) / (beta + k)
+}
+
+func (h *headShadow) process(x float64) float64 {
+	y := h.b0*x + h.b1*h.x1 - h.a1*h.y1
+	h.x1, h.y1 = x, y
+	return y
+}
+
+// wrapDegrees maps an angle to (-180, 180].
+func wrapDegrees(deg float64) float64 {
+	deg = math.Mod(deg, 360)
+	if deg > 180 {
+		deg -= 360
+	} else if deg <= -180 {
+		deg += 360
+	}
+	return deg
+}
+
+// tableAzimuth returns the azimuth of seat k (from 0) of n around a round
+// table at whose head the listener sits. Equally spaced seats subtend equal
+// angles from any point on the circle, so the others appear evenly spread
+// across the front, from left to right, none straight to the side.
+func tableAzimuth(k, n int) float64 {
+	return -90 + 180*float64(k+1)/float64(n+1)
+}
@@ -0,0 +1,189 @@
+package audio
+
+import (
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"voxlink/internal/audio/modes"
+	"voxlink/internal/codec"
+)
+
+func newSpatialMixer(mode SpatialMode) *Mixer {
+	cfg := DefaultMixerConfig()
+	cfg.Spatial = mode
+	return NewMixerWithConfig(cfg)
+}
+
+func TestMixer_TableSeats(t *testing.T) {
+	m := NewMixer()
+	for _, id := range []string{"a", "b", "c"} {
+		m.AddStream(id)
+	}
+	want := map[string]float64{"a": -45, "b": 0, "c": 45}
+	for id, az := range want {
+		if pos, _ := m.Position(id); pos.Azimuth != az || pos.Distance != 1 {
+			t.Fatalf("%s: got %+v, want azimuth %v at the table", id, pos, az)
+		}
+	}
+
+	// The others close up when a peer leaves or is placed elsewhere.
+	m.RemoveStream("b")
+	if pos, _ := m.Position("c"); pos.Azimuth != 30 {
+		t.Fatalf("after leave: c at %v, want 30", pos.Azimuth)
+	}
+	m.SetPosition("a", 120, 2)
+	if pos, _ := m.Position("c"); pos.Azimuth != 0 {
+		t.Fatalf("after placing a: c at %v, want 0", pos.Azimuth)
+	}
+	if pos, _ := m.Position("a"); pos != (Position{Azimuth: 120, Distance: 2}) {
+		t.Fatalf("a: got %+v", pos)
+	}
+}
+
+func TestMixer_Pan(t *testing.T) {
+	tests := []struct {
+		azimuth, distance float64
+		left, right       float64 // expected gains
+	}{
+		{0, 1, 1, 1},
+		{-90, 1, math.Sqrt2, 0},
+		{90, 1, 0, math.Sqrt2},
+		{180, 1, 1, 1}, // behind folds onto the center
+		{-135, 1, math.Sqrt2 * math.Cos(math.Pi/8), math.Sqrt2 * math.Sin(math.Pi/8)},
+		{0, 2, 0.5, 0.5},
+		{0, 0.5, 1, 1}, // no boost closer than a seat
+	}
+	for _, tt := range tests {
+		m := newSpatialMixer(SpatialPan)
+		m.AddStream("peer-1")
+		m.SetPosition("peer-1", tt.azimuth, tt.distance)
+
+		var frame [codec.FrameSize]int16
+		for i := range frame {
+			frame[i] = 10000
+		}
+		m.PushFrame("peer-1", frame)
+		out := m.MixStereo()
+		l, r := float64(out[0][0])/10000, float64(out[1][0])/10000
+		if math.Abs(l-tt.left) > 1e-3 || math.Abs(r-tt.right) > 1e-3 {
+			t.Errorf("azimuth %v distance %v: got L=%.3f R=%.3f, want L=%.3f R=%.3f",
+				tt.azimuth, tt.distance, l, r, tt.left, tt.right)
+		}
+	}
+}
+
+func TestMixer_PanRamps(t *testing.T) {
+	m := newSpatialMixer(SpatialPan)
+	m.AddStream("peer-1")
+	m.SetPosition("peer-1", -90, 1)
+
+	var frame [codec.FrameSize]int16
+	for i := range frame {
+		frame[i] = 8000
+	}
+	m.PushFrame("peer-1", frame)
+	m.MixStereo()
+
+	// Jumping from hard left to hard right must not click.
+	m.SetPosition("peer-1", 90, 1)
+	m.PushFrame("peer-1", frame)
+	out := m.MixStereo()
+	for ch := range out {
+		for i := 1; i < codec.FrameSize; i++ {
+			if d := math.Abs(float64(out[ch][i]) - float64(out[ch][i-1])); d > 20 {
+				t.Fatalf("channel %d sample %d: step of %.0f", ch, i, d)
+			}
+		}
+	}
+}
+
+// binauralResponse renders noise from azimuth through a binaural mixer and
+// returns both channels.
+func binauralResponse(azimuth float64, frames int) (left, right []float64) {
+	m := newSpatialMixer(SpatialBinaural)
+	m.AddStream("peer-1")
+	m.SetPosition("peer-1", azimuth, 1)
+
+	rng := rand.New(rand.NewPCG(1, 2))
+	for range frames {
+		var frame [codec.FrameSize]int16
+		for i := range frame {
+			frame[i] = int16(rng.NormFloat64() * 3000)
+		}
+		m.PushFrame("peer-1", frame)
+		out := m.MixStereo()
+		for i := range codec.FrameSize {
+			left = append(left, float64(out[0][i]))
+			right = append(right, float64(out[1][i]))
+		}
+	}
+	return left, right
+}
+
+func TestMixer_BinauralTimeDifference(t *testing.T) {
+	left, right := binauralResponse(60, 10)
+
+	// The lag maximizing correlation is how much later the far ear hears it.
+	best, bestLag := math.Inf(-1), 0
+	for lag := -40; lag <= 40; lag++ {
+		var dot float64
+		for i := 40; i < len(left)-40; i++ {
+			dot += right[i] * left[i+lag]
+		}
+		if dot > best {
+			best, bestLag = dot, lag
+		}
+	}
+	want := earDelay(150) - earDelay(30)
+	if math.Abs(float64(bestLag)-want) > 2 {
+		t.Fatalf("left ear lags by %d samples, want about %.1f", bestLag, want)
+	}
+}
+
+func TestMixer_BinauralLevelDifference(t *testing.T) {
+	left, right := binauralResponse(90, 10)
+	// Broadband noise from the right: the shadowed left ear hears less.
+	if d := 20 * math.Log10(rms(right)/rms(left)); d < 6 {
+		t.Fatalf("right ear %.1f dB louder, want at least 6", d)
+	}
+
+	// Straight ahead, both ears hear the same.
+	left, right = binauralResponse(0, 10)
+	if d := 20 * math.Log10(rms(right)/rms(left)); math.Abs(d) > 0.1 {
+		t.Fatalf("centered source: ears differ by %.2f dB", d)
+	}
+}
+
+func TestMixer_StereoStreamNotSpatialized(t *testing.T) {
+	m := newSpatialMixer(SpatialBinaural)
+	m.AddStream("music")
+	m.SetPosition("music", 90, 1)
+
+	var frame StereoFrame
+	for i := range codec.FrameSize {
+		frame[0][i] = 3000
+		frame[1][i] = -2000
+	}
+	m.PushStereoFrame("music", frame)
+	out := m.MixStereo()
+	if out[0][0] != 3000 || out[1][0] != -2000 {
+		t.Fatalf("got L=%d R=%d, want the stream's own image", out[0][0], out[1][0])
+	}
+}
+
+func TestParseSpatialMode(t *testing.T) {
+	for _, mode := range []SpatialMode{SpatialOff, SpatialPan, SpatialBinaural} {
+		got, err := ParseSpatialMode(mode.String())
+		if err != nil || got != mode {
+			t.Fatalf("%v: got %v, %v", mode, got, err)
+		}
+	}
+	if names := spatialModeNames[:]; !slices.Equal(names, modes.Spatial()) {
+		t.Fatalf("names %v, want %v", names, modes.Spatial())
+	}
+	if _, err := ParseSpatialMode("surround"); err == nil {
+		t.Fatal("accepted an unknown mode")
+	}
+}
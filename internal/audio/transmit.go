@@ -0,0 +1,192 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"voxlink/internal/audio/modes"
+	"voxlink/internal/codec"
+)
+
+// TransmitMode selects when the pipeline sends captured audio.
+//
+// TransmitVoice uses RNNoise's voice probability while denoising is on. With
+// denoising off, or RNNoise unavailable, it falls back to an energy detector,
+// which cannot tell speech from other sounds well above the background:
+// typing or music open the gate too.
+type TransmitMode int32
+
+const (
+	TransmitContinuous TransmitMode = iota // send every frame
+	TransmitPushToTalk                     // send while the talk key is held
+	TransmitVoice                          // send while the VAD detects speech
+)
+
+var transmitModeNames = [...]string{
+	TransmitContinuous: modes.TransmitContinuous,
+	TransmitPushToTalk: modes.TransmitPushToTalk,
+	TransmitVoice:      modes.TransmitVoice,
+}
+
+func (m TransmitMode) String() string {
+	if m < 0 || int(m) >= len(transmitModeNames) {
+		return fmt.Sprintf("TransmitMode(%d)", int32(m))
+	}
+	return transmitModeNames[m]
+}
+
+// ParseTransmitMode parses a mode name: "continuous", "ptt" or "vad".
+func ParseTransmitMode(s string) (TransmitMode, error) {
+	for m, name := range transmitModeNames {
+		if s == name {
+			return TransmitMode(m), nil
+		}
+	}
+	return 0, fmt.Errorf("audio: unknown transmit mode %q", s)
+}
+
+// TransmitConfig holds the transmit gate settings.
+type TransmitConfig struct {
+	Mode         TransmitMode
+	VADThreshold float32       // voice probability that opens the gate in TransmitVoice
+	Hangover     time.Duration // how long the gate stays open after speech
+	PreRoll      time.Duration // audio from before the gate opened that is sent with it
+}
+
+// DefaultTransmitConfig returns the VoxLink defaults.
+func DefaultTransmitConfig() TransmitConfig {
+	return TransmitConfig{
+		Mode:         TransmitContinuous,
+		VADThreshold: 0.6,
+		Hangover:     400 * time.Millisecond,
+		PreRoll:      100 * time.Millisecond,
+	}
+}
+
+// transmitGate decides which encoded packets the pipeline sends. Every frame
+// is still encoded, so the encoder state stays continuous and, in voice mode,
+// the packets from just before speech was detected can be sent when it is:
+// the VAD needs a frame or two to react, and without this pre-roll the first
+// syllable would be cut.
+type transmitGate struct {
+	mode    atomic.Int32
+	talking atomic.Bool // push-to-talk key held
+	muted   atomic.Bool
+
+	threshold float32
+	hangover  int // frames
+
+	// Owned by the pipeline goroutine.
+	preRoll  [][]byte // ring of the most recent packets not sent
+	next     int
+	buffered int
+	hang     int
+}
+
+func newTransmitGate(cfg TransmitConfig) *transmitGate {
+	g := &transmitGate{
+		threshold: cfg.VADThreshold,
+		hangover:  int(cfg.Hangover / frameDuration),
+		preRoll:   make([][]byte, int(cfg.PreRoll/frameDuration)),
+	}
+	g.mode.Store(int32(cfg.Mode))
+	return g
+}
+
+// admit passes packet, and in voice mode any pre-roll, to send if the gate
+// is open; otherwise it holds on to it as pre-roll.
+func (g *transmitGate) admit(packet []byte, vad float32, send func([]byte)) {
+	if g.muted.Load() {
+		g.reset()
+		return
+	}
+	switch TransmitMode(g.mode.Load()) {
+	case TransmitPushToTalk:
+		g.reset()
+		if g.talking.Load() {
+			send(packet)
+		}
+	case TransmitVoice:
+		if vad >= g.threshold {
+			g.flush(send)
+			g.hang = g.hangover + 1
+		}
+		if g.hang == 0 {
+			g.hold(packet)
+			return
+		}
+		g.hang--
+		send(packet)
+	default:
+		g.reset()
+		send(packet)
+	}
+}
+
+// hold keeps packet as pre-roll, dropping the oldest beyond the limit.
+func (g *transmitGate) hold(packet []byte) {
+	if len(g.preRoll) == 0 {
+		return
+	}
+	g.preRoll[g.next] = packet
+	g.next = (g.next + 1) % len(g.preRoll)
+	g.buffered = min(g.buffered+1, len(g.preRoll))
+}
+
+// flush sends the pre-roll, oldest first.
+func (g *transmitGate) flush(send func([]byte)) {
+	for i := range g.buffered {
+		j := (g.next - g.buffered + i + len(g.preRoll)) % len(g.preRoll)
+		send(g.preRoll[j])
+		g.preRoll[j] = nil
+	}
+	g.buffered = 0
+}
+
+func (g *transmitGate) reset() {
+	clear(g.preRoll)
+	g.buffered = 0
+	g.hang = 0
+}
+
+const (
+	energyFloorMin  = -70.0                   // dBFS; quieter backgrounds count as this level
+	energyFloorRise = 1500 * time.Millisecond // time constant of the noise floor rising to a louder background
+	energyOnset     = 6.0                     // dB above the floor where the voice probability starts to rise
+	energySpan      = 12.0                    // dB over which it rises from 0 to 1
+)
+
+// energyVAD estimates a voice probability from frame levels when RNNoise's
+// is unavailable. It tracks the background level as the noise floor, falling
+// with a quieter frame at once and rising slowly, so pauses in speech keep
+// it down, and rates a frame by how far it rises above the floor.
+type energyVAD struct {
+	floor float64 // dBFS
+	rise  float64 // smoothing per frame
+}
+
+func newEnergyVAD() *energyVAD {
+	return &energyVAD{floor: energyFloorMin, rise: smoothing(energyFloorRise)}
+}
+
+// process returns the voice probability of a frame, given as one array per
+// channel; the loudest channel counts.
+func (v *energyVAD) process(channels ...*[codec.FrameSize]int16) float32 {
+	level := math.Inf(-1)
+	for _, frame := range channels {
+		var sum float64
+		for _, s := range frame {
+			sum += float64(s) * float64(s)
+		}
+		level = max(level, dBFS(math.Sqrt(sum/codec.FrameSize)))
+	}
+	if level < v.floor {
+		v.floor = max(level, energyFloorMin)
+	} else {
+		v.floor += (level - v.floor) * v.rise
+	}
+	p := (level - v.floor - energyOnset) / energySpan
+	return float32(min(max(p, 0), 1))
+}
@@ -0,0 +1,121 @@
+package audio
+
+import (
+	"fmt"
+	"math"
+	"slices"
+	"testing"
+
+	"voxlink/internal/audio/modes"
+	"voxlink/internal/codec"
+)
+
+// runGate feeds the gate one packet per VAD value, named by frame index, and
+// returns the packets it sent.
+func runGate(g *transmitGate, vads ...float32) []string {
+	var sent []string
+	for i, vad := range vads {
+		g.admit([]byte(fmt.Sprint(i)), vad, func(p []byte) { sent = append(sent, string(p)) })
+	}
+	return sent
+}
+
+func TestParseTransmitMode(t *testing.T) {
+	for _, m := range []TransmitMode{TransmitContinuous, TransmitPushToTalk, TransmitVoice} {
+		got, err := ParseTransmitMode(m.String())
+		if err != nil || got != m {
+			t.Errorf("%v: got %v, %v", m, got, err)
+		}
+	}
+	if names := transmitModeNames[:]; !slices.Equal(names, modes.Transmit()) {
+		t.Errorf("names %v, want %v", names, modes.Transmit())
+	}
+	if _, err := ParseTransmitMode("always"); err == nil {
+		t.Error("unknown mode accepted")
+	}
+}
+
+func TestTransmitGate_Continuous(t *testing.T) {
+	g := newTransmitGate(DefaultTransmitConfig())
+	if sent := runGate(g, 0, 0, 1); len(sent) != 3 {
+		t.Fatalf("sent %v, want every packet", sent)
+	}
+	g.muted.Store(true)
+	if sent := runGate(g, 1, 1); len(sent) != 0 {
+		t.Fatalf("muted: sent %v", sent)
+	}
+}
+
+func TestTransmitGate_PushToTalk(t *testing.T) {
+	cfg := DefaultTransmitConfig()
+	cfg.Mode = TransmitPushToTalk
+	g := newTransmitGate(cfg)
+
+	if sent := runGate(g, 1, 1); len(sent) != 0 {
+		t.Fatalf("key up: sent %v", sent)
+	}
+	g.talking.Store(true)
+	if sent := runGate(g, 0, 1); len(sent) != 2 {
+		t.Fatalf("key down: sent %v, want every packet", sent)
+	}
+}
+
+func TestTransmitGate_Voice(t *testing.T) {
+	cfg := TransmitConfig{
+		Mode:         TransmitVoice,
+		VADThreshold: 0.5,
+		Hangover:     2 * frameDuration,
+		PreRoll:      2 * frameDuration,
+	}
+	g := newTransmitGate(cfg)
+
+	// Frames 0-2 are silence: held back, but 1 and 2 go out as pre-roll when
+	// speech starts at 3. Speech ends after 4, the hangover carries 5 and 6,
+	// and 7-9 are held again.
+	sent := runGate(g, 0, 0, 0, 0.9, 0.8, 0.1, 0.2, 0, 0, 0)
+	if want := []string{"1", "2", "3", "4", "5", "6"}; !slices.Equal(sent, want) {
+		t.Fatalf("sent %v, want %v", sent, want)
+	}
+
+	// Pre-roll is not sent twice, and muting discards it.
+	g.muted.Store(true)
+	runGate(g, 0)
+	g.muted.Store(false)
+	if sent := runGate(g, 0.9); !slices.Equal(sent, []string{"0"}) {
+		t.Fatalf("after mute: sent %v, want only the new packet", sent)
+	}
+}
+
+func TestEnergyVAD(t *testing.T) {
+	frameAt := func(amplitude float64) *[codec.FrameSize]int16 {
+		var f [codec.FrameSize]int16
+		for i := range f {
+			f[i] = int16(amplitude * math.Sin(2*math.Pi*300*float64(i)/codec.SampleRate))
+		}
+		return &f
+	}
+	v := newEnergyVAD()
+	if p := v.process(frameAt(0)); p != 0 {
+		t.Fatalf("digital silence: %v", p)
+	}
+
+	// A steady background becomes the floor, however loud.
+	noise := frameAt(300) // about -43 dBFS
+	var p float32
+	for range 300 {
+		p = v.process(noise)
+	}
+	if p != 0 {
+		t.Fatalf("background: %v", p)
+	}
+	if p := v.process(frameAt(10000)); p != 1 {
+		t.Fatalf("speech 30 dB above the background: %v", p)
+	}
+	if p := v.process(noise); p != 0 {
+		t.Fatalf("back to the background: %v", p)
+	}
+	// The loudest channel counts.
+	if p := v.process(noise, frameAt(10000)); p != 1 {
+		t.Fatalf("speech on one channel: %v", p)
+	}
+}
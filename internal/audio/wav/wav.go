@@ -0,0 +1,86 @@
+// Package wav reads and writes mono 16-bit PCM WAV files, the format of the
+// audio fixtures used to test the capture pipeline offline.
+package wav
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrFormat is returned for files that are not mono 16-bit PCM WAV.
+var ErrFormat = errors.New("wav: not a mono 16-bit PCM file")
+
+// Read decodes a mono 16-bit PCM WAV stream and returns its samples and
+// sample rate. Chunks other than fmt and data are skipped.
+func Read(r io.Reader) ([]int16, int, error) {
+	var riff [12]byte
+	if _, err := io.ReadFull(r, riff[:]); err != nil {
+		return nil, 0, fmt.Errorf("wav: read header: %w", err)
+	}
+	if string(riff[0:4]) != "RIFF" || string(riff[8:12]) != "WAVE" {
+		return nil, 0, ErrFormat
+	}
+
+	rate := 0
+	for {
+		var hdr [8]byte
+		if _, err := io.ReadFull(r, hdr[:]); err != nil {
+			return nil, 0, fmt.Errorf("wav: no data chunk: %w", err)
+		}
+		id, size := string(hdr[0:4]), binary.LittleEndian.Uint32(hdr[4:8])
+		switch id {
+		case "fmt ":
+			if size < 16 {
+				return nil, 0, ErrFormat
+			}
+			buf := make([]byte, size+size%2)
+			if _, err := io.ReadFull(r, buf); err != nil {
+				return nil, 0, fmt.Errorf("wav: read fmt chunk: %w", err)
+			}
+			format := binary.LittleEndian.Uint16(buf[0:2])
+			channels := binary.LittleEndian.Uint16(buf[2:4])
+			bits := binary.LittleEndian.Uint16(buf[14:16])
+			if format != 1 || channels != 1 || bits != 16 {
+				return nil, 0, ErrFormat
+			}
+			rate = int(binary.LittleEndian.Uint32(buf[4:8]))
+		case "data":
+			if rate == 0 {
+				return nil, 0, fmt.Errorf("wav: data chunk before fmt chunk")
+			}
+			samples := make([]int16, size/2)
+			if err := binary.Read(r, binary.LittleEndian, samples); err != nil {
+				return nil, 0, fmt.Errorf("wav: read samples: %w", err)
+			}
+			return samples, rate, nil
+		default:
+			if _, err := io.CopyN(io.Discard, r, int64(size+size%2)); err != nil {
+				return nil, 0, fmt.Errorf("wav: skip %q chunk: %w", id, err)
+			}
+		}
+	}
+}
+
+// Write encodes samples as a mono 16-bit PCM WAV stream.
+func Write(w io.Writer, samples []int16, sampleRate int) error {
+	dataSize := uint32(len(samples) * 2)
+	hdr := make([]byte, 44)
+	copy(hdr[0:4], "RIFF")
+	binary.LittleEndian.PutUint32(hdr[4:8], 36+dataSize)
+	copy(hdr[8:16], "WAVEfmt ")
+	binary.LittleEndian.PutUint32(hdr[16:20], 16)
+	binary.LittleEndian.PutUint16(hdr[20:22], 1) // PCM
+	binary.LittleEndian.PutUint16(hdr[22:24], 1) // mono
+	binary.LittleEndian.PutUint32(hdr[24:28], uint32(sampleRate))
+	binary.LittleEndian.PutUint32(hdr[28:32], uint32(sampleRate*2))
+	binary.LittleEndian.PutUint16(hdr[32:34], 2)
+	binary.LittleEndian.PutUint16(hdr[34:36], 16)
+	copy(hdr[36:40], "data")
+	binary.LittleEndian.PutUint32(hdr[40:44], dataSize)
+	if _, err := w.Write(hdr); err != nil {
+		return err
+	}
+	return binary.Write(w, binary.LittleEndian, samples)
+}
@@ -0,0 +1,60 @@
+package wav
+
+import (
+	"bytes"
+	"encoding/binary"
+	"slices"
+	"testing"
+)
+
+func TestRoundTrip(t *testing.T) {
+	samples := []int16{0, 1, -1, 32767, -32768, 1234}
+	var buf bytes.Buffer
+	if err := Write(&buf, samples, 48000); err != nil {
+		t.Fatal(err)
+	}
+	if buf.Len() != 44+2*len(samples) {
+		t.Fatalf("file size: got %d", buf.Len())
+	}
+
+	got, rate, err := Read(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 48000 || !slices.Equal(got, samples) {
+		t.Fatalf("got %v at %d Hz", got, rate)
+	}
+}
+
+func TestRead_SkipsOtherChunks(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, []int16{7, 8}, 16000)
+	file := buf.Bytes()
+
+	// Insert an odd-sized LIST chunk, padded to an even length, before fmt.
+	list := []byte("LIST\x03\x00\x00\x00abc\x00")
+	withList := slices.Concat(file[:12], list, file[12:])
+	binary.LittleEndian.PutUint32(withList[4:8], uint32(len(withList)-8))
+
+	got, rate, err := Read(bytes.NewReader(withList))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rate != 16000 || !slices.Equal(got, []int16{7, 8}) {
+		t.Fatalf("got %v at %d Hz", got, rate)
+	}
+}
+
+func TestRead_RejectsStereo(t *testing.T) {
+	var buf bytes.Buffer
+	Write(&buf, []int16{1, 2}, 48000)
+	file := buf.Bytes()
+	binary.LittleEndian.PutUint16(file[22:24], 2)
+
+	if _, _, err := Read(bytes.NewReader(file)); err != ErrFormat {
+		t.Fatalf("got %v, want ErrFormat", err)
+	}
+	if _, _, err := Read(bytes.NewReader([]byte("not a wav file at all"))); err != ErrFormat {
+		t.Fatalf("garbage: got %v, want ErrFormat", err)
+	}
+}
@@ -0,0 +1,108 @@
+// Package auth authenticates HTTP API callers with static API keys or JWTs
+// and authorizes them by scope.
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Scopes understood by the HTTP API.
+const (
+	ScopeRoomsRead    = "rooms:read"
+	ScopeRoomsAdmin   = "rooms:admin"
+	ScopeAudioControl = "audio:control"
+)
+
+// AllScopes lists every scope, e.g. for a bootstrap admin key.
+var AllScopes = []string{ScopeRoomsRead, ScopeRoomsAdmin, ScopeAudioControl}
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials the
+	// authenticator understands.
+	ErrNoCredentials = errors.New("auth: no credentials")
+	// ErrInvalidCredentials is returned when credentials are present but wrong.
+	ErrInvalidCredentials = errors.New("auth: invalid credentials")
+)
+
+// Principal is an authenticated API caller.
+type Principal struct {
+	Subject string
+	Scopes  []string
+}
+
+// HasScope reports whether the principal was granted scope.
+func (p *Principal) HasScope(scope string) bool {
+	return p != nil && slices.Contains(p.Scopes, scope)
+}
+
+// Authenticator verifies the credentials carried by an HTTP request.
+type Authenticator interface {
+	// Authenticate returns ErrNoCredentials if the request has no credentials
+	// for this authenticator, or another error if they are invalid.
+	Authenticate(r *http.Request) (*Principal, error)
+}
+
+// Chain tries each authenticator in order and returns the first success.
+// An authenticator returning ErrNoCredentials passes to the next one; any
+// other error is final.
+func Chain(authenticators ...Authenticator) Authenticator {
+	return chain(authenticators)
+}
+
+type chain []Authenticator
+
+func (c chain) Authenticate(r *http.Request) (*Principal, error) {
+	for _, a := range c {
+		p, err := a.Authenticate(r)
+		if errors.Is(err, ErrNoCredentials) {
+			continue
+		}
+		return p, err
+	}
+	return nil, ErrNoCredentials
+}
+
+// BearerToken extracts the token from an "Authorization: Bearer" header.
+func BearerToken(r *http.Request) (string, bool) {
+	h := r.Header.Get("Authorization")
+	if len(h) < 7 || !strings.EqualFold(h[:7], "bearer ") {
+		return "", false
+	}
+	token := strings.TrimSpace(h[7:])
+	return token, token != ""
+}
+
+type principalKey struct{}
+
+// NewContext returns a copy of ctx carrying p.
+func NewContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext returns the principal stored by Require, if any.
+func FromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// Require wraps next so that it only runs for callers authenticated by a and
+// granted scope. Unauthenticated requests get 401, requests lacking the scope 403.
+func Require(a Authenticator, scope string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, err := a.Authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="voxlink"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !p.HasScope(scope) {
+			http.Error(w, "forbidden: missing scope "+scope, http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(NewContext(r.Context(), p)))
+	})
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequire(t *testing.T) {
+	keys, err := NewStaticKeys([]APIKey{
+		{Name: "reader", Key: "read-key", Scopes: []string{ScopeRoomsRead}},
+		{Name: "admin", Key: "admin-key", Scopes: AllScopes},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var gotSubject string
+	h := Require(keys, ScopeRoomsAdmin, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		p, _ := FromContext(r.Context())
+		gotSubject = p.Subject
+	}))
+
+	tests := []struct {
+		name   string
+		header string
+		want   int
+	}{
+		{"no credentials", "", http.StatusUnauthorized},
+		{"wrong key", "Bearer nope", http.StatusUnauthorized},
+		{"missing scope", "Bearer read-key", http.StatusForbidden},
+		{"granted", "Bearer admin-key", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			if tt.header != "" {
+				req.Header.Set("Authorization", tt.header)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, req)
+			if w.Code != tt.want {
+				t.Fatalf("status: got %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+	if gotSubject != "admin" {
+		t.Fatalf("subject: got %q, want %q", gotSubject, "admin")
+	}
+}
+
+func TestStaticKeys_XAPIKeyHeader(t *testing.T) {
+	keys, _ := NewStaticKeys([]APIKey{{Key: "k1", Scopes: []string{ScopeAudioControl}}})
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("X-API-Key", "k1")
+	p, err := keys.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "key-0" || !p.HasScope(ScopeAudioControl) {
+		t.Fatalf("principal: %+v", p)
+	}
+}
+
+func TestChain(t *testing.T) {
+	keys, _ := NewStaticKeys([]APIKey{{Name: "ops", Key: "k1", Scopes: AllScopes}})
+	ks, _ := ParseJWKS([]byte(hmacJWKS))
+	a := Chain(keys, NewJWTAuthenticator(NewJWTVerifier(ks)))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	if _, err := a.Authenticate(req); err != ErrNoCredentials {
+		t.Fatalf("no credentials: got %v, want %v", err, ErrNoCredentials)
+	}
+
+	req.Header.Set("Authorization", "Bearer k1")
+	if p, err := a.Authenticate(req); err != nil || p.Subject != "ops" {
+		t.Fatalf("api key: got %+v, %v", p, err)
+	}
+
+	req.Header.Set("Authorization", "Bearer "+signHS256(t, map[string]any{"sub": "svc", "scope": "rooms:read", "exp": future()}))
+	if p, err := a.Authenticate(req); err != nil || p.Subject != "svc" {
+		t.Fatalf("jwt: got %+v, %v", p, err)
+	}
+}
@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is a single JSON Web Key. Only symmetric ("oct") and RSA keys are supported.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg,omitempty"`
+	K   string `json:"k,omitempty"` // oct: base64url secret
+	N   string `json:"n,omitempty"` // RSA: base64url modulus
+	E   string `json:"e,omitempty"` // RSA: base64url exponent
+}
+
+// KeySet holds verification keys indexed by key ID.
+type KeySet struct {
+	keys map[string]verifyKey
+}
+
+type verifyKey struct {
+	alg string // HS256 or RS256
+	key any    // []byte or *rsa.PublicKey
+}
+
+// ParseJWKS parses a JSON Web Key Set document.
+func ParseJWKS(data []byte) (*KeySet, error) {
+	var doc struct {
+		Keys []JWK `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse jwks: %w", err)
+	}
+	ks := &KeySet{keys: make(map[string]verifyKey)}
+	for i, k := range doc.Keys {
+		vk, err := k.verifyKey()
+		if err != nil {
+			return nil, fmt.Errorf("jwks key %d (%q): %w", i, k.Kid, err)
+		}
+		if _, dup := ks.keys[k.Kid]; dup {
+			return nil, fmt.Errorf("jwks: duplicate kid %q", k.Kid)
+		}
+		ks.keys[k.Kid] = vk
+	}
+	if len(ks.keys) == 0 {
+		return nil, errors.New("jwks: no keys")
+	}
+	return ks, nil
+}
+
+// LoadJWKS reads a JSON Web Key Set from a local file.
+func LoadJWKS(path string) (*KeySet, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwks: %w", err)
+	}
+	return ParseJWKS(data)
+}
+
+func (k JWK) verifyKey() (verifyKey, error) {
+	switch k.Kty {
+	case "oct":
+		if k.Alg != "" && k.Alg != "HS256" {
+			return verifyKey{}, fmt.Errorf("unsupported alg %q for oct key", k.Alg)
+		}
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil || len(secret) == 0 {
+			return verifyKey{}, errors.New("invalid oct key material")
+		}
+		return verifyKey{alg: "HS256", key: secret}, nil
+	case "RSA":
+		if k.Alg != "" && k.Alg != "RS256" {
+			return verifyKey{}, fmt.Errorf("unsupported alg %q for RSA key", k.Alg)
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil || len(n) == 0 {
+			return verifyKey{}, errors.New("invalid RSA modulus")
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil || len(e) == 0 || len(e) > 4 {
+			return verifyKey{}, errors.New("invalid RSA exponent")
+		}
+		pub := &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+		return verifyKey{alg: "RS256", key: pub}, nil
+	default:
+		return verifyKey{}, fmt.Errorf("unsupported kty %q", k.Kty)
+	}
+}
+
+// keyfunc selects the verification key by the token's kid header and checks
+// that the token's alg matches the key type. A token without kid is accepted
+// only when the set contains a single key.
+func (ks *KeySet) keyfunc(t *jwt.Token) (any, error) {
+	kid, _ := t.Header["kid"].(string)
+	vk, ok := ks.keys[kid]
+	if !ok && kid == "" && len(ks.keys) == 1 {
+		for _, only := range ks.keys {
+			vk, ok = only, true
+		}
+	}
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %q", kid)
+	}
+	if t.Method.Alg() != vk.alg {
+		return nil, fmt.Errorf("alg %s does not match key %q", t.Method.Alg(), kid)
+	}
+	return vk.key, nil
+}
+
+// JWTVerifier verifies HS256/RS256 tokens against a local key set.
+type JWTVerifier struct {
+	keys     *KeySet
+	issuer   string
+	audience string
+	leeway   time.Duration
+	now      func() time.Time
+}
+
+// JWTOption configures a JWTVerifier.
+type JWTOption func(*JWTVerifier)
+
+// WithIssuer requires tokens to carry the given iss claim.
+func WithIssuer(iss string) JWTOption {
+	return func(v *JWTVerifier) { v.issuer = iss }
+}
+
+// WithAudience requires tokens to list the given aud claim.
+func WithAudience(aud string) JWTOption {
+	return func(v *JWTVerifier) { v.audience = aud }
+}
+
+// WithLeeway allows for clock skew when validating exp and nbf.
+func WithLeeway(d time.Duration) JWTOption {
+	return func(v *JWTVerifier) { v.leeway = d }
+}
+
+// WithClock overrides the time source used to validate exp and nbf.
+func WithClock(now func() time.Time) JWTOption {
+	return func(v *JWTVerifier) { v.now = now }
+}
+
+// NewJWTVerifier creates a verifier for the given key set.
+func NewJWTVerifier(keys *KeySet, opts ...JWTOption) *JWTVerifier {
+	v := &JWTVerifier{keys: keys, now: time.Now}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Verify checks the token's signature and registered claims and decodes its
+// claims into claims. Tokens must carry an exp claim.
+func (v *JWTVerifier) Verify(token string, claims jwt.Claims) error {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{"HS256", "RS256"}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(v.leeway),
+		jwt.WithTimeFunc(v.now),
+	}
+	if v.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(v.issuer))
+	}
+	if v.audience != "" {
+		opts = append(opts, jwt.WithAudience(v.audience))
+	}
+	if _, err := jwt.NewParser(opts...).ParseWithClaims(token, claims, v.keys.keyfunc); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	return nil
+}
+
+// APIClaims are the claims read from API access tokens. Scopes may be given
+// as a space-separated "scope" string (RFC 8693) or a "scopes" array.
+type APIClaims struct {
+	jwt.RegisteredClaims
+	Scope  string   `json:"scope,omitempty"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// AllScopes returns the union of the scope and scopes claims.
+func (c *APIClaims) AllScopes() []string {
+	out := append([]string(nil), c.Scopes...)
+	return append(out, strings.Fields(c.Scope)...)
+}
+
+// JWTAuthenticator authenticates bearer JWTs with a JWTVerifier.
+type JWTAuthenticator struct {
+	verifier *JWTVerifier
+}
+
+// NewJWTAuthenticator creates an Authenticator backed by v.
+func NewJWTAuthenticator(v *JWTVerifier) *JWTAuthenticator {
+	return &JWTAuthenticator{verifier: v}
+}
+
+// Authenticate implements Authenticator.
+func (a *JWTAuthenticator) Authenticate(r *http.Request) (*Principal, error) {
+	token, ok := BearerToken(r)
+	if !ok || !looksLikeJWT(token) {
+		return nil, ErrNoCredentials
+	}
+	var claims APIClaims
+	if err := a.verifier.Verify(token, &claims); err != nil {
+		return nil, err
+	}
+	return &Principal{Subject: claims.Subject, Scopes: claims.AllScopes()}, nil
+}
+
+// looksLikeJWT reports whether s has the three-segment compact JWS shape.
+func looksLikeJWT(s string) bool {
+	return strings.Count(s, ".") == 2
+}
@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var hmacSecret = []byte("0123456789abcdef0123456789abcdef")
+
+var hmacJWKS = `{"keys":[{"kty":"oct","kid":"hs","alg":"HS256","k":"` +
+	base64.RawURLEncoding.EncodeToString(hmacSecret) + `"}]}`
+
+func future() int64 { return time.Now().Add(time.Hour).Unix() }
+
+func signHS256(t *testing.T, claims map[string]any) string {
+	t.Helper()
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims(claims))
+	tok.Header["kid"] = "hs"
+	s, err := tok.SignedString(hmacSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func rsaJWKS(t *testing.T, key *rsa.PrivateKey) []byte {
+	t.Helper()
+	data, _ := json.Marshal(map[string]any{"keys": []JWK{{
+		Kty: "RSA",
+		Kid: "rs",
+		Alg: "RS256",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}}})
+	return data
+}
+
+func TestJWTVerifier_HS256(t *testing.T) {
+	ks, err := ParseJWKS([]byte(hmacJWKS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := NewJWTVerifier(ks, WithIssuer("voxlink-tests"))
+
+	var claims APIClaims
+	token := signHS256(t, map[string]any{
+		"iss":    "voxlink-tests",
+		"sub":    "alice",
+		"scope":  "rooms:read audio:control",
+		"scopes": []string{"rooms:admin"},
+		"exp":    future(),
+	})
+	if err := v.Verify(token, &claims); err != nil {
+		t.Fatal(err)
+	}
+	p := &Principal{Scopes: claims.AllScopes()}
+	for _, s := range AllScopes {
+		if !p.HasScope(s) {
+			t.Fatalf("missing scope %q in %v", s, p.Scopes)
+		}
+	}
+
+	bad := []struct {
+		name   string
+		claims map[string]any
+	}{
+		{"expired", map[string]any{"iss": "voxlink-tests", "exp": time.Now().Add(-time.Hour).Unix()}},
+		{"no exp", map[string]any{"iss": "voxlink-tests"}},
+		{"wrong issuer", map[string]any{"iss": "someone-else", "exp": future()}},
+	}
+	for _, tt := range bad {
+		t.Run(tt.name, func(t *testing.T) {
+			err := v.Verify(signHS256(t, tt.claims), &APIClaims{})
+			if !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("got %v, want ErrInvalidCredentials", err)
+			}
+		})
+	}
+}
+
+func TestJWTVerifier_RS256(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ks, err := ParseJWKS(rsaJWKS(t, key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := NewJWTAuthenticator(NewJWTVerifier(ks))
+
+	tok := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{"sub": "svc", "scope": "rooms:admin", "exp": future()})
+	tok.Header["kid"] = "rs"
+	signed, err := tok.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("Authorization", "Bearer "+signed)
+	p, err := a.Authenticate(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Subject != "svc" || !p.HasScope(ScopeRoomsAdmin) {
+		t.Fatalf("principal: %+v", p)
+	}
+
+	// An HS256 token signed with the RSA public key must not verify (alg confusion).
+	forged := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"sub": "evil", "exp": future()})
+	forged.Header["kid"] = "rs"
+	forgedStr, _ := forged.SignedString(key.N.Bytes())
+	req.Header.Set("Authorization", "Bearer "+forgedStr)
+	if _, err := a.Authenticate(req); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("forged token: got %v, want ErrInvalidCredentials", err)
+	}
+}
+
+func TestParseJWKS_Errors(t *testing.T) {
+	for _, doc := range []string{
+		`not json`,
+		`{"keys":[]}`,
+		`{"keys":[{"kty":"EC","kid":"x"}]}`,
+		`{"keys":[{"kty":"oct","kid":"x","alg":"RS256","k":"c2VjcmV0"}]}`,
+		`{"keys":[{"kty":"oct","kid":"x","k":"c2VjcmV0"},{"kty":"oct","kid":"x","k":"c2VjcmV0"}]}`,
+	} {
+		if _, err := ParseJWKS([]byte(doc)); err == nil {
+			t.Fatalf("ParseJWKS(%s) should fail", doc)
+		}
+	}
+}
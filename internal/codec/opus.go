@@ -8,36 +8,74 @@ import (
 
 const (
 	SampleRate    = 48000
-	Channels      = 1
-	FrameSize     = 960   // 20ms at 48kHz
+	Channels      = 1     // default; stereo rooms use 2
+	FrameSize     = 960   // 20ms at 48kHz, per channel
 	Bitrate       = 24000 // 24 kbps
+	StereoBitrate = 96000 // 96 kbps, for music
 	MaxPacketSize = 4000
 )
 
+// EncoderConfig holds the tunable Opus encoder settings.
+type EncoderConfig struct {
+	Bitrate  int  // bits per second
+	DTX      bool // discontinuous transmission: send almost nothing during silence
+	Channels int  // 1 or 2; 0 means Channels
+}
+
+// DefaultEncoderConfig returns the VoxLink defaults.
+func DefaultEncoderConfig() EncoderConfig {
+	return EncoderConfig{Bitrate: Bitrate, DTX: true, Channels: Channels}
+}
+
+// DefaultStereoEncoderConfig returns the VoxLink defaults for stereo rooms.
+func DefaultStereoEncoderConfig() EncoderConfig {
+	return EncoderConfig{Bitrate: StereoBitrate, Channels: 2}
+}
+
 // Encoder wraps an Opus encoder with VoxLink parameters.
 type Encoder struct {
-	enc *opus.Encoder
+	enc      *opus.Encoder
+	channels int
 }
 
 // NewEncoder creates an Opus encoder configured for VoIP with DTX.
 func NewEncoder() (*Encoder, error) {
-	enc, err := opus.NewEncoder(SampleRate, Channels, opus.AppVoIP)
+	return NewEncoderWithConfig(DefaultEncoderConfig())
+}
+
+// NewEncoderWithConfig creates an Opus encoder with custom settings. Mono
+// encoders are tuned for speech (VoIP); stereo ones, meant for music, for
+// general audio.
+func NewEncoderWithConfig(cfg EncoderConfig) (*Encoder, error) {
+	channels, err := channelCount(cfg.Channels)
+	if err != nil {
+		return nil, err
+	}
+	app := opus.AppVoIP
+	if channels == 2 {
+		app = opus.AppAudio
+	}
+	enc, err := opus.NewEncoder(SampleRate, channels, app)
 	if err != nil {
 		return nil, fmt.Errorf("opus encoder: %w", err)
 	}
-	if err := enc.SetBitrate(Bitrate); err != nil {
+	if err := enc.SetBitrate(cfg.Bitrate); err != nil {
 		return nil, fmt.Errorf("set bitrate: %w", err)
 	}
-	if err := enc.SetDTX(true); err != nil {
+	if err := enc.SetDTX(cfg.DTX); err != nil {
 		return nil, fmt.Errorf("set DTX: %w", err)
 	}
-	return &Encoder{enc: enc}, nil
+	return &Encoder{enc: enc, channels: channels}, nil
 }
 
-// Encode encodes a FrameSize slice of int16 PCM samples into Opus.
+// Channels returns the number of channels the encoder takes.
+func (e *Encoder) Channels() int { return e.channels }
+
+// Encode encodes FrameSize int16 PCM samples per channel, interleaved, into
+// Opus.
 func (e *Encoder) Encode(pcm []int16) ([]byte, error) {
-	if len(pcm) != FrameSize {
-		return nil, fmt.Errorf("expected %d samples, got %d", FrameSize, len(pcm))
+	if len(pcm) != FrameSize*e.channels {
+		return nil, fmt.Errorf("expected %d samples, got %d", FrameSize*e.channels, len(pcm))
 	}
 	buf := make([]byte, MaxPacketSize)
 	n, err := e.enc.Encode(pcm, buf)
@@ -52,27 +90,53 @@ func (e *Encoder) Close() {}
 
 // Decoder wraps an Opus decoder with VoxLink parameters.
 type Decoder struct {
-	dec *opus.Decoder
+	dec      *opus.Decoder
+	channels int
 }
 
-// NewDecoder creates an Opus decoder.
+// NewDecoder creates a mono Opus decoder.
 func NewDecoder() (*Decoder, error) {
-	dec, err := opus.NewDecoder(SampleRate, Channels)
+	return NewDecoderWithChannels(Channels)
+}
+
+// NewDecoderWithChannels creates an Opus decoder producing 1 or 2 channels.
+// Opus decodes mono and stereo packets alike into either.
+func NewDecoderWithChannels(channels int) (*Decoder, error) {
+	channels, err := channelCount(channels)
+	if err != nil {
+		return nil, err
+	}
+	dec, err := opus.NewDecoder(SampleRate, channels)
 	if err != nil {
 		return nil, fmt.Errorf("opus decoder: %w", err)
 	}
-	return &Decoder{dec: dec}, nil
+	return &Decoder{dec: dec, channels: channels}, nil
 }
 
-// Decode decodes an Opus packet into FrameSize int16 PCM samples.
+// Channels returns the number of channels the decoder produces.
+func (d *Decoder) Channels() int { return d.channels }
+
+// Decode decodes an Opus packet into FrameSize int16 PCM samples per
+// channel, interleaved.
 func (d *Decoder) Decode(data []byte) ([]int16, error) {
-	pcm := make([]int16, FrameSize)
+	pcm := make([]int16, FrameSize*d.channels)
 	n, err := d.dec.Decode(data, pcm)
 	if err != nil {
 		return nil, fmt.Errorf("opus decode: %w", err)
 	}
-	return pcm[:n], nil
+	return pcm[:n*d.channels], nil
 }
 
 // Close is a no-op but exists for symmetry.
 func (d *Decoder) Close() {}
+
+func channelCount(n int) (int, error) {
+	switch n {
+	case 0:
+		return Channels, nil
+	case 1, 2:
+		return n, nil
+	default:
+		return 0, fmt.Errorf("unsupported channel count %d", n)
+	}
+}
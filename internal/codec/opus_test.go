@@ -102,3 +102,62 @@ func sinApprox(x float64) float64 {
 	}
 	return (16 * x * (3.141593 - x)) / (49.348 - 4*x*(3.141593-x))
 }
+
+func TestOpusStereoRoundTrip(t *testing.T) {
+	if testing.Short() {
+		t.Skip("skipping CGo test in short mode")
+	}
+
+	enc, err := NewEncoderWithConfig(DefaultStereoEncoderConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec, err := NewDecoderWithChannels(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if enc.Channels() != 2 || dec.Channels() != 2 {
+		t.Fatalf("channels: encoder %d, decoder %d", enc.Channels(), dec.Channels())
+	}
+
+	// A tone on the left channel only, interleaved.
+	input := make([]int16, 2*FrameSize)
+	for i := range FrameSize {
+		input[2*i] = int16(8000.0 * sinApprox(float64(i)*440.0*2.0*3.14159/float64(SampleRate)))
+	}
+	if _, err := enc.Encode(input[:FrameSize]); err == nil {
+		t.Fatal("stereo encoder accepted a mono frame")
+	}
+
+	// Let the encoder settle, then check the image survives.
+	var decoded []int16
+	for range 5 {
+		encoded, err := enc.Encode(input)
+		if err != nil {
+			t.Fatalf("encode: %v", err)
+		}
+		if decoded, err = dec.Decode(encoded); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+	}
+	if len(decoded) != 2*FrameSize {
+		t.Fatalf("decoded length: got %d, want %d", len(decoded), 2*FrameSize)
+	}
+	var left, right float64
+	for i := range FrameSize {
+		left += float64(decoded[2*i]) * float64(decoded[2*i])
+		right += float64(decoded[2*i+1]) * float64(decoded[2*i+1])
+	}
+	if left < 100*right {
+		t.Fatalf("stereo image lost: left energy %.0f, right %.0f", left, right)
+	}
+}
+
+func TestChannelCount(t *testing.T) {
+	if _, err := NewEncoderWithConfig(EncoderConfig{Bitrate: Bitrate, Channels: 3}); err == nil {
+		t.Fatal("3-channel encoder created")
+	}
+	if _, err := NewDecoderWithChannels(-1); err == nil {
+		t.Fatal("-1-channel decoder created")
+	}
+}
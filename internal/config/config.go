@@ -0,0 +1,457 @@
+// Package config holds VoxLink's runtime configuration and loads it from a
+// YAML, TOML or JSON file, VOXLINK_* environment variables and flags.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"voxlink/internal/audio/modes"
+	"voxlink/internal/origin"
+	"voxlink/internal/sfu"
+)
+
+// Config is the complete VoxLink configuration.
+type Config struct {
+	Server  ServerConfig  `json:"server" yaml:"server" toml:"server"`
+	Auth    AuthConfig    `json:"auth" yaml:"auth" toml:"auth"`
+	SFU     SFUConfig     `json:"sfu" yaml:"sfu" toml:"sfu"`
+	Cluster ClusterConfig `json:"cluster" yaml:"cluster" toml:"cluster"`
+	ICE     ICEConfig     `json:"ice" yaml:"ice" toml:"ice"`
+	Codec   CodecConfig   `json:"codec" yaml:"codec" toml:"codec"`
+	Audio   AudioConfig   `json:"audio" yaml:"audio" toml:"audio"`
+	Log     LogConfig     `json:"log" yaml:"log" toml:"log"`
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	Addr           string         `json:"addr" yaml:"addr" toml:"addr"`
+	AllowedOrigins []string       `json:"allowedOrigins" yaml:"allowedOrigins" toml:"allowedOrigins"`
+	Dev            bool           `json:"dev" yaml:"dev" toml:"dev"`
+	StatsInterval  Duration       `json:"statsInterval" yaml:"statsInterval" toml:"statsInterval"`
+	TLS            TLSConfig      `json:"tls" yaml:"tls" toml:"tls"`
+	Shutdown       ShutdownConfig `json:"shutdown" yaml:"shutdown" toml:"shutdown"`
+	Limits         LimitsConfig   `json:"limits" yaml:"limits" toml:"limits"`
+}
+
+// LimitsConfig configures abuse protection on the signaling WebSocket. The
+// per-message rate limits are built in.
+type LimitsConfig struct {
+	Enabled        bool     `json:"enabled" yaml:"enabled" toml:"enabled"`
+	MaxRoomsPerIP  int      `json:"maxRoomsPerIp" yaml:"maxRoomsPerIp" toml:"maxRoomsPerIp"` // open rooms created from one IP; 0 is unlimited
+	BanThreshold   int      `json:"banThreshold" yaml:"banThreshold" toml:"banThreshold"`    // rate limit violations within banWindow that ban an IP; 0 never bans
+	BanWindow      Duration `json:"banWindow" yaml:"banWindow" toml:"banWindow"`
+	BanDuration    Duration `json:"banDuration" yaml:"banDuration" toml:"banDuration"`
+	TrustedProxies []string `json:"trustedProxies" yaml:"trustedProxies" toml:"trustedProxies"` // IPs or CIDRs of reverse proxies whose X-Forwarded-For is believed
+}
+
+// Proxies parses TrustedProxies; a plain IP is a prefix of its own.
+func (l LimitsConfig) Proxies() ([]netip.Prefix, error) {
+	prefixes := make([]netip.Prefix, 0, len(l.TrustedProxies))
+	for _, s := range l.TrustedProxies {
+		if addr, err := netip.ParseAddr(s); err == nil {
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q is neither an IP nor a CIDR", s)
+		}
+		prefixes = append(prefixes, p.Masked())
+	}
+	return prefixes, nil
+}
+
+// ShutdownConfig configures graceful shutdown.
+type ShutdownConfig struct {
+	DrainTimeout Duration `json:"drainTimeout" yaml:"drainTimeout" toml:"drainTimeout"` // time allowed for clients to disconnect
+	ReconnectURL string   `json:"reconnectUrl" yaml:"reconnectUrl" toml:"reconnectUrl"` // sent to clients as a reconnect hint
+	RetryAfter   Duration `json:"retryAfter" yaml:"retryAfter" toml:"retryAfter"`       // suggested delay before reconnecting
+}
+
+// TLSConfig configures HTTPS.
+type TLSConfig struct {
+	Cert         string   `json:"cert" yaml:"cert" toml:"cert"`
+	Key          string   `json:"key" yaml:"key" toml:"key"`
+	SelfSigned   bool     `json:"selfSigned" yaml:"selfSigned" toml:"selfSigned"`
+	Dir          string   `json:"dir" yaml:"dir" toml:"dir"`
+	Hosts        []string `json:"hosts" yaml:"hosts" toml:"hosts"`
+	HTTPRedirect string   `json:"httpRedirect" yaml:"httpRedirect" toml:"httpRedirect"`
+}
+
+// Enabled reports whether HTTPS is configured.
+func (c TLSConfig) Enabled() bool {
+	return c.Cert != "" || c.Key != "" || c.SelfSigned
+}
+
+// AuthConfig configures API and join authentication.
+type AuthConfig struct {
+	AdminToken   string `json:"adminToken" yaml:"adminToken" toml:"adminToken" secret:"true"`
+	APIKeysFile  string `json:"apiKeysFile" yaml:"apiKeysFile" toml:"apiKeysFile"`
+	JWKSFile     string `json:"jwksFile" yaml:"jwksFile" toml:"jwksFile"`
+	JWTIssuer    string `json:"jwtIssuer" yaml:"jwtIssuer" toml:"jwtIssuer"`
+	JWTAudience  string `json:"jwtAudience" yaml:"jwtAudience" toml:"jwtAudience"`
+	JoinJWKSFile string `json:"joinJwksFile" yaml:"joinJwksFile" toml:"joinJwksFile"`
+	JoinIssuer   string `json:"joinIssuer" yaml:"joinIssuer" toml:"joinIssuer"`
+	JoinAudience string `json:"joinAudience" yaml:"joinAudience" toml:"joinAudience"`
+}
+
+// SFUConfig configures room lifetime.
+type SFUConfig struct {
+	GracePeriod Duration `json:"gracePeriod" yaml:"gracePeriod" toml:"gracePeriod"`
+	GCInterval  Duration `json:"gcInterval" yaml:"gcInterval" toml:"gcInterval"`
+	RoomsFile   string   `json:"roomsFile" yaml:"roomsFile" toml:"roomsFile"`    // JSON file of persistent rooms; empty disables them
+	CodeLength  int      `json:"codeLength" yaml:"codeLength" toml:"codeLength"` // characters in generated room codes
+	DataRate    float64  `json:"dataRate" yaml:"dataRate" toml:"dataRate"`       // data channel messages per second and peer; 0 disables the limit
+	DataBurst   int      `json:"dataBurst" yaml:"dataBurst" toml:"dataBurst"`    // data channel messages a peer may send at once
+}
+
+// ClusterConfig configures multi-node deployments. Nodes sharing a room
+// directory redirect clients to the node that hosts a room.
+type ClusterConfig struct {
+	NodeID        string   `json:"nodeId" yaml:"nodeId" toml:"nodeId"`          // default: host name
+	PublicURL     string   `json:"publicUrl" yaml:"publicUrl" toml:"publicUrl"` // base URL clients use to reach this node
+	Directory     string   `json:"directory" yaml:"directory" toml:"directory"` // memory or redis
+	RedisAddr     string   `json:"redisAddr" yaml:"redisAddr" toml:"redisAddr"` // host:port
+	RedisPassword string   `json:"redisPassword" yaml:"redisPassword" toml:"redisPassword" secret:"true"`
+	RedisDB       int      `json:"redisDb" yaml:"redisDb" toml:"redisDb"`
+	KeyPrefix     string   `json:"keyPrefix" yaml:"keyPrefix" toml:"keyPrefix"`
+	OwnershipTTL  Duration `json:"ownershipTtl" yaml:"ownershipTtl" toml:"ownershipTtl"`            // lifetime of unrefreshed ownership records
+	RelaySecret   string   `json:"relaySecret" yaml:"relaySecret" toml:"relaySecret" secret:"true"` // shared by all nodes; enables relay links
+	Cascade       bool     `json:"cascade" yaml:"cascade" toml:"cascade"`                           // relay other nodes' rooms instead of redirecting
+}
+
+// ICEConfig lists the STUN and TURN servers offered to peer connections.
+type ICEConfig struct {
+	STUNServers    []string `json:"stunServers" yaml:"stunServers" toml:"stunServers"`
+	TURNServers    []string `json:"turnServers" yaml:"turnServers" toml:"turnServers"`
+	TURNUsername   string   `json:"turnUsername" yaml:"turnUsername" toml:"turnUsername"`
+	TURNCredential string   `json:"turnCredential" yaml:"turnCredential" toml:"turnCredential" secret:"true"`
+}
+
+// CodecConfig configures the Opus encoder of the native audio pipeline.
+type CodecConfig struct {
+	Bitrate int  `json:"bitrate" yaml:"bitrate" toml:"bitrate"` // bits per second
+	DTX     bool `json:"dtx" yaml:"dtx" toml:"dtx"`
+}
+
+// AudioConfig configures the native audio pipeline; audio.PipelineConfigFrom
+// and its siblings convert it to the audio package settings.
+type AudioConfig struct {
+	RingBufferFrames int            `json:"ringBufferFrames" yaml:"ringBufferFrames" toml:"ringBufferFrames"`
+	SampleRate       int            `json:"sampleRate" yaml:"sampleRate" toml:"sampleRate"`                // device rate in Hz; 0 is the device default
+	FramesPerBuffer  int            `json:"framesPerBuffer" yaml:"framesPerBuffer" toml:"framesPerBuffer"` // device buffer size; 0 lets PortAudio choose
+	Denoise          bool           `json:"denoise" yaml:"denoise" toml:"denoise"`
+	EchoCancel       bool           `json:"echoCancel" yaml:"echoCancel" toml:"echoCancel"`
+	Normalize        bool           `json:"normalize" yaml:"normalize" toml:"normalize"` // level each peer's stream in the mixer
+	Spatial          string         `json:"spatial" yaml:"spatial" toml:"spatial"`       // off, pan or binaural placement of peers in stereo output
+	AGC              AGCConfig      `json:"agc" yaml:"agc" toml:"agc"`
+	Transmit         TransmitConfig `json:"transmit" yaml:"transmit" toml:"transmit"`
+	Sidetone         SidetoneConfig `json:"sidetone" yaml:"sidetone" toml:"sidetone"`
+}
+
+// SidetoneConfig configures local monitoring: the processed capture played
+// back to the user's own headphones.
+type SidetoneConfig struct {
+	Enabled bool    `json:"enabled" yaml:"enabled" toml:"enabled"`
+	Level   float64 `json:"level" yaml:"level" toml:"level"` // dB relative to the capture
+}
+
+// TransmitConfig configures when the native audio pipeline sends audio.
+type TransmitConfig struct {
+	Mode         string   `json:"mode" yaml:"mode" toml:"mode"` // continuous, ptt or vad
+	VADThreshold float64  `json:"vadThreshold" yaml:"vadThreshold" toml:"vadThreshold"`
+	Hangover     Duration `json:"hangover" yaml:"hangover" toml:"hangover"`
+	PreRoll      Duration `json:"preRoll" yaml:"preRoll" toml:"preRoll"`
+}
+
+// AGCConfig configures automatic gain control of the native audio pipeline.
+type AGCConfig struct {
+	Enabled     bool     `json:"enabled" yaml:"enabled" toml:"enabled"`
+	TargetLevel float64  `json:"targetLevel" yaml:"targetLevel" toml:"targetLevel"` // dBFS
+	MaxGain     float64  `json:"maxGain" yaml:"maxGain" toml:"maxGain"`             // dB
+	Attack      Duration `json:"attack" yaml:"attack" toml:"attack"`
+	Release     Duration `json:"release" yaml:"release" toml:"release"`
+}
+
+// LogConfig configures logging.
+type LogConfig struct {
+	Level  string `json:"level" yaml:"level" toml:"level"`    // debug, info, warn or error
+	Format string `json:"format" yaml:"format" toml:"format"` // console or json
+}
+
+// Default returns the built-in configuration.
+func Default() Config {
+	return Config{
+		Server: ServerConfig{
+			Addr:          ":8080",
+			StatsInterval: Duration{5 * time.Second},
+			Shutdown: ShutdownConfig{
+				DrainTimeout: Duration{10 * time.Second},
+			},
+			Limits: LimitsConfig{
+				Enabled:       true,
+				MaxRoomsPerIP: 10,
+				BanThreshold:  30,
+				BanWindow:     Duration{time.Minute},
+				BanDuration:   Duration{10 * time.Minute},
+			},
+		},
+		SFU: SFUConfig{
+			GracePeriod: Duration{30 * time.Second},
+			GCInterval:  Duration{10 * time.Second},
+			CodeLength:  8,
+			DataRate:    30,
+			DataBurst:   60,
+		},
+		Cluster: ClusterConfig{
+			Directory:    "memory",
+			KeyPrefix:    "voxlink:room:",
+			OwnershipTTL: Duration{60 * time.Second},
+		},
+		ICE: ICEConfig{
+			STUNServers: []string{"stun:stun.l.google.com:19302"},
+		},
+		Codec: CodecConfig{
+			Bitrate: 24000,
+			DTX:     true,
+		},
+		Audio: AudioConfig{
+			RingBufferFrames: 8,
+			Denoise:          true,
+			EchoCancel:       true,
+			Spatial:          modes.SpatialPan,
+			AGC: AGCConfig{
+				Enabled:     true,
+				TargetLevel: -18,
+				MaxGain:     24,
+				Attack:      Duration{20 * time.Millisecond},
+				Release:     Duration{400 * time.Millisecond},
+			},
+			Transmit: TransmitConfig{
+				Mode:         modes.TransmitContinuous,
+				VADThreshold: 0.6,
+				Hangover:     Duration{400 * time.Millisecond},
+				PreRoll:      Duration{100 * time.Millisecond},
+			},
+			Sidetone: SidetoneConfig{
+				Level: -20,
+			},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: "console",
+		},
+	}
+}
+
+var (
+	logLevels   = []string{"debug", "info", "warn", "error"}
+	logFormats  = []string{"console", "json"}
+	directories = []string{"memory", "redis"}
+)
+
+// Validate checks the configuration and reports every problem found, each
+// prefixed with the setting's path (e.g. "sfu.gracePeriod").
+func (c *Config) Validate() error {
+	var errs []error
+	fail := func(path, format string, args ...any) {
+		errs = append(errs, fmt.Errorf("%s: %s", path, fmt.Sprintf(format, args...)))
+	}
+
+	if _, _, err := net.SplitHostPort(c.Server.Addr); err != nil {
+		fail("server.addr", "invalid listen address %q", c.Server.Addr)
+	}
+	if _, err := origin.NewPolicy(c.Server.AllowedOrigins); err != nil {
+		fail("server.allowedOrigins", "%v", err)
+	}
+	if c.Server.StatsInterval.Duration < 0 {
+		fail("server.statsInterval", "must not be negative")
+	}
+
+	if c.Server.Shutdown.DrainTimeout.Duration <= 0 {
+		fail("server.shutdown.drainTimeout", "must be positive")
+	}
+	if c.Server.Shutdown.RetryAfter.Duration < 0 {
+		fail("server.shutdown.retryAfter", "must not be negative")
+	}
+	if u := c.Server.Shutdown.ReconnectURL; u != "" {
+		if parsed, err := url.Parse(u); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			fail("server.shutdown.reconnectUrl", "%q is not an absolute URL", u)
+		}
+	}
+	if l := c.Server.Limits; l.Enabled {
+		if l.MaxRoomsPerIP < 0 {
+			fail("server.limits.maxRoomsPerIp", "must not be negative")
+		}
+		if l.BanThreshold < 0 {
+			fail("server.limits.banThreshold", "must not be negative")
+		}
+		if l.BanThreshold > 0 && (l.BanWindow.Duration <= 0 || l.BanDuration.Duration <= 0) {
+			fail("server.limits", "banWindow and banDuration must be positive when banThreshold is set")
+		}
+		if _, err := l.Proxies(); err != nil {
+			fail("server.limits.trustedProxies", "%v", err)
+		}
+	}
+
+	tls := c.Server.TLS
+	if (tls.Cert == "") != (tls.Key == "") {
+		fail("server.tls", "cert and key must be set together")
+	}
+	if tls.SelfSigned && tls.Cert != "" {
+		fail("server.tls", "selfSigned cannot be combined with cert and key")
+	}
+	if tls.HTTPRedirect != "" {
+		if !tls.Enabled() {
+			fail("server.tls.httpRedirect", "requires TLS")
+		} else if _, _, err := net.SplitHostPort(tls.HTTPRedirect); err != nil {
+			fail("server.tls.httpRedirect", "invalid listen address %q", tls.HTTPRedirect)
+		}
+	}
+
+	if c.Auth.JWKSFile == "" && (c.Auth.JWTIssuer != "" || c.Auth.JWTAudience != "") {
+		fail("auth.jwksFile", "required when jwtIssuer or jwtAudience is set")
+	}
+	if c.Auth.JoinJWKSFile == "" && (c.Auth.JoinIssuer != "" || c.Auth.JoinAudience != "") {
+		fail("auth.joinJwksFile", "required when joinIssuer or joinAudience is set")
+	}
+
+	if c.SFU.GracePeriod.Duration <= 0 {
+		fail("sfu.gracePeriod", "must be positive")
+	}
+	if c.SFU.GCInterval.Duration <= 0 {
+		fail("sfu.gcInterval", "must be positive")
+	}
+	if c.SFU.CodeLength < sfu.MinCodeLength || c.SFU.CodeLength > sfu.MaxCodeLength {
+		fail("sfu.codeLength", "must be between %d and %d, got %d", sfu.MinCodeLength, sfu.MaxCodeLength, c.SFU.CodeLength)
+	}
+	if c.SFU.DataRate < 0 {
+		fail("sfu.dataRate", "must not be negative")
+	}
+	if c.SFU.DataRate > 0 && c.SFU.DataBurst < 1 {
+		fail("sfu.dataBurst", "must be at least 1 when dataRate is set")
+	}
+
+	cl := c.Cluster
+	if !slices.Contains(directories, cl.Directory) {
+		fail("cluster.directory", "%q is not one of %s", cl.Directory, strings.Join(directories, ", "))
+	}
+	if cl.PublicURL != "" {
+		if parsed, err := url.Parse(cl.PublicURL); err != nil || parsed.Scheme == "" || parsed.Host == "" {
+			fail("cluster.publicUrl", "%q is not an absolute URL", cl.PublicURL)
+		}
+	}
+	if cl.Directory == "redis" {
+		if _, _, err := net.SplitHostPort(cl.RedisAddr); err != nil {
+			fail("cluster.redisAddr", "invalid address %q", cl.RedisAddr)
+		}
+		if cl.PublicURL == "" {
+			fail("cluster.publicUrl", "required with a shared directory")
+		}
+	}
+	if cl.Cascade && cl.RelaySecret == "" {
+		fail("cluster.cascade", "requires relaySecret")
+	}
+	if cl.RedisDB < 0 {
+		fail("cluster.redisDb", "must not be negative")
+	}
+	if cl.OwnershipTTL.Duration <= c.SFU.GCInterval.Duration {
+		fail("cluster.ownershipTtl", "must exceed sfu.gcInterval, which is when ownership is refreshed")
+	}
+
+	for _, u := range c.ICE.STUNServers {
+		if !strings.HasPrefix(u, "stun:") && !strings.HasPrefix(u, "stuns:") {
+			fail("ice.stunServers", "%q is not a stun: or stuns: URL", u)
+		}
+	}
+	for _, u := range c.ICE.TURNServers {
+		if !strings.HasPrefix(u, "turn:") && !strings.HasPrefix(u, "turns:") {
+			fail("ice.turnServers", "%q is not a turn: or turns: URL", u)
+		}
+	}
+	if len(c.ICE.TURNServers) > 0 && (c.ICE.TURNUsername == "" || c.ICE.TURNCredential == "") {
+		fail("ice.turnServers", "turnUsername and turnCredential are required")
+	}
+
+	if c.Codec.Bitrate < 6000 || c.Codec.Bitrate > 510000 {
+		fail("codec.bitrate", "%d is outside the Opus range 6000-510000", c.Codec.Bitrate)
+	}
+	if c.Audio.RingBufferFrames < 2 {
+		fail("audio.ringBufferFrames", "must be at least 2, got %d", c.Audio.RingBufferFrames)
+	}
+	if r := c.Audio.SampleRate; r != 0 && (r < 8000 || r > 192000) {
+		fail("audio.sampleRate", "%d is outside 8000-192000 Hz", r)
+	}
+	if c.Audio.FramesPerBuffer < 0 {
+		fail("audio.framesPerBuffer", "must not be negative, got %d", c.Audio.FramesPerBuffer)
+	}
+	if c.Audio.AGC.TargetLevel < -60 || c.Audio.AGC.TargetLevel >= 0 {
+		fail("audio.agc.targetLevel", "%v is outside -60 to 0 dBFS", c.Audio.AGC.TargetLevel)
+	}
+	if c.Audio.AGC.MaxGain < 0 || c.Audio.AGC.MaxGain > 60 {
+		fail("audio.agc.maxGain", "%v is outside 0 to 60 dB", c.Audio.AGC.MaxGain)
+	}
+	if c.Audio.AGC.Attack.Duration <= 0 {
+		fail("audio.agc.attack", "must be positive")
+	}
+	if c.Audio.AGC.Release.Duration <= 0 {
+		fail("audio.agc.release", "must be positive")
+	}
+	if c.Audio.Sidetone.Level < -60 || c.Audio.Sidetone.Level > 0 {
+		fail("audio.sidetone.level", "%v is outside -60 to 0 dB", c.Audio.Sidetone.Level)
+	}
+	if !modes.IsSpatial(c.Audio.Spatial) {
+		fail("audio.spatial", "%q is not one of %s", c.Audio.Spatial, strings.Join(modes.Spatial(), ", "))
+	}
+	if !modes.IsTransmit(c.Audio.Transmit.Mode) {
+		fail("audio.transmit.mode", "%q is not one of %s", c.Audio.Transmit.Mode, strings.Join(modes.Transmit(), ", "))
+	}
+	if c.Audio.Transmit.VADThreshold <= 0 || c.Audio.Transmit.VADThreshold > 1 {
+		fail("audio.transmit.vadThreshold", "%v is outside (0, 1]", c.Audio.Transmit.VADThreshold)
+	}
+	if c.Audio.Transmit.Hangover.Duration < 0 || c.Audio.Transmit.PreRoll.Duration < 0 {
+		fail("audio.transmit", "hangover and preRoll must not be negative")
+	}
+
+	if !slices.Contains(logLevels, c.Log.Level) {
+		fail("log.level", "%q is not one of %s", c.Log.Level, strings.Join(logLevels, ", "))
+	}
+	if !slices.Contains(logFormats, c.Log.Format) {
+		fail("log.format", "%q is not one of %s", c.Log.Format, strings.Join(logFormats, ", "))
+	}
+
+	return errors.Join(errs...)
+}
+
+// Duration is a time.Duration written as a Go duration string ("30s") in
+// config files and environment variables.
+type Duration struct {
+	time.Duration
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Duration) UnmarshalText(text []byte) error {
+	v, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("invalid duration %q", text)
+	}
+	d.Duration = v
+	return nil
+}
+
+// Set implements flag.Value.
+func (d *Duration) Set(s string) error {
+	return d.UnmarshalText([]byte(s))
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"net/netip"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDefault_Valid(t *testing.T) {
+	cfg := Default()
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("default config invalid: %v", err)
+	}
+}
+
+func TestValidate_ReportsEveryProblem(t *testing.T) {
+	cfg := Default()
+	cfg.Server.Addr = "8080"
+	cfg.Server.TLS.Cert = "cert.pem"
+	cfg.SFU.GracePeriod = Duration{}
+	cfg.SFU.CodeLength = 2
+	cfg.SFU.DataBurst = 0
+	cfg.Server.Limits.BanDuration = Duration{}
+	cfg.Server.Limits.TrustedProxies = []string{"10.0.0.0/8", "proxy.internal"}
+	cfg.ICE.STUNServers = []string{"stun.example.com:3478"}
+	cfg.ICE.TURNServers = []string{"turn:turn.example.com"}
+	cfg.Codec.Bitrate = 1000
+	cfg.Audio.AGC.TargetLevel = 6
+	cfg.Audio.Spatial = "surround"
+	cfg.Audio.Sidetone.Level = 10
+	cfg.Audio.Transmit.Mode = "always"
+	cfg.Log.Level = "verbose"
+
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	for _, want := range []string{
+		"server.addr",
+		"server.tls: cert and key must be set together",
+		"sfu.gracePeriod: must be positive",
+		"sfu.codeLength",
+		"sfu.dataBurst",
+		"server.limits: banWindow and banDuration",
+		`server.limits.trustedProxies: "proxy.internal"`,
+		"ice.stunServers",
+		"ice.turnServers: turnUsername and turnCredential are required",
+		"codec.bitrate",
+		"audio.agc.targetLevel",
+		"audio.sidetone.level",
+		"audio.spatial",
+		"audio.transmit.mode",
+		"log.level",
+	} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+}
+
+func TestValidate_RedirectRequiresTLS(t *testing.T) {
+	cfg := Default()
+	cfg.Server.TLS.HTTPRedirect = ":80"
+	if err := cfg.Validate(); err == nil || !strings.Contains(err.Error(), "server.tls.httpRedirect: requires TLS") {
+		t.Fatalf("got %v", err)
+	}
+	cfg.Server.TLS.SelfSigned = true
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidate_Cluster(t *testing.T) {
+	cfg := Default()
+	cfg.Cluster.Directory = "redis"
+	cfg.Cluster.OwnershipTTL = cfg.SFU.GCInterval
+	cfg.Cluster.Cascade = true
+	err := cfg.Validate()
+	for _, want := range []string{
+		"cluster.cascade: requires relaySecret",
+		"cluster.redisAddr",
+		"cluster.publicUrl: required",
+		"cluster.ownershipTtl",
+	} {
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in:\n%v", want, err)
+		}
+	}
+
+	cfg.Cluster.RedisAddr = "localhost:6379"
+	cfg.Cluster.PublicURL = "https://node1.example.com"
+	cfg.Cluster.OwnershipTTL = Duration{time.Minute}
+	cfg.Cluster.RelaySecret = "s3cret"
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDuration_Text(t *testing.T) {
+	var d Duration
+	if err := d.UnmarshalText([]byte("1m30s")); err != nil {
+		t.Fatal(err)
+	}
+	if d.Duration != 90*time.Second {
+		t.Fatalf("got %v", d.Duration)
+	}
+	if text, _ := d.MarshalText(); string(text) != "1m30s" {
+		t.Fatalf("marshal: got %q", text)
+	}
+	if err := d.UnmarshalText([]byte("30")); err == nil {
+		t.Fatal("expected error for duration without unit")
+	}
+}
+
+func TestLimitsConfig_Proxies(t *testing.T) {
+	l := LimitsConfig{TrustedProxies: []string{"10.0.0.7", "10.1.2.3/16", "fd00::/8"}}
+	got, err := l.Proxies()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.7/32"),
+		netip.MustParsePrefix("10.1.0.0/16"),
+		netip.MustParsePrefix("fd00::/8"),
+	}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,346 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// EnvPrefix prefixes every environment variable read by ApplyEnv.
+const EnvPrefix = "VOXLINK_"
+
+// envConfigFile names the config file when -config is not given.
+const envConfigFile = EnvPrefix + "CONFIG"
+
+// envAliases maps variables from earlier releases to their current names.
+var envAliases = map[string]string{
+	EnvPrefix + "ADMIN_TOKEN": EnvPrefix + "AUTH_ADMIN_TOKEN",
+}
+
+// Load builds the effective configuration from, in increasing precedence:
+// the defaults, the config file named by -config or VOXLINK_CONFIG, VOXLINK_*
+// environment variables, and flags given in args. The result is validated.
+// environ is in the form returned by os.Environ.
+func Load(name string, args, environ []string) (Config, error) {
+	// Parse the flags once to find the config file and remember which flags
+	// were set, then replay them on top of the file and environment.
+	probe := Default()
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	probe.RegisterFlags(fs)
+	path := fs.String("config", lookupEnv(environ, envConfigFile), "config file (.yaml, .yml, .toml or .json)")
+	if err := fs.Parse(args); err != nil {
+		return Config{}, err
+	}
+	if fs.NArg() > 0 {
+		return Config{}, fmt.Errorf("unexpected arguments: %s", strings.Join(fs.Args(), " "))
+	}
+
+	cfg := Default()
+	if *path != "" {
+		if err := cfg.LoadFile(*path); err != nil {
+			return Config{}, err
+		}
+	}
+	if err := cfg.ApplyEnv(environ); err != nil {
+		return Config{}, err
+	}
+
+	final := flag.NewFlagSet(name, flag.ContinueOnError)
+	cfg.RegisterFlags(final)
+	var setErr error
+	fs.Visit(func(f *flag.Flag) {
+		if f.Name == "config" || setErr != nil {
+			return
+		}
+		if err := final.Set(f.Name, f.Value.String()); err != nil {
+			setErr = fmt.Errorf("flag -%s: %w", f.Name, err)
+		}
+	})
+	if setErr != nil {
+		return Config{}, setErr
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return Config{}, fmt.Errorf("invalid configuration:\n%w", err)
+	}
+	return cfg, nil
+}
+
+// RegisterFlags defines command-line flags bound to c's fields. Only the
+// settings commonly changed per run have flags; everything else is set in
+// the config file or environment.
+func (c *Config) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Server.Addr, "addr", c.Server.Addr, "HTTP listen address")
+	fs.Var((*listFlag)(&c.Server.AllowedOrigins), "allowed-origins", "comma-separated browser origins allowed to use /ws and /api (e.g. https://*.example.com); same-origin is always allowed")
+	fs.BoolVar(&c.Server.Dev, "dev", c.Server.Dev, "development mode: accept WebSocket and API requests from any origin")
+
+	fs.StringVar(&c.Server.TLS.Cert, "tls-cert", c.Server.TLS.Cert, "TLS certificate file (PEM); reloaded when it changes")
+	fs.StringVar(&c.Server.TLS.Key, "tls-key", c.Server.TLS.Key, "TLS private key file (PEM)")
+	fs.BoolVar(&c.Server.TLS.SelfSigned, "tls-self-signed", c.Server.TLS.SelfSigned, "serve HTTPS with a certificate issued by a generated local CA")
+	fs.StringVar(&c.Server.TLS.Dir, "tls-dir", c.Server.TLS.Dir, "directory for the generated local CA and certificate (default: <user config dir>/voxlink/tls)")
+	fs.Var((*listFlag)(&c.Server.TLS.Hosts), "tls-hosts", "comma-separated extra host names or IPs for the self-signed certificate")
+	fs.StringVar(&c.Server.TLS.HTTPRedirect, "http-redirect", c.Server.TLS.HTTPRedirect, "with TLS, also listen on this address (e.g. :80) and redirect HTTP to HTTPS")
+
+	fs.StringVar(&c.Auth.AdminToken, "admin-token", c.Auth.AdminToken, "API key granted all scopes")
+	fs.StringVar(&c.Auth.APIKeysFile, "api-keys", c.Auth.APIKeysFile, "JSON file with API keys and their scopes")
+	fs.StringVar(&c.Auth.JWKSFile, "jwks", c.Auth.JWKSFile, "JWKS file with keys for verifying API JWTs (HS256/RS256)")
+	fs.StringVar(&c.Auth.JWTIssuer, "jwt-issuer", c.Auth.JWTIssuer, "required iss claim of API JWTs")
+	fs.StringVar(&c.Auth.JWTAudience, "jwt-audience", c.Auth.JWTAudience, "required aud claim of API JWTs")
+	fs.StringVar(&c.Auth.JoinJWKSFile, "join-jwks", c.Auth.JoinJWKSFile, "JWKS file with keys for verifying join tokens; when set, /ws requires a token")
+	fs.StringVar(&c.Auth.JoinIssuer, "join-issuer", c.Auth.JoinIssuer, "required iss claim of join tokens")
+	fs.StringVar(&c.Auth.JoinAudience, "join-audience", c.Auth.JoinAudience, "required aud claim of join tokens")
+
+	fs.StringVar(&c.SFU.RoomsFile, "rooms-file", c.SFU.RoomsFile, "JSON file storing persistent rooms, which survive restarts and are never garbage-collected")
+	fs.IntVar(&c.SFU.CodeLength, "code-length", c.SFU.CodeLength, "characters in generated room codes; each adds almost 5 bits of entropy")
+	fs.Float64Var(&c.SFU.DataRate, "data-rate", c.SFU.DataRate, "data channel messages each peer may send per second; 0 disables the limit")
+	fs.IntVar(&c.SFU.DataBurst, "data-burst", c.SFU.DataBurst, "data channel messages each peer may send at once")
+
+	fs.StringVar(&c.Cluster.NodeID, "node-id", c.Cluster.NodeID, "name of this node in the room directory (default: host name)")
+	fs.StringVar(&c.Cluster.PublicURL, "public-url", c.Cluster.PublicURL, "base URL clients use to reach this node, e.g. https://node1.example.com")
+	fs.StringVar(&c.Cluster.Directory, "room-directory", c.Cluster.Directory, "room directory shared by nodes: memory (single node) or redis")
+	fs.StringVar(&c.Cluster.RedisAddr, "redis-addr", c.Cluster.RedisAddr, "address of the Redis-protocol server for -room-directory=redis")
+	fs.BoolVar(&c.Cluster.Cascade, "cascade", c.Cluster.Cascade, "relay rooms hosted on other nodes instead of redirecting clients (requires cluster.relaySecret)")
+
+	fs.Var(&c.Server.Shutdown.DrainTimeout, "drain-timeout", "time allowed for clients to disconnect on shutdown")
+
+	fs.BoolVar(&c.Server.Limits.Enabled, "rate-limits", c.Server.Limits.Enabled, "rate-limit signaling messages per connection and IP, and ban abusive IPs")
+	fs.IntVar(&c.Server.Limits.MaxRoomsPerIP, "max-rooms-per-ip", c.Server.Limits.MaxRoomsPerIP, "open rooms one IP may create; 0 is unlimited")
+	fs.IntVar(&c.Server.Limits.BanThreshold, "ban-threshold", c.Server.Limits.BanThreshold, "rate limit violations within the ban window that ban an IP; 0 never bans")
+	fs.Var(&c.Server.Limits.BanDuration, "ban-duration", "how long abusive IPs stay banned")
+
+	fs.StringVar(&c.Log.Level, "log-level", c.Log.Level, "log level: debug, info, warn or error")
+	fs.StringVar(&c.Log.Format, "log-format", c.Log.Format, "log format: console or json")
+}
+
+// LoadFile merges the config file at path into c. The format is chosen by
+// the file extension; unknown keys are rejected so typos do not go unnoticed.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read config: %w", err)
+	}
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		if err := dec.Decode(c); err != nil && !errors.Is(err, io.EOF) {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+	case ".toml":
+		md, err := toml.Decode(string(data), c)
+		if err != nil {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+		if undecoded := md.Undecoded(); len(undecoded) > 0 {
+			return fmt.Errorf("parse config %s: unknown key %q", path, undecoded[0].String())
+		}
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(c); err != nil {
+			return fmt.Errorf("parse config %s: %w", path, err)
+		}
+	default:
+		return fmt.Errorf("config %s: unsupported format %q (use .yaml, .toml or .json)", path, ext)
+	}
+	return nil
+}
+
+// ApplyEnv overrides c with VOXLINK_* variables from environ. Each setting's
+// variable is its path in upper snake case, e.g. sfu.gracePeriod is
+// VOXLINK_SFU_GRACE_PERIOD; lists are comma-separated. Unknown VOXLINK_*
+// variables are rejected.
+func (c *Config) ApplyEnv(environ []string) error {
+	vars := make(map[string]string)
+	for _, kv := range environ {
+		k, v, ok := strings.Cut(kv, "=")
+		if ok && strings.HasPrefix(k, EnvPrefix) {
+			vars[k] = v
+		}
+	}
+	for alias, canonical := range envAliases {
+		if v, ok := vars[alias]; ok {
+			if _, set := vars[canonical]; !set {
+				vars[canonical] = v
+			}
+			delete(vars, alias)
+		}
+	}
+	delete(vars, envConfigFile)
+
+	var errs []error
+	walk(reflect.ValueOf(c).Elem(), "", func(path string, field reflect.Value, _ reflect.StructField) {
+		key := envName(path)
+		v, ok := vars[key]
+		if !ok {
+			return
+		}
+		delete(vars, key)
+		if err := setValue(field, v); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", key, err))
+		}
+	})
+	for k := range vars {
+		errs = append(errs, fmt.Errorf("%s: unknown setting", k))
+	}
+	return errors.Join(errs...)
+}
+
+// Print writes c in the given format ("yaml", "toml" or "json") with secrets
+// redacted.
+func (c Config) Print(w io.Writer, format string) error {
+	redacted := c.Redacted()
+	switch format {
+	case "yaml", "":
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(redacted); err != nil {
+			return err
+		}
+		return enc.Close()
+	case "toml":
+		return toml.NewEncoder(w).Encode(redacted)
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(redacted)
+	default:
+		return fmt.Errorf("unsupported format %q (use yaml, toml or json)", format)
+	}
+}
+
+// Redacted returns a copy of c with secret settings masked.
+func (c Config) Redacted() Config {
+	walk(reflect.ValueOf(&c).Elem(), "", func(_ string, field reflect.Value, sf reflect.StructField) {
+		if sf.Tag.Get("secret") == "true" && field.String() != "" {
+			field.SetString("REDACTED")
+		}
+	})
+	return c
+}
+
+var textUnmarshalerType = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// walk calls fn for every leaf setting of the struct v with its dotted path
+// built from the yaml tags.
+func walk(v reflect.Value, prefix string, fn func(path string, field reflect.Value, sf reflect.StructField)) {
+	t := v.Type()
+	for i := range t.NumField() {
+		sf := t.Field(i)
+		name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+		path := name
+		if prefix != "" {
+			path = prefix + "." + name
+		}
+		field := v.Field(i)
+		if field.Kind() == reflect.Struct && !reflect.PointerTo(field.Type()).Implements(textUnmarshalerType) {
+			walk(field, path, fn)
+			continue
+		}
+		fn(path, field, sf)
+	}
+}
+
+// envName converts a setting path such as "server.tls.httpRedirect" to its
+// environment variable, VOXLINK_SERVER_TLS_HTTP_REDIRECT.
+func envName(path string) string {
+	var b strings.Builder
+	b.WriteString(EnvPrefix)
+	for i, seg := range strings.Split(path, ".") {
+		if i > 0 {
+			b.WriteByte('_')
+		}
+		runes := []rune(seg)
+		for j, r := range runes {
+			// Start a new word at a lower-to-upper transition, or at the last
+			// capital of an acronym followed by a lowercase letter ("TLSDir").
+			if j > 0 && unicode.IsUpper(r) && (unicode.IsLower(runes[j-1]) ||
+				j+1 < len(runes) && unicode.IsLower(runes[j+1]) && unicode.IsUpper(runes[j-1])) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+func setValue(field reflect.Value, s string) error {
+	if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		field.SetBool(b)
+	case reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		field.SetInt(int64(n))
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		field.SetFloat(f)
+	case reflect.Slice:
+		field.Set(reflect.ValueOf(splitList(s)))
+	default:
+		return fmt.Errorf("unsupported setting type %s", field.Type())
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	var out []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
+func lookupEnv(environ []string, key string) string {
+	for _, kv := range environ {
+		if k, v, ok := strings.Cut(kv, "="); ok && k == key {
+			return v
+		}
+	}
+	return ""
+}
+
+// listFlag is a flag.Value for a comma-separated list that replaces the
+// configured list when set.
+type listFlag []string
+
+func (l *listFlag) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *listFlag) Set(s string) error {
+	*l = splitList(s)
+	return nil
+}
@@ -0,0 +1,207 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadFile_Formats(t *testing.T) {
+	files := map[string]string{
+		"voxlink.yaml": `
+server:
+  addr: ":9000"
+  allowedOrigins: ["*.example.com"]
+sfu:
+  gracePeriod: 1m
+codec:
+  bitrate: 32000
+`,
+		"voxlink.toml": `
+[server]
+addr = ":9000"
+allowedOrigins = ["*.example.com"]
+
+[sfu]
+gracePeriod = "1m"
+
+[codec]
+bitrate = 32000
+`,
+		"voxlink.json": `{
+  "server": {"addr": ":9000", "allowedOrigins": ["*.example.com"]},
+  "sfu": {"gracePeriod": "1m"},
+  "codec": {"bitrate": 32000}
+}`,
+	}
+	for name, content := range files {
+		t.Run(name, func(t *testing.T) {
+			cfg := Default()
+			if err := cfg.LoadFile(writeFile(t, name, content)); err != nil {
+				t.Fatal(err)
+			}
+			if cfg.Server.Addr != ":9000" || cfg.SFU.GracePeriod.Duration != time.Minute || cfg.Codec.Bitrate != 32000 {
+				t.Fatalf("got %+v", cfg)
+			}
+			if !reflect.DeepEqual(cfg.Server.AllowedOrigins, []string{"*.example.com"}) {
+				t.Fatalf("allowedOrigins: got %v", cfg.Server.AllowedOrigins)
+			}
+			// Settings absent from the file keep their defaults.
+			if cfg.SFU.GCInterval.Duration != 10*time.Second || !cfg.Audio.Denoise {
+				t.Fatalf("defaults lost: %+v", cfg)
+			}
+		})
+	}
+}
+
+func TestLoadFile_UnknownKey(t *testing.T) {
+	for name, content := range map[string]string{
+		"typo.yaml": "sfu:\n  gracePeriode: 1m\n",
+		"typo.toml": "[sfu]\ngracePeriode = \"1m\"\n",
+		"typo.json": `{"sfu": {"gracePeriode": "1m"}}`,
+	} {
+		cfg := Default()
+		err := cfg.LoadFile(writeFile(t, name, content))
+		if err == nil || !strings.Contains(err.Error(), "gracePeriode") {
+			t.Errorf("%s: got %v, want unknown key error", name, err)
+		}
+	}
+}
+
+func TestApplyEnv(t *testing.T) {
+	cfg := Default()
+	err := cfg.ApplyEnv([]string{
+		"VOXLINK_SERVER_ADDR=:7000",
+		"VOXLINK_SERVER_TLS_SELF_SIGNED=true",
+		"VOXLINK_SERVER_TLS_HTTP_REDIRECT=:80",
+		"VOXLINK_SFU_GC_INTERVAL=5s",
+		"VOXLINK_ICE_STUN_SERVERS=stun:a.example.com, stun:b.example.com",
+		"VOXLINK_AUDIO_RING_BUFFER_FRAMES=16",
+		"VOXLINK_SFU_DATA_RATE=12.5",
+		"VOXLINK_SERVER_LIMITS_MAX_ROOMS_PER_IP=3",
+		"VOXLINK_ADMIN_TOKEN=legacy",
+		"HOME=/root",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":7000" || !cfg.Server.TLS.SelfSigned || cfg.Server.TLS.HTTPRedirect != ":80" {
+		t.Fatalf("server: got %+v", cfg.Server)
+	}
+	if cfg.SFU.GCInterval.Duration != 5*time.Second || cfg.Audio.RingBufferFrames != 16 {
+		t.Fatalf("got %+v %+v", cfg.SFU, cfg.Audio)
+	}
+	if cfg.SFU.DataRate != 12.5 || cfg.Server.Limits.MaxRoomsPerIP != 3 {
+		t.Fatalf("got data rate %v, max rooms per IP %d", cfg.SFU.DataRate, cfg.Server.Limits.MaxRoomsPerIP)
+	}
+	if !reflect.DeepEqual(cfg.ICE.STUNServers, []string{"stun:a.example.com", "stun:b.example.com"}) {
+		t.Fatalf("stun: got %v", cfg.ICE.STUNServers)
+	}
+	if cfg.Auth.AdminToken != "legacy" {
+		t.Fatalf("legacy admin token alias not applied: %q", cfg.Auth.AdminToken)
+	}
+}
+
+func TestApplyEnv_Errors(t *testing.T) {
+	cfg := Default()
+	err := cfg.ApplyEnv([]string{"VOXLINK_SFU_GRACE_PERIOD=soon", "VOXLINK_SFU_GRACE=1s"})
+	if err == nil {
+		t.Fatal("expected errors")
+	}
+	for _, want := range []string{"VOXLINK_SFU_GRACE_PERIOD: invalid duration", "VOXLINK_SFU_GRACE: unknown setting"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("missing %q in %v", want, err)
+		}
+	}
+}
+
+func TestLoad_Precedence(t *testing.T) {
+	path := writeFile(t, "voxlink.yaml", "server:\n  addr: \":9000\"\n  dev: true\nlog:\n  level: debug\n")
+	cfg, err := Load("voxlink",
+		[]string{"-config", path, "-addr", ":9100", "-allowed-origins", "a.example.com,b.example.com"},
+		[]string{"VOXLINK_SERVER_ADDR=:9050", "VOXLINK_LOG_LEVEL=warn"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Server.Addr != ":9100" {
+		t.Errorf("addr: got %q, want flag value", cfg.Server.Addr)
+	}
+	if cfg.Log.Level != "warn" {
+		t.Errorf("log level: got %q, want env value", cfg.Log.Level)
+	}
+	if !cfg.Server.Dev {
+		t.Error("dev: file value lost")
+	}
+	if !reflect.DeepEqual(cfg.Server.AllowedOrigins, []string{"a.example.com", "b.example.com"}) {
+		t.Errorf("allowed origins: got %v", cfg.Server.AllowedOrigins)
+	}
+}
+
+func TestLoad_ConfigFromEnvAndValidation(t *testing.T) {
+	path := writeFile(t, "voxlink.json", `{"codec": {"bitrate": 100}}`)
+	_, err := Load("voxlink", nil, []string{"VOXLINK_CONFIG=" + path})
+	if err == nil || !strings.Contains(err.Error(), "codec.bitrate") {
+		t.Fatalf("got %v, want codec.bitrate validation error", err)
+	}
+}
+
+func TestPrint_RedactsSecrets(t *testing.T) {
+	cfg := Default()
+	cfg.Auth.AdminToken = "s3cret"
+	cfg.ICE.TURNCredential = "hunter2"
+	for _, format := range []string{"yaml", "toml", "json"} {
+		var buf bytes.Buffer
+		if err := cfg.Print(&buf, format); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if strings.Contains(out, "s3cret") || strings.Contains(out, "hunter2") {
+			t.Errorf("%s output leaks secrets:\n%s", format, out)
+		}
+		if !strings.Contains(out, "30s") {
+			t.Errorf("%s output lacks durations as strings:\n%s", format, out)
+		}
+
+		// The printed config loads back to the same settings.
+		ext := map[string]string{"yaml": ".yaml", "toml": ".toml", "json": ".json"}[format]
+		back := Default()
+		if err := back.LoadFile(writeFile(t, "printed"+ext, out)); err != nil {
+			t.Fatalf("%s: reload printed config: %v", format, err)
+		}
+		// Compare formatted values: empty lists may come back as nil.
+		if got, want := fmt.Sprintf("%+v", back), fmt.Sprintf("%+v", cfg.Redacted()); got != want {
+			t.Errorf("%s round trip:\ngot  %s\nwant %s", format, got, want)
+		}
+	}
+	if cfg.Auth.AdminToken != "s3cret" {
+		t.Fatal("Print modified the config")
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	for path, want := range map[string]string{
+		"server.addr":             "VOXLINK_SERVER_ADDR",
+		"server.tls.httpRedirect": "VOXLINK_SERVER_TLS_HTTP_REDIRECT",
+		"auth.joinJwksFile":       "VOXLINK_AUTH_JOIN_JWKS_FILE",
+		"audio.ringBufferFrames":  "VOXLINK_AUDIO_RING_BUFFER_FRAMES",
+		"cluster.ownershipTtl":    "VOXLINK_CLUSTER_OWNERSHIP_TTL",
+	} {
+		if got := envName(path); got != want {
+			t.Errorf("envName(%q) = %q, want %q", path, got, want)
+		}
+	}
+}
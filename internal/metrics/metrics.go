@@ -0,0 +1,347 @@
+// Package metrics implements a minimal metrics registry that is exposed in
+// the Prometheus text exposition format. It has no dependency on a Prometheus
+// client library or server.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DefaultBuckets are the histogram buckets (in seconds) used for latencies.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1}
+
+// Default is the registry used by the package-level constructors and Handler.
+var Default = NewRegistry()
+
+type metricType string
+
+const (
+	typeCounter   metricType = "counter"
+	typeGauge     metricType = "gauge"
+	typeHistogram metricType = "histogram"
+)
+
+// family is a named metric with zero or more labelled series.
+type family interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metric families and renders them as text.
+type Registry struct {
+	mu       sync.Mutex
+	families map[string]family
+}
+
+// NewRegistry creates an empty registry.
+func NewRegistry() *Registry {
+	return &Registry{families: make(map[string]family)}
+}
+
+func (r *Registry) register(f family) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.families[f.name()]; exists {
+		panic("metrics: duplicate registration of " + f.name())
+	}
+	r.families[f.name()] = f
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format,
+// sorted by metric name.
+func (r *Registry) WriteText(w io.Writer) {
+	r.mu.Lock()
+	fams := make([]family, 0, len(r.families))
+	for _, f := range r.families {
+		fams = append(fams, f)
+	}
+	r.mu.Unlock()
+
+	sort.Slice(fams, func(i, j int) bool { return fams[i].name() < fams[j].name() })
+	for _, f := range fams {
+		f.write(w)
+	}
+}
+
+// Handler returns an HTTP handler that serves the registry.
+func (r *Registry) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		r.WriteText(w)
+	})
+}
+
+// Handler returns an HTTP handler that serves the default registry.
+func Handler() http.Handler {
+	return Default.Handler()
+}
+
+// Counter is a monotonically increasing value.
+type Counter struct {
+	v atomic.Uint64
+}
+
+// Inc adds one to the counter.
+func (c *Counter) Inc() { c.v.Add(1) }
+
+// Add adds n to the counter.
+func (c *Counter) Add(n uint64) { c.v.Add(n) }
+
+// Value returns the current count.
+func (c *Counter) Value() uint64 { return c.v.Load() }
+
+// Gauge is a value that can go up and down.
+type Gauge struct {
+	v atomic.Int64
+}
+
+// Inc adds one to the gauge.
+func (g *Gauge) Inc() { g.v.Add(1) }
+
+// Dec subtracts one from the gauge.
+func (g *Gauge) Dec() { g.v.Add(-1) }
+
+// Add adds n (which may be negative) to the gauge.
+func (g *Gauge) Add(n int64) { g.v.Add(n) }
+
+// Set sets the gauge to n.
+func (g *Gauge) Set(n int64) { g.v.Store(n) }
+
+// Value returns the current value.
+func (g *Gauge) Value() int64 { return g.v.Load() }
+
+// Histogram counts observations into cumulative buckets.
+type Histogram struct {
+	buckets []float64
+	counts  []atomic.Uint64 // per bucket, non-cumulative; last entry is +Inf
+	count   atomic.Uint64
+	sumBits atomic.Uint64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]atomic.Uint64, len(buckets)+1),
+	}
+}
+
+// Observe records a single observation.
+func (h *Histogram) Observe(v float64) {
+	i := sort.SearchFloat64s(h.buckets, v)
+	h.counts[i].Add(1)
+	h.count.Add(1)
+	for {
+		old := h.sumBits.Load()
+		sum := math.Float64frombits(old) + v
+		if h.sumBits.CompareAndSwap(old, math.Float64bits(sum)) {
+			return
+		}
+	}
+}
+
+// Count returns the number of observations.
+func (h *Histogram) Count() uint64 { return h.count.Load() }
+
+// vec holds the labelled series of one family.
+type vec[T any] struct {
+	fname  string
+	help   string
+	typ    metricType
+	labels []string
+	newFn  func() *T
+	writeS func(w io.Writer, name, labels string, m *T)
+
+	mu     sync.RWMutex
+	series map[string]*T
+	keys   map[string][]string
+}
+
+func (v *vec[T]) name() string { return v.fname }
+
+func (v *vec[T]) with(values ...string) *T {
+	if len(values) != len(v.labels) {
+		panic(fmt.Sprintf("metrics: %s expects %d label values, got %d", v.fname, len(v.labels), len(values)))
+	}
+	key := strings.Join(values, "\xff")
+
+	v.mu.RLock()
+	m, ok := v.series[key]
+	v.mu.RUnlock()
+	if ok {
+		return m
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if m, ok := v.series[key]; ok {
+		return m
+	}
+	m = v.newFn()
+	v.series[key] = m
+	v.keys[key] = append([]string(nil), values...)
+	return m
+}
+
+func (v *vec[T]) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", v.fname, escapeHelp(v.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", v.fname, v.typ)
+
+	v.mu.RLock()
+	keys := make([]string, 0, len(v.series))
+	for k := range v.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		v.writeS(w, v.fname, formatLabels(v.labels, v.keys[k]), v.series[k])
+	}
+	v.mu.RUnlock()
+}
+
+func newVec[T any](r *Registry, name, help string, typ metricType, labels []string,
+	newFn func() *T, writeS func(io.Writer, string, string, *T)) *vec[T] {
+	v := &vec[T]{
+		fname:  name,
+		help:   help,
+		typ:    typ,
+		labels: labels,
+		newFn:  newFn,
+		writeS: writeS,
+		series: make(map[string]*T),
+		keys:   make(map[string][]string),
+	}
+	r.register(v)
+	return v
+}
+
+func writeCounter(w io.Writer, name, labels string, c *Counter) {
+	fmt.Fprintf(w, "%s%s %d\n", name, labels, c.Value())
+}
+
+func writeGauge(w io.Writer, name, labels string, g *Gauge) {
+	fmt.Fprintf(w, "%s%s %d\n", name, labels, g.Value())
+}
+
+func writeHistogram(w io.Writer, name, labels string, h *Histogram) {
+	var cum uint64
+	for i, le := range h.buckets {
+		cum += h.counts[i].Load()
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", formatFloat(le)), cum)
+	}
+	cum += h.counts[len(h.buckets)].Load()
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, withLabel(labels, "le", "+Inf"), cum)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(math.Float64frombits(h.sumBits.Load())))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, h.count.Load())
+}
+
+// CounterVec is a counter partitioned by label values.
+type CounterVec struct{ v *vec[Counter] }
+
+// With returns the counter for the given label values, creating it if needed.
+func (c *CounterVec) With(values ...string) *Counter { return c.v.with(values...) }
+
+// HistogramVec is a histogram partitioned by label values.
+type HistogramVec struct{ v *vec[Histogram] }
+
+// With returns the histogram for the given label values, creating it if needed.
+func (h *HistogramVec) With(values ...string) *Histogram { return h.v.with(values...) }
+
+// NewCounter registers an unlabelled counter on r.
+func (r *Registry) NewCounter(name, help string) *Counter {
+	return r.NewCounterVec(name, help).With()
+}
+
+// NewGauge registers an unlabelled gauge on r.
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	v := newVec(r, name, help, typeGauge, nil, func() *Gauge { return &Gauge{} }, writeGauge)
+	return v.with()
+}
+
+// NewHistogram registers an unlabelled histogram on r. Nil buckets selects DefaultBuckets.
+func (r *Registry) NewHistogram(name, help string, buckets []float64) *Histogram {
+	return r.NewHistogramVec(name, help, buckets).With()
+}
+
+// NewCounterVec registers a counter family with the given label names on r.
+func (r *Registry) NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return &CounterVec{newVec(r, name, help, typeCounter, labels, func() *Counter { return &Counter{} }, writeCounter)}
+}
+
+// NewHistogramVec registers a histogram family with the given label names on r.
+// Nil buckets selects DefaultBuckets.
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	if buckets == nil {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64(nil), buckets...)
+	sort.Float64s(buckets)
+	return &HistogramVec{newVec(r, name, help, typeHistogram, labels, func() *Histogram { return newHistogram(buckets) }, writeHistogram)}
+}
+
+// NewCounter registers an unlabelled counter on the default registry.
+func NewCounter(name, help string) *Counter { return Default.NewCounter(name, help) }
+
+// NewGauge registers an unlabelled gauge on the default registry.
+func NewGauge(name, help string) *Gauge { return Default.NewGauge(name, help) }
+
+// NewHistogram registers an unlabelled histogram on the default registry.
+func NewHistogram(name, help string, buckets []float64) *Histogram {
+	return Default.NewHistogram(name, help, buckets)
+}
+
+// NewCounterVec registers a labelled counter family on the default registry.
+func NewCounterVec(name, help string, labels ...string) *CounterVec {
+	return Default.NewCounterVec(name, help, labels...)
+}
+
+// NewHistogramVec registers a labelled histogram family on the default registry.
+func NewHistogramVec(name, help string, buckets []float64, labels ...string) *HistogramVec {
+	return Default.NewHistogramVec(name, help, buckets, labels...)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteByte('{')
+	for i, n := range names {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(n)
+		b.WriteString(`="`)
+		b.WriteString(escapeLabel(values[i]))
+		b.WriteByte('"')
+	}
+	b.WriteByte('}')
+	return b.String()
+}
+
+func withLabel(labels, name, value string) string {
+	pair := name + `="` + value + `"`
+	if labels == "" {
+		return "{" + pair + "}"
+	}
+	return labels[:len(labels)-1] + "," + pair + "}"
+}
+
+func formatFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+var (
+	helpEscaper  = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+	labelEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+)
+
+func escapeHelp(s string) string  { return helpEscaper.Replace(s) }
+func escapeLabel(s string) string { return labelEscaper.Replace(s) }
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRegistry_TextFormat(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("test_requests_total", "Total requests.")
+	g := r.NewGauge("test_connections", "Open connections.")
+	cv := r.NewCounterVec("test_messages_total", "Messages by type.", "type")
+
+	c.Add(3)
+	g.Inc()
+	g.Inc()
+	g.Dec()
+	cv.With("join").Inc()
+	cv.With("say \"hi\"").Add(2)
+
+	var b strings.Builder
+	r.WriteText(&b)
+	out := b.String()
+
+	for _, want := range []string{
+		"# HELP test_requests_total Total requests.\n# TYPE test_requests_total counter\ntest_requests_total 3\n",
+		"# TYPE test_connections gauge\ntest_connections 1\n",
+		`test_messages_total{type="join"} 1` + "\n",
+		`test_messages_total{type="say \"hi\""} 2` + "\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+
+	// Families are sorted by name.
+	if strings.Index(out, "test_connections") > strings.Index(out, "test_requests_total") {
+		t.Fatalf("families not sorted:\n%s", out)
+	}
+}
+
+func TestHistogram_Buckets(t *testing.T) {
+	r := NewRegistry()
+	h := r.NewHistogramVec("test_latency_seconds", "Latency.", []float64{0.1, 1}, "op")
+
+	h.With("read").Observe(0.05)
+	h.With("read").Observe(0.5)
+	h.With("read").Observe(5)
+
+	var b strings.Builder
+	r.WriteText(&b)
+	out := b.String()
+
+	for _, want := range []string{
+		`test_latency_seconds_bucket{op="read",le="0.1"} 1`,
+		`test_latency_seconds_bucket{op="read",le="1"} 2`,
+		`test_latency_seconds_bucket{op="read",le="+Inf"} 3`,
+		`test_latency_seconds_sum{op="read"} 5.55`,
+		`test_latency_seconds_count{op="read"} 3`,
+	} {
+		if !strings.Contains(out, want+"\n") {
+			t.Fatalf("output missing %q:\n%s", want, out)
+		}
+	}
+}
+
+func TestRegistry_DuplicatePanics(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_dup_total", "Dup.")
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("duplicate registration should panic")
+		}
+	}()
+	r.NewGauge("test_dup_total", "Dup.")
+}
+
+func TestRegistry_Handler(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("test_hits_total", "Hits.").Inc()
+
+	srv := httptest.NewServer(r.Handler())
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Fatalf("content type: got %q", ct)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(body), "test_hits_total 1\n") {
+		t.Fatalf("body missing counter:\n%s", body)
+	}
+}
@@ -0,0 +1,127 @@
+// Package origin decides which cross-origin browser requests may reach the
+// signaling WebSocket and the HTTP API.
+package origin
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy is a list of allowed origins. Patterns are matched with path.Match
+// against the origin's host (e.g. "app.example.com", "*.example.com",
+// "localhost:5173"), or against the full origin if the pattern contains a
+// scheme (e.g. "https://*.example.com"). This is the matching used by
+// websocket.AcceptOptions.OriginPatterns. Same-origin requests are always
+// allowed.
+type Policy struct {
+	patterns []string
+	allowAll bool
+}
+
+// NewPolicy validates patterns and returns a policy allowing them. An empty
+// list allows same-origin requests only.
+func NewPolicy(patterns []string) (*Policy, error) {
+	p := &Policy{}
+	for _, pat := range patterns {
+		pat = strings.ToLower(strings.TrimSpace(pat))
+		if pat == "" {
+			continue
+		}
+		if _, err := path.Match(pat, ""); err != nil {
+			return nil, fmt.Errorf("origin pattern %q: %w", pat, err)
+		}
+		p.patterns = append(p.patterns, pat)
+	}
+	return p, nil
+}
+
+// AllowAll returns a policy that accepts every origin. It is meant for
+// local development only.
+func AllowAll() *Policy {
+	return &Policy{allowAll: true}
+}
+
+// Permissive reports whether the policy accepts every origin.
+func (p *Policy) Permissive() bool {
+	return p != nil && p.allowAll
+}
+
+// Patterns returns the configured origin patterns.
+func (p *Policy) Patterns() []string {
+	if p == nil {
+		return nil
+	}
+	return append([]string(nil), p.patterns...)
+}
+
+// Allowed reports whether a request from origin to host is allowed. An empty
+// origin (a non-browser client) is always allowed.
+func (p *Policy) Allowed(origin, host string) bool {
+	if origin == "" || p.Permissive() {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, host) {
+		return true
+	}
+	full := strings.ToLower(u.Scheme + "://" + u.Host)
+	h := strings.ToLower(u.Host)
+	for _, pat := range p.Patterns() {
+		target := h
+		if strings.Contains(pat, "://") {
+			target = full
+		}
+		if ok, _ := path.Match(pat, target); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// CORS request headers accepted by the API.
+var corsHeaders = strings.Join([]string{"Authorization", "Content-Type", "X-API-Key"}, ", ")
+
+const corsMaxAge = 10 * time.Minute
+
+// CORS wraps next with a CORS policy: allowed cross-origin requests get the
+// Access-Control-* response headers, and preflight requests are answered
+// directly. Requests from other origins are passed through without CORS
+// headers, so browsers refuse to expose the response. A nil policy allows
+// same-origin requests only.
+func (p *Policy) CORS(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		o := r.Header.Get("Origin")
+		if o == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Add("Vary", "Origin")
+		allowed := p.Allowed(o, r.Host)
+		preflight := r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != ""
+		if preflight {
+			if !allowed {
+				http.Error(w, "origin not allowed", http.StatusForbidden)
+				return
+			}
+			h := w.Header()
+			h.Set("Access-Control-Allow-Origin", o)
+			h.Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+			h.Set("Access-Control-Allow-Headers", corsHeaders)
+			h.Set("Access-Control-Max-Age", strconv.Itoa(int(corsMaxAge.Seconds())))
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		if allowed {
+			w.Header().Set("Access-Control-Allow-Origin", o)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
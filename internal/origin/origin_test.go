@@ -0,0 +1,100 @@
+package origin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPolicy_Allowed(t *testing.T) {
+	p, err := NewPolicy([]string{"app.example.com", "*.example.org", "https://*.secure.test", " "})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},                        // non-browser client
+		{"http://voxlink.local", true},    // same origin
+		{"https://app.example.com", true}, // exact host
+		{"https://APP.example.com", true}, // case-insensitive
+		{"https://a.example.org", true},   // wildcard subdomain
+		{"https://a.b.example.org", true}, // nested subdomain
+		{"https://example.org", false},    // wildcard requires a subdomain
+		{"https://x.secure.test", true},   // scheme pattern
+		{"http://x.secure.test", false},   // wrong scheme
+		{"https://evil.com", false},       // not listed
+		{"https://app.example.com.evil.com", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		if got := p.Allowed(tt.origin, "voxlink.local"); got != tt.want {
+			t.Errorf("Allowed(%q) = %v, want %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestPolicy_NilAndAllowAll(t *testing.T) {
+	var p *Policy
+	if p.Allowed("https://evil.com", "voxlink.local") {
+		t.Error("nil policy should allow same-origin only")
+	}
+	if !p.Allowed("https://voxlink.local", "voxlink.local") {
+		t.Error("nil policy should allow same origin")
+	}
+	if !AllowAll().Allowed("https://evil.com", "voxlink.local") {
+		t.Error("AllowAll should allow any origin")
+	}
+}
+
+func TestNewPolicy_BadPattern(t *testing.T) {
+	if _, err := NewPolicy([]string{"[a-"}); err == nil {
+		t.Fatal("expected error for malformed pattern")
+	}
+}
+
+func TestPolicy_CORS(t *testing.T) {
+	p, _ := NewPolicy([]string{"*.example.com"})
+	h := p.CORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	// Preflight from an allowed origin is answered without reaching next.
+	req := httptest.NewRequest(http.MethodOptions, "/api/admin/rooms", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "DELETE")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status: got %d, want 204", w.Code)
+	}
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "https://app.example.com" {
+		t.Fatalf("allow-origin: got %q", got)
+	}
+
+	// Preflight from another origin is refused.
+	req.Header.Set("Origin", "https://evil.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed preflight status: got %d, want 403", w.Code)
+	}
+
+	// Simple requests reach next; only allowed origins get CORS headers.
+	for origin, want := range map[string]string{
+		"https://app.example.com": "https://app.example.com",
+		"https://evil.com":        "",
+	} {
+		req := httptest.NewRequest(http.MethodGet, "/api/room/X", nil)
+		req.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != http.StatusTeapot {
+			t.Fatalf("%s: status %d, want next handler", origin, w.Code)
+		}
+		if got := w.Header().Get("Access-Control-Allow-Origin"); got != want {
+			t.Errorf("%s: allow-origin %q, want %q", origin, got, want)
+		}
+	}
+}
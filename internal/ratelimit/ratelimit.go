@@ -0,0 +1,218 @@
+// Package ratelimit provides keyed token buckets and a temporary ban list,
+// used to protect the signaling server from clients that flood it.
+package ratelimit
+
+import (
+	"maps"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// Rule is a token bucket: Rate events per second on average, up to Burst at
+// once. A rule with a zero Rate is unlimited.
+type Rule struct {
+	Rate  float64 `json:"rate" yaml:"rate" toml:"rate"`
+	Burst int     `json:"burst" yaml:"burst" toml:"burst"`
+}
+
+// Unlimited reports whether the rule allows everything.
+func (r Rule) Unlimited() bool { return r.Rate <= 0 }
+
+// NewLimiter returns a limiter enforcing the rule, or nil if it is unlimited.
+func (r Rule) NewLimiter() *rate.Limiter {
+	if r.Unlimited() {
+		return nil
+	}
+	return rate.NewLimiter(rate.Limit(r.Rate), r.Burst)
+}
+
+// Allow takes a token from l if one is available. Otherwise it returns false
+// and how long until the next token; a nil limiter allows everything.
+func Allow(l *rate.Limiter, now time.Time) (bool, time.Duration) {
+	if l == nil {
+		return true, 0
+	}
+	res := l.ReserveN(now, 1)
+	if !res.OK() {
+		return false, time.Duration(1<<63 - 1)
+	}
+	if d := res.DelayFrom(now); d > 0 {
+		res.CancelAt(now)
+		return false, d
+	}
+	return true, 0
+}
+
+// sweepEvery is how often Keyed and BanList drop state that no longer
+// matters.
+const sweepEvery = time.Minute
+
+// Keyed holds one token bucket per key, e.g. per client IP. Buckets that
+// have refilled completely are forgotten, so memory is bounded by the keys
+// active in the last Burst/Rate seconds. It is safe for concurrent use.
+type Keyed struct {
+	rule Rule
+	now  func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	limiter *rate.Limiter
+	full    time.Time // when the bucket is full again if left alone
+}
+
+// NewKeyed returns token buckets following rule.
+func NewKeyed(rule Rule) *Keyed {
+	return &Keyed{rule: rule, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// Allow takes a token from key's bucket; see the package-level Allow.
+func (k *Keyed) Allow(key string) (bool, time.Duration) {
+	if k.rule.Unlimited() {
+		return true, 0
+	}
+	now := k.now()
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sweepLocked(now)
+
+	b, ok := k.buckets[key]
+	if !ok {
+		b = &bucket{limiter: k.rule.NewLimiter()}
+		k.buckets[key] = b
+	}
+	allowed, wait := Allow(b.limiter, now)
+	if allowed {
+		missing := float64(k.rule.Burst) - b.limiter.TokensAt(now)
+		b.full = now.Add(time.Duration(missing / k.rule.Rate * float64(time.Second)))
+	}
+	return allowed, wait
+}
+
+// Len returns the number of keys with a bucket that is not full.
+func (k *Keyed) Len() int {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.sweepLocked(k.now())
+	return len(k.buckets)
+}
+
+func (k *Keyed) sweepLocked(now time.Time) {
+	if now.Sub(k.lastSweep) < sweepEvery {
+		return
+	}
+	k.lastSweep = now
+	maps.DeleteFunc(k.buckets, func(_ string, b *bucket) bool { return !now.Before(b.full) })
+}
+
+// BanList bans keys temporarily, either explicitly or once they collect
+// Threshold strikes within Window. It is safe for concurrent use.
+type BanList struct {
+	threshold int
+	window    time.Duration
+	duration  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	strikes   map[string][]time.Time
+	bans      map[string]time.Time // key → end of the ban
+	lastSweep time.Time
+}
+
+// NewBanList returns a ban list that bans a key for duration once it has
+// threshold strikes within window. A zero threshold never bans
+// automatically.
+func NewBanList(threshold int, window, duration time.Duration) *BanList {
+	return &BanList{
+		threshold: threshold,
+		window:    window,
+		duration:  duration,
+		now:       time.Now,
+		strikes:   make(map[string][]time.Time),
+		bans:      make(map[string]time.Time),
+	}
+}
+
+// Strike records a violation by key and reports whether it is now banned.
+func (b *BanList) Strike(key string) bool {
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.sweepLocked(now)
+	if until, ok := b.bans[key]; ok && now.Before(until) {
+		return true
+	}
+	if b.threshold <= 0 {
+		return false
+	}
+	recent := b.strikes[key]
+	for len(recent) > 0 && now.Sub(recent[0]) >= b.window {
+		recent = recent[1:]
+	}
+	recent = append(recent, now)
+	if len(recent) < b.threshold {
+		b.strikes[key] = recent
+		return false
+	}
+	delete(b.strikes, key)
+	b.bans[key] = now.Add(b.duration)
+	return true
+}
+
+// Ban bans key for d, or for the list's default duration if d is zero.
+func (b *BanList) Ban(key string, d time.Duration) time.Time {
+	if d <= 0 {
+		d = b.duration
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until := b.now().Add(d)
+	b.bans[key] = until
+	delete(b.strikes, key)
+	return until
+}
+
+// Unban lifts a ban and reports whether key was banned.
+func (b *BanList) Unban(key string) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.bans[key]
+	delete(b.bans, key)
+	delete(b.strikes, key)
+	return ok && b.now().Before(until)
+}
+
+// Banned returns the end of key's ban, if it is banned.
+func (b *BanList) Banned(key string) (time.Time, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	until, ok := b.bans[key]
+	if !ok || !b.now().Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// Bans returns the current bans and when they end.
+func (b *BanList) Bans() map[string]time.Time {
+	now := b.now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.lastSweep = time.Time{}
+	b.sweepLocked(now)
+	return maps.Clone(b.bans)
+}
+
+func (b *BanList) sweepLocked(now time.Time) {
+	if now.Sub(b.lastSweep) < sweepEvery {
+		return
+	}
+	b.lastSweep = now
+	maps.DeleteFunc(b.bans, func(_ string, until time.Time) bool { return !now.Before(until) })
+	maps.DeleteFunc(b.strikes, func(_ string, s []time.Time) bool { return now.Sub(s[len(s)-1]) >= b.window })
+}
@@ -0,0 +1,120 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newClock() *fakeClock { return &fakeClock{t: time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)} }
+
+func TestKeyed_Allow(t *testing.T) {
+	clock := newClock()
+	k := NewKeyed(Rule{Rate: 2, Burst: 3})
+	k.now = clock.now
+
+	for i := range 3 {
+		if ok, _ := k.Allow("10.0.0.1"); !ok {
+			t.Fatalf("request %d of the burst was refused", i+1)
+		}
+	}
+	ok, wait := k.Allow("10.0.0.1")
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("after the burst: got %v, %v; want refused for 500ms", ok, wait)
+	}
+	if ok, _ := k.Allow("10.0.0.2"); !ok {
+		t.Fatal("another key shares the bucket")
+	}
+
+	clock.advance(500 * time.Millisecond)
+	if ok, _ := k.Allow("10.0.0.1"); !ok {
+		t.Fatal("refill was not granted")
+	}
+}
+
+func TestKeyed_ForgetsFullBuckets(t *testing.T) {
+	clock := newClock()
+	k := NewKeyed(Rule{Rate: 1, Burst: 5})
+	k.now = clock.now
+
+	k.Allow("a")
+	k.Allow("b")
+	if n := k.Len(); n != 2 {
+		t.Fatalf("Len: got %d, want 2", n)
+	}
+	clock.advance(sweepEvery)
+	if n := k.Len(); n != 0 {
+		t.Fatalf("Len after refill: got %d, want 0", n)
+	}
+}
+
+func TestKeyed_Unlimited(t *testing.T) {
+	k := NewKeyed(Rule{})
+	for range 1000 {
+		if ok, _ := k.Allow("a"); !ok {
+			t.Fatal("unlimited rule refused")
+		}
+	}
+	if n := k.Len(); n != 0 {
+		t.Fatalf("unlimited rule keeps %d buckets", n)
+	}
+}
+
+func TestBanList_Strikes(t *testing.T) {
+	clock := newClock()
+	b := NewBanList(3, time.Minute, 10*time.Minute)
+	b.now = clock.now
+
+	b.Strike("ip")
+	clock.advance(2 * time.Minute) // the first strike expires
+	b.Strike("ip")
+	if b.Strike("ip") {
+		t.Fatal("banned after two strikes within the window")
+	}
+	if !b.Strike("ip") {
+		t.Fatal("not banned after three strikes within the window")
+	}
+	until, ok := b.Banned("ip")
+	if !ok || !until.Equal(clock.t.Add(10*time.Minute)) {
+		t.Fatalf("Banned: got %v, %v", until, ok)
+	}
+	if _, ok := b.Banned("other"); ok {
+		t.Fatal("unrelated key is banned")
+	}
+
+	clock.advance(10 * time.Minute)
+	if _, ok := b.Banned("ip"); ok {
+		t.Fatal("ban did not expire")
+	}
+	if len(b.Bans()) != 0 {
+		t.Fatalf("expired ban listed: %v", b.Bans())
+	}
+}
+
+func TestBanList_Manual(t *testing.T) {
+	clock := newClock()
+	b := NewBanList(0, time.Minute, time.Hour)
+	b.now = clock.now
+
+	for range 100 {
+		if b.Strike("ip") {
+			t.Fatal("a zero threshold banned automatically")
+		}
+	}
+	if until := b.Ban("ip", 0); !until.Equal(clock.t.Add(time.Hour)) {
+		t.Fatalf("Ban with the default duration ends %v", until)
+	}
+	if _, ok := b.Bans()["ip"]; !ok {
+		t.Fatal("ban not listed")
+	}
+	if !b.Unban("ip") || b.Unban("ip") {
+		t.Fatal("Unban should report the ban once")
+	}
+	if _, ok := b.Banned("ip"); ok {
+		t.Fatal("still banned after Unban")
+	}
+}
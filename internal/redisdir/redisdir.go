@@ -0,0 +1,297 @@
+// Package redisdir implements sfu.RoomDirectory on a Redis-protocol server
+// (Redis, Valkey, KeyDB, ...), so that several VoxLink nodes can share room
+// ownership.
+//
+// Each room code is stored as a key holding the owning node as JSON. Keys
+// expire after the ownership TTL unless the owner refreshes them, so the rooms
+// of a crashed node become available again.
+package redisdir
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"voxlink/internal/sfu"
+)
+
+// Defaults for New.
+const (
+	DefaultKeyPrefix = "voxlink:room:"
+	DefaultTTL       = 60 * time.Second
+)
+
+const dialTimeout = 5 * time.Second
+
+// Directory is a RoomDirectory backed by a Redis-protocol server. It keeps one
+// connection, redialled after an error, and is safe for concurrent use.
+type Directory struct {
+	addr     string
+	password string
+	db       int
+	prefix   string
+	ttl      time.Duration
+
+	mu   sync.Mutex
+	conn net.Conn
+	rd   *bufio.Reader
+}
+
+var _ sfu.RoomDirectory = (*Directory)(nil)
+
+// Option configures a Directory.
+type Option func(*Directory)
+
+// WithPassword authenticates with AUTH after connecting.
+func WithPassword(password string) Option {
+	return func(d *Directory) { d.password = password }
+}
+
+// WithDB selects a logical database after connecting.
+func WithDB(db int) Option {
+	return func(d *Directory) { d.db = db }
+}
+
+// WithKeyPrefix sets the prefix of room keys (default DefaultKeyPrefix).
+func WithKeyPrefix(prefix string) Option {
+	return func(d *Directory) { d.prefix = prefix }
+}
+
+// WithTTL sets how long an ownership record lives without a refresh (default
+// DefaultTTL). It should comfortably exceed the SFU's GC interval, which is
+// when records are refreshed.
+func WithTTL(ttl time.Duration) Option {
+	return func(d *Directory) {
+		if ttl > 0 {
+			d.ttl = ttl
+		}
+	}
+}
+
+// New creates a directory for the server at addr (host:port). The connection
+// is established on first use.
+func New(addr string, opts ...Option) *Directory {
+	d := &Directory{addr: addr, prefix: DefaultKeyPrefix, ttl: DefaultTTL}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Claim implements sfu.RoomDirectory with SET NX PX.
+func (d *Directory) Claim(ctx context.Context, code string, owner sfu.Node) (bool, error) {
+	value, err := json.Marshal(owner)
+	if err != nil {
+		return false, err
+	}
+	reply, err := d.do(ctx, "SET", d.key(code), string(value), "NX", "PX", d.ttlMillis())
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+// Lookup implements sfu.RoomDirectory.
+func (d *Directory) Lookup(ctx context.Context, code string) (sfu.Node, bool, error) {
+	reply, err := d.do(ctx, "GET", d.key(code))
+	if err != nil || reply == nil {
+		return sfu.Node{}, false, err
+	}
+	value, ok := reply.(string)
+	if !ok {
+		return sfu.Node{}, false, fmt.Errorf("redisdir: unexpected GET reply %T", reply)
+	}
+	var owner sfu.Node
+	if err := json.Unmarshal([]byte(value), &owner); err != nil {
+		return sfu.Node{}, false, fmt.Errorf("redisdir: decode owner of %s: %w", code, err)
+	}
+	return owner, true, nil
+}
+
+// Release implements sfu.RoomDirectory. The ownership check and the delete are
+// separate commands; should the record expire and be claimed by another node
+// in between, that node's claim is lost. With a TTL far above the refresh
+// interval this requires the owner to have stalled for a whole TTL.
+func (d *Directory) Release(ctx context.Context, code string, owner sfu.Node) error {
+	cur, ok, err := d.Lookup(ctx, code)
+	if err != nil || !ok || cur.ID != owner.ID {
+		return err
+	}
+	_, err = d.do(ctx, "DEL", d.key(code))
+	return err
+}
+
+// Refresh implements sfu.RoomDirectory. Records of owner get a fresh TTL;
+// records that expired are claimed again, so a room survives a directory
+// outage longer than the TTL unless another node took its code meanwhile.
+func (d *Directory) Refresh(ctx context.Context, codes []string, owner sfu.Node) error {
+	var errs []error
+	for _, code := range codes {
+		cur, ok, err := d.Lookup(ctx, code)
+		switch {
+		case err != nil:
+			errs = append(errs, err)
+		case !ok:
+			if _, err := d.Claim(ctx, code, owner); err != nil {
+				errs = append(errs, err)
+			}
+		case cur.ID == owner.ID:
+			if _, err := d.do(ctx, "PEXPIRE", d.key(code), d.ttlMillis()); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close closes the connection, if any.
+func (d *Directory) Close() error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.conn == nil {
+		return nil
+	}
+	err := d.conn.Close()
+	d.conn, d.rd = nil, nil
+	return err
+}
+
+func (d *Directory) key(code string) string {
+	return d.prefix + code
+}
+
+func (d *Directory) ttlMillis() string {
+	return strconv.FormatInt(d.ttl.Milliseconds(), 10)
+}
+
+// ServerError is an error reply from the server.
+type ServerError string
+
+func (e ServerError) Error() string { return "redisdir: " + string(e) }
+
+// do sends one command and returns its reply: nil for a null reply, a string
+// for simple and bulk strings, an int64 for integers and []any for arrays.
+func (d *Directory) do(ctx context.Context, args ...string) (any, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if err := d.connect(ctx); err != nil {
+		return nil, err
+	}
+	reply, err := d.roundTrip(ctx, args)
+	var serverErr ServerError
+	if err != nil && !errors.As(err, &serverErr) {
+		// The stream may be out of sync; start over on the next call.
+		d.conn.Close()
+		d.conn, d.rd = nil, nil
+	}
+	return reply, err
+}
+
+func (d *Directory) connect(ctx context.Context) error {
+	if d.conn != nil {
+		return nil
+	}
+	dialer := net.Dialer{Timeout: dialTimeout}
+	conn, err := dialer.DialContext(ctx, "tcp", d.addr)
+	if err != nil {
+		return fmt.Errorf("redisdir: %w", err)
+	}
+	d.conn, d.rd = conn, bufio.NewReader(conn)
+
+	var setup [][]string
+	if d.password != "" {
+		setup = append(setup, []string{"AUTH", d.password})
+	}
+	if d.db != 0 {
+		setup = append(setup, []string{"SELECT", strconv.Itoa(d.db)})
+	}
+	for _, cmd := range setup {
+		if _, err := d.roundTrip(ctx, cmd); err != nil {
+			conn.Close()
+			d.conn, d.rd = nil, nil
+			return fmt.Errorf("redisdir: %s: %w", cmd[0], err)
+		}
+	}
+	return nil
+}
+
+func (d *Directory) roundTrip(ctx context.Context, args []string) (any, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(dialTimeout)
+	}
+	d.conn.SetDeadline(deadline)
+
+	buf := fmt.Appendf(nil, "*%d\r\n", len(args))
+	for _, a := range args {
+		buf = fmt.Appendf(buf, "$%d\r\n%s\r\n", len(a), a)
+	}
+	if _, err := d.conn.Write(buf); err != nil {
+		return nil, fmt.Errorf("redisdir: %w", err)
+	}
+	return readReply(d.rd)
+}
+
+func readReply(rd *bufio.Reader) (any, error) {
+	line, err := rd.ReadString('\n')
+	if err != nil {
+		return nil, fmt.Errorf("redisdir: %w", err)
+	}
+	if len(line) < 3 || line[len(line)-2] != '\r' {
+		return nil, fmt.Errorf("redisdir: malformed reply %q", line)
+	}
+	kind, body := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return body, nil
+	case '-':
+		return nil, ServerError(body)
+	case ':':
+		n, err := strconv.ParseInt(body, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("redisdir: malformed integer %q", body)
+		}
+		return n, nil
+	case '$':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redisdir: malformed bulk length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		data := make([]byte, n+2)
+		if _, err := io.ReadFull(rd, data); err != nil {
+			return nil, fmt.Errorf("redisdir: %w", err)
+		}
+		return string(data[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(body)
+		if err != nil {
+			return nil, fmt.Errorf("redisdir: malformed array length %q", body)
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]any, n)
+		for i := range items {
+			if items[i], err = readReply(rd); err != nil {
+				return nil, err
+			}
+		}
+		return items, nil
+	}
+	return nil, fmt.Errorf("redisdir: unknown reply type %q", kind)
+}
@@ -0,0 +1,280 @@
+package redisdir
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"voxlink/internal/sfu"
+)
+
+// fakeRedis is a stand-in for a Redis server that understands the commands
+// the directory sends.
+type fakeRedis struct {
+	password string
+
+	mu      sync.Mutex
+	values  map[string]string
+	expires map[string]time.Time
+	dbs     []string // SELECT arguments seen
+}
+
+func startFakeRedis(t *testing.T, password string) (*fakeRedis, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	f := &fakeRedis{password: password, values: map[string]string{}, expires: map[string]time.Time{}}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go f.serve(conn)
+		}
+	}()
+	return f, ln.Addr().String()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer conn.Close()
+	rd := bufio.NewReader(conn)
+	authed := f.password == ""
+	for {
+		args, err := readCommand(rd)
+		if err != nil {
+			return
+		}
+		cmd := strings.ToUpper(args[0])
+		if !authed && cmd != "AUTH" {
+			io.WriteString(conn, "-NOAUTH Authentication required.\r\n")
+			continue
+		}
+		var reply string
+		switch cmd {
+		case "AUTH":
+			if args[1] != f.password {
+				reply = "-WRONGPASS invalid password\r\n"
+				break
+			}
+			authed = true
+			reply = "+OK\r\n"
+		default:
+			reply = f.exec(cmd, args[1:])
+		}
+		io.WriteString(conn, reply)
+	}
+}
+
+func (f *fakeRedis) exec(cmd string, args []string) string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for k, exp := range f.expires {
+		if time.Now().After(exp) {
+			delete(f.values, k)
+			delete(f.expires, k)
+		}
+	}
+
+	switch cmd {
+	case "SELECT":
+		f.dbs = append(f.dbs, args[0])
+		return "+OK\r\n"
+	case "SET":
+		key, value := args[0], args[1]
+		var nx bool
+		var ttl time.Duration
+		for i := 2; i < len(args); i++ {
+			switch strings.ToUpper(args[i]) {
+			case "NX":
+				nx = true
+			case "PX":
+				i++
+				ms, _ := strconv.Atoi(args[i])
+				ttl = time.Duration(ms) * time.Millisecond
+			}
+		}
+		if _, exists := f.values[key]; exists && nx {
+			return "$-1\r\n"
+		}
+		f.values[key] = value
+		delete(f.expires, key)
+		if ttl > 0 {
+			f.expires[key] = time.Now().Add(ttl)
+		}
+		return "+OK\r\n"
+	case "GET":
+		v, ok := f.values[args[0]]
+		if !ok {
+			return "$-1\r\n"
+		}
+		return fmt.Sprintf("$%d\r\n%s\r\n", len(v), v)
+	case "DEL":
+		_, ok := f.values[args[0]]
+		delete(f.values, args[0])
+		delete(f.expires, args[0])
+		if ok {
+			return ":1\r\n"
+		}
+		return ":0\r\n"
+	case "PEXPIRE":
+		if _, ok := f.values[args[0]]; !ok {
+			return ":0\r\n"
+		}
+		ms, _ := strconv.Atoi(args[1])
+		f.expires[args[0]] = time.Now().Add(time.Duration(ms) * time.Millisecond)
+		return ":1\r\n"
+	}
+	return "-ERR unknown command '" + cmd + "'\r\n"
+}
+
+func (f *fakeRedis) key(k string) (string, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	v, ok := f.values[k]
+	return v, ok
+}
+
+func readCommand(rd *bufio.Reader) ([]string, error) {
+	reply, err := readReply(rd)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]any)
+	if !ok || len(items) == 0 {
+		return nil, fmt.Errorf("not a command: %v", reply)
+	}
+	args := make([]string, len(items))
+	for i, it := range items {
+		args[i], _ = it.(string)
+	}
+	return args, nil
+}
+
+func TestDirectory_ClaimLookupRelease(t *testing.T) {
+	f, addr := startFakeRedis(t, "")
+	d := New(addr, WithKeyPrefix("test:"))
+	defer d.Close()
+	ctx := context.Background()
+
+	a := sfu.Node{ID: "a", URL: "https://a.example.com"}
+	b := sfu.Node{ID: "b", URL: "https://b.example.com"}
+
+	if ok, err := d.Claim(ctx, "ABCD-EFGH", a); err != nil || !ok {
+		t.Fatalf("first claim: %v, %v", ok, err)
+	}
+	if ok, err := d.Claim(ctx, "ABCD-EFGH", b); err != nil || ok {
+		t.Fatalf("second claim: got %v, %v; want false", ok, err)
+	}
+	if _, ok := f.key("test:ABCD-EFGH"); !ok {
+		t.Fatal("key prefix not applied")
+	}
+
+	owner, ok, err := d.Lookup(ctx, "ABCD-EFGH")
+	if err != nil || !ok || owner != a {
+		t.Fatalf("lookup: got %+v, %v, %v", owner, ok, err)
+	}
+	if _, ok, err := d.Lookup(ctx, "NONE-NONE"); err != nil || ok {
+		t.Fatalf("lookup of unknown code: %v, %v", ok, err)
+	}
+
+	if err := d.Release(ctx, "ABCD-EFGH", b); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := d.Lookup(ctx, "ABCD-EFGH"); !ok {
+		t.Fatal("release by a non-owner removed the record")
+	}
+	if err := d.Release(ctx, "ABCD-EFGH", a); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := d.Lookup(ctx, "ABCD-EFGH"); ok {
+		t.Fatal("record survived release by its owner")
+	}
+}
+
+func TestDirectory_TTLAndRefresh(t *testing.T) {
+	_, addr := startFakeRedis(t, "")
+	d := New(addr, WithTTL(100*time.Millisecond))
+	defer d.Close()
+	ctx := context.Background()
+	a := sfu.Node{ID: "a"}
+
+	d.Claim(ctx, "KEEP-KEEP", a)
+	d.Claim(ctx, "DROP-DROP", a)
+	for range 4 {
+		time.Sleep(50 * time.Millisecond)
+		if err := d.Refresh(ctx, []string{"KEEP-KEEP"}, a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, ok, _ := d.Lookup(ctx, "KEEP-KEEP"); !ok {
+		t.Fatal("refreshed record expired")
+	}
+	if _, ok, _ := d.Lookup(ctx, "DROP-DROP"); ok {
+		t.Fatal("unrefreshed record did not expire")
+	}
+
+	// An expired record of a live room is claimed again.
+	if err := d.Refresh(ctx, []string{"DROP-DROP"}, a); err != nil {
+		t.Fatal(err)
+	}
+	if owner, ok, _ := d.Lookup(ctx, "DROP-DROP"); !ok || owner.ID != "a" {
+		t.Fatal("refresh did not reclaim an expired record")
+	}
+}
+
+func TestDirectory_AuthAndSelect(t *testing.T) {
+	f, addr := startFakeRedis(t, "s3cret")
+	ctx := context.Background()
+
+	bad := New(addr, WithPassword("wrong"))
+	defer bad.Close()
+	if _, _, err := bad.Lookup(ctx, "ABCD-EFGH"); err == nil || !strings.Contains(err.Error(), "WRONGPASS") {
+		t.Fatalf("wrong password: got %v", err)
+	}
+
+	d := New(addr, WithPassword("s3cret"), WithDB(3))
+	defer d.Close()
+	if _, err := d.Claim(ctx, "ABCD-EFGH", sfu.Node{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	dbs := f.dbs
+	f.mu.Unlock()
+	if len(dbs) != 1 || dbs[0] != "3" {
+		t.Fatalf("SELECT: got %v, want [3]", dbs)
+	}
+}
+
+func TestDirectory_Reconnect(t *testing.T) {
+	_, addr := startFakeRedis(t, "")
+	d := New(addr)
+	defer d.Close()
+	ctx := context.Background()
+
+	if _, err := d.Claim(ctx, "ABCD-EFGH", sfu.Node{ID: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	// Break the connection behind the directory's back.
+	d.mu.Lock()
+	d.conn.Close()
+	d.mu.Unlock()
+
+	if _, _, err := d.Lookup(ctx, "ABCD-EFGH"); err == nil {
+		t.Fatal("expected an error on the broken connection")
+	}
+	if _, ok, err := d.Lookup(ctx, "ABCD-EFGH"); err != nil || !ok {
+		t.Fatalf("lookup after reconnect: %v, %v", ok, err)
+	}
+}
@@ -0,0 +1,118 @@
+// Package roomstore implements sfu.RoomStore on local storage.
+package roomstore
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+
+	"voxlink/internal/sfu"
+)
+
+// JSONFile stores rooms as a JSON array in a single file, rewritten in full on
+// every change. It suits the handful of persistent rooms a deployment has.
+type JSONFile struct {
+	path string
+
+	mu sync.Mutex
+}
+
+var _ sfu.RoomStore = (*JSONFile)(nil)
+
+// NewJSONFile returns a store backed by the file at path. The file is created
+// on the first Save; a missing file holds no rooms.
+func NewJSONFile(path string) *JSONFile {
+	return &JSONFile{path: path}
+}
+
+// Load implements sfu.RoomStore.
+func (f *JSONFile) Load(ctx context.Context) ([]sfu.RoomRecord, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.read()
+}
+
+// Save implements sfu.RoomStore.
+func (f *JSONFile) Save(ctx context.Context, rec sfu.RoomRecord) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records, err := f.read()
+	if err != nil {
+		return err
+	}
+	i := slices.IndexFunc(records, func(r sfu.RoomRecord) bool { return r.Code == rec.Code })
+	if i >= 0 {
+		records[i] = rec
+	} else {
+		records = append(records, rec)
+	}
+	return f.write(records)
+}
+
+// Delete implements sfu.RoomStore.
+func (f *JSONFile) Delete(ctx context.Context, code string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	records, err := f.read()
+	if err != nil {
+		return err
+	}
+	n := len(records)
+	records = slices.DeleteFunc(records, func(r sfu.RoomRecord) bool { return r.Code == code })
+	if len(records) == n {
+		return nil
+	}
+	return f.write(records)
+}
+
+func (f *JSONFile) read() ([]sfu.RoomRecord, error) {
+	data, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("roomstore: %w", err)
+	}
+	if len(strings.TrimSpace(string(data))) == 0 {
+		return nil, nil
+	}
+	var records []sfu.RoomRecord
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("roomstore: parse %s: %w", f.path, err)
+	}
+	return records, nil
+}
+
+// write replaces the file atomically, so a crash never leaves it truncated.
+func (f *JSONFile) write(records []sfu.RoomRecord) error {
+	if records == nil {
+		records = []sfu.RoomRecord{}
+	}
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*")
+	if err != nil {
+		return fmt.Errorf("roomstore: %w", err)
+	}
+	defer os.Remove(tmp.Name()) // no-op after a successful rename
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("roomstore: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("roomstore: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), f.path); err != nil {
+		return fmt.Errorf("roomstore: %w", err)
+	}
+	return nil
+}
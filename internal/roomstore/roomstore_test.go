@@ -0,0 +1,82 @@
+package roomstore
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"voxlink/internal/sfu"
+)
+
+func TestJSONFile_SaveLoadDelete(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rooms.json")
+	store := NewJSONFile(path)
+	ctx := context.Background()
+
+	records, err := store.Load(ctx)
+	if err != nil || len(records) != 0 {
+		t.Fatalf("missing file: got %v, %v", records, err)
+	}
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	standup := sfu.RoomRecord{
+		Code:      "STANDUP",
+		RoomMeta:  sfu.RoomMeta{Title: "Daily standup", Owner: "alice"},
+		Options:   sfu.RoomOptions{MaxPeers: 12},
+		CreatedAt: created,
+	}
+	if err := store.Save(ctx, standup); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(ctx, sfu.RoomRecord{Code: "RETRO"}); err != nil {
+		t.Fatal(err)
+	}
+	standup.Description = "15 minutes, cameras optional"
+	if err := store.Save(ctx, standup); err != nil {
+		t.Fatal(err)
+	}
+
+	// A fresh store sees the same file.
+	records, err = NewJSONFile(path).Load(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 2 || records[0] != standup || records[1].Code != "RETRO" {
+		t.Fatalf("loaded %+v", records)
+	}
+
+	if err := store.Delete(ctx, "RETRO"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Delete(ctx, "UNKNOWN"); err != nil {
+		t.Fatal(err)
+	}
+	records, _ = store.Load(ctx)
+	if len(records) != 1 || records[0].Code != "STANDUP" {
+		t.Fatalf("after delete: %+v", records)
+	}
+
+	// No temporary files are left behind.
+	entries, _ := os.ReadDir(filepath.Dir(path))
+	if len(entries) != 1 {
+		t.Fatalf("directory holds %d entries, want 1", len(entries))
+	}
+}
+
+func TestJSONFile_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rooms.json")
+	os.WriteFile(path, []byte("{not json"), 0o600)
+	store := NewJSONFile(path)
+	if _, err := store.Load(context.Background()); err == nil {
+		t.Fatal("expected a parse error")
+	}
+	// Saving must not overwrite a file it could not read.
+	if err := store.Save(context.Background(), sfu.RoomRecord{Code: "ROOM"}); err == nil {
+		t.Fatal("expected Save to fail")
+	}
+	if data, _ := os.ReadFile(path); string(data) != "{not json" {
+		t.Fatalf("file was rewritten: %q", data)
+	}
+}
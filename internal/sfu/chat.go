@@ -0,0 +1,151 @@
+package sfu
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Chat limits.
+const (
+	MaxChatLength      = 2000 // characters per message
+	DefaultChatHistory = 100  // messages kept per room
+)
+
+// Chat errors.
+var (
+	ErrChatEmpty     = errors.New("message is empty")
+	ErrChatTooLong   = errors.New("message is too long")
+	ErrChatNotFound  = errors.New("message not found")
+	ErrChatForbidden = errors.New("not allowed to change this message")
+	ErrPeerNotFound  = errors.New("peer not found")
+)
+
+// ChatMessage is a text message posted in a room.
+type ChatMessage struct {
+	ID       string
+	PeerID   string
+	Name     string // author's display name when posting
+	Text     string
+	SentAt   time.Time
+	EditedAt time.Time // zero unless edited
+}
+
+// CheckChatText validates the text of a new or edited message.
+func CheckChatText(text string) error {
+	if strings.TrimSpace(text) == "" {
+		return ErrChatEmpty
+	}
+	if utf8.RuneCountInString(text) > MaxChatLength {
+		return ErrChatTooLong
+	}
+	return nil
+}
+
+// PostChat adds a message by the given peer to the room's history. The
+// oldest messages are dropped once the history is full.
+func (r *Room) PostChat(peerID, text string) (ChatMessage, error) {
+	if err := CheckChatText(text); err != nil {
+		return ChatMessage{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	peer, ok := r.peers[peerID]
+	if !ok {
+		return ChatMessage{}, ErrPeerNotFound
+	}
+	msg := ChatMessage{
+		ID:     uuid.NewString(),
+		PeerID: peerID,
+		Name:   peer.Name,
+		Text:   text,
+		SentAt: time.Now().UTC(),
+	}
+	r.appendChatLocked(msg)
+	return msg, nil
+}
+
+// EditChat replaces the text of a message. Only its author may edit it.
+func (r *Room) EditChat(peerID, id, text string) (ChatMessage, error) {
+	if err := CheckChatText(text); err != nil {
+		return ChatMessage{}, err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.chatIndexLocked(id)
+	if i < 0 {
+		return ChatMessage{}, ErrChatNotFound
+	}
+	if r.chat[i].PeerID != peerID {
+		return ChatMessage{}, ErrChatForbidden
+	}
+	r.chat[i].Text = text
+	r.chat[i].EditedAt = time.Now().UTC()
+	return r.chat[i], nil
+}
+
+// DeleteChat removes a message. Its author and the room's hosts may delete
+// it.
+func (r *Room) DeleteChat(peerID, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i := r.chatIndexLocked(id)
+	if i < 0 {
+		return ErrChatNotFound
+	}
+	if r.chat[i].PeerID != peerID {
+		peer, ok := r.peers[peerID]
+		if !ok || peer.Role != RoleHost {
+			return ErrChatForbidden
+		}
+	}
+	r.chat = slices.Delete(r.chat, i, i+1)
+	return nil
+}
+
+// ChatHistory returns a snapshot of the room's messages, oldest first.
+func (r *Room) ChatHistory() []ChatMessage {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return slices.Clone(r.chat)
+}
+
+// PutChat records a message as posted or edited elsewhere, replacing any
+// message with the same ID. Mirrors use it to follow their origin's history.
+func (r *Room) PutChat(msg ChatMessage) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.chatIndexLocked(msg.ID); i >= 0 {
+		r.chat[i] = msg
+		return
+	}
+	r.appendChatLocked(msg)
+}
+
+// RemoveChat removes a message without permission checks; see PutChat.
+func (r *Room) RemoveChat(id string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if i := r.chatIndexLocked(id); i >= 0 {
+		r.chat = slices.Delete(r.chat, i, i+1)
+	}
+}
+
+func (r *Room) appendChatLocked(msg ChatMessage) {
+	limit := r.Options.ChatHistory
+	if limit == 0 {
+		limit = DefaultChatHistory
+	}
+	r.chat = append(r.chat, msg)
+	if n := len(r.chat) - limit; n > 0 {
+		r.chat = slices.Delete(r.chat, 0, n)
+	}
+}
+
+func (r *Room) chatIndexLocked(id string) int {
+	return slices.IndexFunc(r.chat, func(m ChatMessage) bool { return m.ID == id })
+}
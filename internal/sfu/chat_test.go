@@ -0,0 +1,73 @@
+package sfu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRoom_Chat(t *testing.T) {
+	r := NewRoom("TEST-CODE")
+	host := r.AddPeerWithRole("Alice", RoleHost)
+	bob := r.AddPeer("Bob")
+	carol := r.AddPeer("Carol")
+
+	m, err := r.PostChat(bob.ID, "https://example.com/agenda")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.ID == "" || m.Name != "Bob" || m.SentAt.IsZero() || !m.EditedAt.IsZero() {
+		t.Fatalf("posted message: %+v", m)
+	}
+
+	// Only the author edits.
+	if _, err := r.EditChat(carol.ID, m.ID, "hijacked"); err != ErrChatForbidden {
+		t.Fatalf("edit by another peer: got %v", err)
+	}
+	edited, err := r.EditChat(bob.ID, m.ID, "https://example.com/agenda-v2")
+	if err != nil || edited.EditedAt.IsZero() || r.ChatHistory()[0].Text != edited.Text {
+		t.Fatalf("edit: %+v, %v", edited, err)
+	}
+
+	// The author and hosts delete; other members do not.
+	m2, _ := r.PostChat(carol.ID, "hello")
+	if err := r.DeleteChat(bob.ID, m2.ID); err != ErrChatForbidden {
+		t.Fatalf("delete by another member: got %v", err)
+	}
+	if err := r.DeleteChat(host.ID, m2.ID); err != nil {
+		t.Fatalf("delete by host: %v", err)
+	}
+	if err := r.DeleteChat(bob.ID, m.ID); err != nil {
+		t.Fatalf("delete by author: %v", err)
+	}
+	if err := r.DeleteChat(bob.ID, m.ID); err != ErrChatNotFound {
+		t.Fatalf("second delete: got %v", err)
+	}
+	if len(r.ChatHistory()) != 0 {
+		t.Fatalf("history: %+v", r.ChatHistory())
+	}
+
+	if _, err := r.PostChat(bob.ID, "  \n"); err != ErrChatEmpty {
+		t.Fatalf("blank message: got %v", err)
+	}
+	if _, err := r.PostChat(bob.ID, strings.Repeat("é", MaxChatLength+1)); err != ErrChatTooLong {
+		t.Fatalf("long message: got %v", err)
+	}
+	if _, err := r.PostChat(bob.ID, strings.Repeat("é", MaxChatLength)); err != nil {
+		t.Fatalf("message at the limit: %v", err)
+	}
+	if _, err := r.PostChat("nobody", "hi"); err != ErrPeerNotFound {
+		t.Fatalf("unknown peer: got %v", err)
+	}
+}
+
+func TestRoom_ChatHistoryBounded(t *testing.T) {
+	r := NewRoomWithOptions("TEST-CODE", RoomOptions{ChatHistory: 3})
+	p := r.AddPeer("Bob")
+	for _, text := range []string{"1", "2", "3", "4", "5"} {
+		r.PostChat(p.ID, text)
+	}
+	history := r.ChatHistory()
+	if len(history) != 3 || history[0].Text != "3" || history[2].Text != "5" {
+		t.Fatalf("history: %+v", history)
+	}
+}
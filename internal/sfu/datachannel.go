@@ -0,0 +1,120 @@
+package sfu
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/pion/webrtc/v4"
+	"golang.org/x/time/rate"
+)
+
+// Labels of the data channels the SFU opens on every peer connection.
+const (
+	DataChannelOrdered   = "data"           // ordered and reliable
+	DataChannelUnordered = "data-unordered" // unordered, never retransmitted
+)
+
+// Data channel limits.
+const (
+	MaxDataMessageSize = 16 << 10 // bytes; the largest message every browser can send
+	DefaultDataRate    = 30       // messages per second and peer
+	DefaultDataBurst   = 60       // messages a peer may send at once
+)
+
+// Data channel errors.
+var (
+	ErrDataTooLarge   = errors.New("data message is too large")
+	ErrDataMalformed  = errors.New("data message is not a JSON object with a payload")
+	ErrDataNotOpen    = errors.New("data channel is not open")
+	errDataNoChannels = errors.New("peer has no data channels")
+)
+
+// DataMessage is an application message relayed between the peers of a room,
+// e.g. a reaction, a cursor position or game state. Clients send it with To
+// naming the recipients, or empty to reach everyone else in the room. The SFU
+// sets From before forwarding; it never interprets Payload.
+type DataMessage struct {
+	From    string          `json:"from,omitempty"`
+	To      []string        `json:"to,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// ParseDataMessage decodes a message received from the peer with the given
+// ID and stamps it as sent by that peer.
+func ParseDataMessage(from string, data []byte) (DataMessage, error) {
+	if len(data) > MaxDataMessageSize {
+		return DataMessage{}, ErrDataTooLarge
+	}
+	var msg DataMessage
+	if err := json.Unmarshal(data, &msg); err != nil || len(msg.Payload) == 0 {
+		return DataMessage{}, ErrDataMalformed
+	}
+	msg.From = from
+	return msg, nil
+}
+
+// DataChannels are the SFU's data channels to one peer. Messages arriving
+// on one are forwarded on the channel of the same kind to their recipients,
+// so a sender picks ordered or unordered delivery by the channel it uses.
+type DataChannels struct {
+	Ordered   *webrtc.DataChannel
+	Unordered *webrtc.DataChannel
+
+	limiter *rate.Limiter
+}
+
+// Channel returns the ordered or the unordered channel.
+func (d *DataChannels) Channel(ordered bool) *webrtc.DataChannel {
+	if ordered {
+		return d.Ordered
+	}
+	return d.Unordered
+}
+
+// Allow reports whether the peer may send another message now, consuming a
+// token of its rate limit if so.
+func (d *DataChannels) Allow() bool {
+	return d.limiter == nil || d.limiter.Allow()
+}
+
+// Send delivers an encoded message to the peer as text, the form browsers
+// receive as a string.
+func (d *DataChannels) Send(ordered bool, data []byte) error {
+	if d == nil {
+		return errDataNoChannels
+	}
+	dc := d.Channel(ordered)
+	if dc == nil || dc.ReadyState() != webrtc.DataChannelStateOpen {
+		return ErrDataNotOpen
+	}
+	return dc.SendText(string(data))
+}
+
+// OnMessage calls fn for each message the peer sends on either channel.
+func (d *DataChannels) OnMessage(fn func(ordered bool, data []byte)) {
+	d.Ordered.OnMessage(func(m webrtc.DataChannelMessage) { fn(true, m.Data) })
+	d.Unordered.OnMessage(func(m webrtc.DataChannelMessage) { fn(false, m.Data) })
+}
+
+// createDataChannels opens the peer's data channels on pc. It must run
+// before the offer is created so the SDP negotiates SCTP.
+func (pm *PeerManager) createDataChannels(pc *webrtc.PeerConnection) (*DataChannels, error) {
+	ordered, err := pc.CreateDataChannel(DataChannelOrdered, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create data channel: %w", err)
+	}
+	inOrder, noRetransmits := false, uint16(0)
+	unordered, err := pc.CreateDataChannel(DataChannelUnordered, &webrtc.DataChannelInit{
+		Ordered:        &inOrder,
+		MaxRetransmits: &noRetransmits,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create data channel: %w", err)
+	}
+	d := &DataChannels{Ordered: ordered, Unordered: unordered}
+	if pm.dataRate > 0 {
+		d.limiter = rate.NewLimiter(rate.Limit(pm.dataRate), pm.dataBurst)
+	}
+	return d, nil
+}
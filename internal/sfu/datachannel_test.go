@@ -0,0 +1,85 @@
+package sfu
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestPeerManager_OfferNegotiatesDataChannels(t *testing.T) {
+	pm := NewPeerManager(newTestAPI())
+
+	pc, data, offer, err := pm.CreatePeerConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	if !strings.Contains(offer.SDP, "m=application") || !strings.Contains(offer.SDP, "webrtc-datachannel") {
+		t.Fatalf("offer does not negotiate SCTP:\n%s", offer.SDP)
+	}
+	if data.Ordered.Label() != DataChannelOrdered || !data.Ordered.Ordered() {
+		t.Fatalf("ordered channel: label %q, ordered %v", data.Ordered.Label(), data.Ordered.Ordered())
+	}
+	if data.Unordered.Label() != DataChannelUnordered || data.Unordered.Ordered() {
+		t.Fatalf("unordered channel: label %q, ordered %v", data.Unordered.Label(), data.Unordered.Ordered())
+	}
+	if r := data.Unordered.MaxRetransmits(); r == nil || *r != 0 {
+		t.Fatalf("unordered channel retransmits: got %v, want 0", r)
+	}
+	if err := data.Send(true, []byte(`{}`)); err != ErrDataNotOpen {
+		t.Fatalf("Send before connecting: got %v, want ErrDataNotOpen", err)
+	}
+}
+
+func TestDataChannels_RateLimit(t *testing.T) {
+	pm := NewPeerManager(newTestAPI(), WithDataRateLimit(1, 3))
+	pc, data, _, err := pm.CreatePeerConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	for i := range 3 {
+		if !data.Allow() {
+			t.Fatalf("message %d of the burst was refused", i+1)
+		}
+	}
+	if data.Allow() {
+		t.Fatal("message beyond the burst was allowed")
+	}
+
+	unlimited := NewPeerManager(newTestAPI(), WithDataRateLimit(0, 0))
+	pc2, data2, _, err := unlimited.CreatePeerConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc2.Close()
+	for range 1000 {
+		if !data2.Allow() {
+			t.Fatal("unlimited peer was refused")
+		}
+	}
+}
+
+func TestParseDataMessage(t *testing.T) {
+	msg, err := ParseDataMessage("peer-1", []byte(`{"from":"forged","to":["peer-2"],"payload":{"emoji":"+1"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.From != "peer-1" {
+		t.Fatalf("from: got %q, want the sender", msg.From)
+	}
+	if len(msg.To) != 1 || msg.To[0] != "peer-2" || string(msg.Payload) != `{"emoji":"+1"}` {
+		t.Fatalf("unexpected message %+v", msg)
+	}
+
+	for in, want := range map[string]error{
+		`not json`:          ErrDataMalformed,
+		`{"to":["peer-2"]}`: ErrDataMalformed,
+		`{"payload":"` + strings.Repeat("x", MaxDataMessageSize) + `"}`: ErrDataTooLarge,
+	} {
+		if _, err := ParseDataMessage("peer-1", []byte(in)); err != want {
+			t.Errorf("ParseDataMessage(%.20q): got %v, want %v", in, err, want)
+		}
+	}
+}
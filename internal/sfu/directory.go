@@ -0,0 +1,74 @@
+package sfu
+
+import (
+	"context"
+	"sync"
+)
+
+// Node identifies a VoxLink instance in a multi-node deployment.
+type Node struct {
+	ID  string `json:"id"`
+	URL string `json:"url"` // public base URL clients use to reach the node, e.g. https://node1.example.com
+}
+
+// RoomDirectory records which node owns each room code. All nodes of a
+// deployment share one directory; a node claims a code when it creates a
+// room and releases it when the room closes.
+type RoomDirectory interface {
+	// Claim records owner as the owner of code unless another node already
+	// owns it. It reports whether the claim succeeded.
+	Claim(ctx context.Context, code string, owner Node) (bool, error)
+	// Lookup returns the owner of code.
+	Lookup(ctx context.Context, code string) (Node, bool, error)
+	// Release removes the ownership record of code if it is held by owner.
+	Release(ctx context.Context, code string, owner Node) error
+	// Refresh confirms that owner still holds codes. Directories that expire
+	// ownership records (so that rooms of a crashed node become free again)
+	// extend them; others may ignore it.
+	Refresh(ctx context.Context, codes []string, owner Node) error
+}
+
+// MemoryDirectory is a process-local RoomDirectory, suitable for a single node.
+type MemoryDirectory struct {
+	mu     sync.Mutex
+	owners map[string]Node
+}
+
+// NewMemoryDirectory creates an empty in-memory directory.
+func NewMemoryDirectory() *MemoryDirectory {
+	return &MemoryDirectory{owners: make(map[string]Node)}
+}
+
+// Claim implements RoomDirectory.
+func (d *MemoryDirectory) Claim(_ context.Context, code string, owner Node) (bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if _, taken := d.owners[code]; taken {
+		return false, nil
+	}
+	d.owners[code] = owner
+	return true, nil
+}
+
+// Lookup implements RoomDirectory.
+func (d *MemoryDirectory) Lookup(_ context.Context, code string) (Node, bool, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	owner, ok := d.owners[code]
+	return owner, ok, nil
+}
+
+// Release implements RoomDirectory.
+func (d *MemoryDirectory) Release(_ context.Context, code string, owner Node) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cur, ok := d.owners[code]; ok && cur.ID == owner.ID {
+		delete(d.owners, code)
+	}
+	return nil
+}
+
+// Refresh implements RoomDirectory. Memory records do not expire.
+func (d *MemoryDirectory) Refresh(context.Context, []string, Node) error {
+	return nil
+}
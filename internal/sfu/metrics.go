@@ -0,0 +1,20 @@
+package sfu
+
+import "voxlink/internal/metrics"
+
+var (
+	roomsGauge = metrics.NewGauge("voxlink_rooms",
+		"Number of open rooms.")
+	roomsGCTotal = metrics.NewCounter("voxlink_rooms_gc_total",
+		"Number of empty rooms removed by the garbage collector.")
+	peersGauge = metrics.NewGauge("voxlink_peers",
+		"Number of peers in open rooms.")
+	subscriptionsGauge = metrics.NewGauge("voxlink_subscriptions",
+		"Number of active track forwarding subscriptions.")
+	forwardedPackets = metrics.NewCounter("voxlink_forwarded_packets_total",
+		"RTP packets forwarded to subscribers.")
+	forwardedBytes = metrics.NewCounter("voxlink_forwarded_bytes_total",
+		"RTP bytes forwarded to subscribers.")
+	droppedPackets = metrics.NewCounter("voxlink_dropped_packets_total",
+		"RTP packets lost on a source track (sequence gaps) or that could not be forwarded.")
+)
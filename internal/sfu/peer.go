@@ -8,6 +8,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/pion/interceptor"
 	"github.com/pion/webrtc/v4"
 )
 
@@ -15,39 +16,107 @@ import (
 // to a local track on the subscriber's PeerConnection.
 type Subscription struct {
 	Track  *webrtc.TrackLocalStaticRTP
+	Sender *webrtc.RTPSender
 	Cancel context.CancelFunc
+
+	packets atomic.Uint64
+	bytes   atomic.Uint64
+	missed  atomic.Uint64
+}
+
+// Stats returns the forwarding counters of the subscription.
+func (s *Subscription) Stats() ForwardStats {
+	return ForwardStats{
+		Packets: s.packets.Load(),
+		Bytes:   s.bytes.Load(),
+		Missed:  s.missed.Load(),
+	}
 }
 
 // WebRTCPeer extends Peer with WebRTC connection state.
 type WebRTCPeer struct {
 	*Peer
 	PC                *webrtc.PeerConnection
+	Data              *DataChannels            // nil for peers without data channels
 	Subs              map[string]*Subscription // source peerID -> subscription
 	PendingCandidates []webrtc.ICECandidateInit
 	Mu                sync.Mutex
 }
 
+// DefaultICEServers are offered to peer connections unless WithICEServers is used.
+var DefaultICEServers = []webrtc.ICEServer{
+	{URLs: []string{"stun:stun.l.google.com:19302"}},
+}
+
 // PeerManager handles WebRTC PeerConnection creation and track forwarding.
 type PeerManager struct {
-	api    *webrtc.API
-	logger *slog.Logger
+	api        *webrtc.API
+	stereoAPI  *webrtc.API // for stereo rooms; nil uses api
+	logger     *slog.Logger
+	iceServers []webrtc.ICEServer
+	dataRate   float64 // data messages per second and peer; 0 disables the limit
+	dataBurst  int
+}
+
+// PeerManagerOption configures optional PeerManager fields.
+type PeerManagerOption func(*PeerManager)
+
+// WithICEServers sets the STUN/TURN servers used by new peer connections.
+func WithICEServers(servers []webrtc.ICEServer) PeerManagerOption {
+	return func(pm *PeerManager) {
+		pm.iceServers = servers
+	}
+}
+
+// WithDataRateLimit limits how many data channel messages each peer may send:
+// perSecond on average, up to burst at once. A zero rate disables the limit.
+func WithDataRateLimit(perSecond float64, burst int) PeerManagerOption {
+	return func(pm *PeerManager) {
+		pm.dataRate = perSecond
+		pm.dataBurst = burst
+	}
+}
+
+// WithStereoAPI sets the WebRTC API used for peers of stereo rooms, normally
+// one from NewStereoWebRTCAPI. Without it those peers use the main API.
+func WithStereoAPI(api *webrtc.API) PeerManagerOption {
+	return func(pm *PeerManager) {
+		pm.stereoAPI = api
+	}
 }
 
 // NewPeerManager creates a PeerManager with the given WebRTC API.
-func NewPeerManager(api *webrtc.API) *PeerManager {
-	return &PeerManager{api: api, logger: slog.Default()}
+func NewPeerManager(api *webrtc.API, opts ...PeerManagerOption) *PeerManager {
+	pm := &PeerManager{
+		api:        api,
+		logger:     slog.Default(),
+		iceServers: DefaultICEServers,
+		dataRate:   DefaultDataRate,
+		dataBurst:  DefaultDataBurst,
+	}
+	for _, opt := range opts {
+		opt(pm)
+	}
+	return pm
 }
 
-// CreatePeerConnection creates a new PeerConnection and generates an SDP offer.
-// The SFU acts as the offerer; the client will answer.
-func (pm *PeerManager) CreatePeerConnection() (*webrtc.PeerConnection, webrtc.SessionDescription, error) {
-	pc, err := pm.api.NewPeerConnection(webrtc.Configuration{
-		ICEServers: []webrtc.ICEServer{
-			{URLs: []string{"stun:stun.l.google.com:19302"}},
-		},
-	})
+// CreatePeerConnection creates a new PeerConnection with the peer's data
+// channels and generates an SDP offer. The SFU acts as the offerer; the
+// client will answer.
+func (pm *PeerManager) CreatePeerConnection() (*webrtc.PeerConnection, *DataChannels, webrtc.SessionDescription, error) {
+	return pm.CreatePeerConnectionWithOptions(RoomOptions{})
+}
+
+// CreatePeerConnectionWithOptions is CreatePeerConnection for a peer of a
+// room with the given options: peers of stereo rooms are offered stereo Opus.
+func (pm *PeerManager) CreatePeerConnectionWithOptions(opts RoomOptions) (*webrtc.PeerConnection, *DataChannels, webrtc.SessionDescription, error) {
+	api := pm.api
+	if opts.Stereo && pm.stereoAPI != nil {
+		api = pm.stereoAPI
+	}
+	pc, err := pm.newPeerConnection(api)
 	if err != nil {
-		return nil, webrtc.SessionDescription{}, fmt.Errorf("new peer connection: %w", err)
+		return nil, nil, webrtc.SessionDescription{}, err
 	}
 
 	// Add a transceiver for receiving audio from the client.
@@ -56,20 +125,42 @@ func (pm *PeerManager) CreatePeerConnection() (*webrtc.PeerConnection, webrtc.Se
 	})
 	if err != nil {
 		pc.Close()
-		return nil, webrtc.SessionDescription{}, fmt.Errorf("add transceiver: %w", err)
+		return nil, nil, webrtc.SessionDescription{}, fmt.Errorf("add transceiver: %w", err)
+	}
+
+	data, err := pm.createDataChannels(pc)
+	if err != nil {
+		pc.Close()
+		return nil, nil, webrtc.SessionDescription{}, err
 	}
 
 	offer, err := pc.CreateOffer(nil)
 	if err != nil {
 		pc.Close()
-		return nil, webrtc.SessionDescription{}, fmt.Errorf("create offer: %w", err)
+		return nil, nil, webrtc.SessionDescription{}, fmt.Errorf("create offer: %w", err)
 	}
 	if err := pc.SetLocalDescription(offer); err != nil {
 		pc.Close()
-		return nil, webrtc.SessionDescription{}, fmt.Errorf("set local desc: %w", err)
+		return nil, nil, webrtc.SessionDescription{}, fmt.Errorf("set local desc: %w", err)
 	}
 
-	return pc, offer, nil
+	return pc, data, offer, nil
+}
+
+// NewPeerConnection creates a bare PeerConnection with the configured ICE
+// servers, for callers that negotiate it themselves (e.g. node relays).
+func (pm *PeerManager) NewPeerConnection() (*webrtc.PeerConnection, error) {
+	return pm.newPeerConnection(pm.api)
+}
+
+func (pm *PeerManager) newPeerConnection(api *webrtc.API) (*webrtc.PeerConnection, error) {
+	pc, err := api.NewPeerConnection(webrtc.Configuration{
+		ICEServers: pm.iceServers,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("new peer connection: %w", err)
+	}
+	return pc, nil
 }
 
 // SubscribeToTrack adds a forwarding track from sourcePeer to subscriberPC.
@@ -78,26 +169,41 @@ func (pm *PeerManager) SubscribeToTrack(
 	ctx context.Context,
 	remoteTrack *webrtc.TrackRemote,
 	subscriberPC *webrtc.PeerConnection,
+) (*Subscription, error) {
+	return pm.SubscribeToTrackAs(ctx, remoteTrack, subscriberPC, remoteTrack.StreamID())
+}
+
+// SubscribeToTrackAs is SubscribeToTrack with the forwarded track placed in
+// the given media stream. Relays use the source peer ID as stream ID so the
+// receiving node can attribute the track.
+func (pm *PeerManager) SubscribeToTrackAs(
+	ctx context.Context,
+	remoteTrack *webrtc.TrackRemote,
+	subscriberPC *webrtc.PeerConnection,
+	streamID string,
 ) (*Subscription, error) {
 	localTrack, err := webrtc.NewTrackLocalStaticRTP(
 		remoteTrack.Codec().RTPCodecCapability,
 		remoteTrack.ID(),
-		remoteTrack.StreamID(),
+		streamID,
 	)
 	if err != nil {
 		return nil, fmt.Errorf("new local track: %w", err)
 	}
 
-	if _, err := subscriberPC.AddTrack(localTrack); err != nil {
+	sender, err := subscriberPC.AddTrack(localTrack)
+	if err != nil {
 		return nil, fmt.Errorf("add track: %w", err)
 	}
 
 	ctx, cancel := context.WithCancel(ctx)
-	sub := &Subscription{Track: localTrack, Cancel: cancel}
+	sub := &Subscription{Track: localTrack, Sender: sender, Cancel: cancel}
 
 	// Forward RTP packets in a goroutine with periodic logging.
 	go func() {
-		var count atomic.Int64
+		subscriptionsGauge.Inc()
+		defer subscriptionsGauge.Dec()
+
 		go func() {
 			ticker := time.NewTicker(5 * time.Second)
 			defer ticker.Stop()
@@ -106,16 +212,18 @@ func (pm *PeerManager) SubscribeToTrack(
 				case <-ctx.Done():
 					return
 				case <-ticker.C:
-					n := count.Load()
+					n := sub.packets.Load()
 					if n > 0 {
 						pm.logger.Info("RTP forwarding",
 							slog.String("track", remoteTrack.ID()),
-							slog.Int64("packets_total", n),
+							slog.Uint64("packets_total", n),
+							slog.Uint64("packets_missed", sub.missed.Load()),
 						)
 					}
 				}
 			}
 		}()
+		var seq seqTracker
 		for {
 			select {
 			case <-ctx.Done():
@@ -129,28 +237,77 @@ func (pm *PeerManager) SubscribeToTrack(
 				return
 			}
 			if err := localTrack.WriteRTP(pkt); err != nil {
+				droppedPackets.Inc()
 				pm.logger.Info("RTP write ended", slog.String("track", remoteTrack.ID()), slog.String("err", err.Error()))
 				return
 			}
-			count.Add(1)
+			if gap := seq.next(pkt.SequenceNumber); gap > 0 {
+				sub.missed.Add(uint64(gap))
+				droppedPackets.Add(uint64(gap))
+			}
+			size := uint64(pkt.MarshalSize())
+			sub.packets.Add(1)
+			sub.bytes.Add(size)
+			forwardedPackets.Inc()
+			forwardedBytes.Add(size)
 		}
 	}()
 
 	return sub, nil
 }
 
+// seqTracker follows the RTP sequence numbers of a source track.
+type seqTracker struct {
+	last    uint16
+	started bool
+}
+
+// next records seq and returns the number of packets missing before it.
+// Large jumps are treated as a stream reset rather than loss.
+func (t *seqTracker) next(seq uint16) uint16 {
+	var gap uint16
+	if t.started {
+		if g := seq - t.last - 1; g < 1000 {
+			gap = g
+		}
+	}
+	t.last, t.started = seq, true
+	return gap
+}
+
+// opusFmtp are the Opus format parameters offered to every peer.
+const opusFmtp = "minptime=10;useinbandfec=1"
+
 // NewWebRTCAPI creates a WebRTC API configured for audio-only (Opus).
+// The default interceptors (NACK, RTCP reports, stats) are registered so that
+// GetStats reports RTP stream statistics.
 func NewWebRTCAPI() *webrtc.API {
+	return newWebRTCAPI(opusFmtp)
+}
+
+// NewStereoWebRTCAPI is NewWebRTCAPI for stereo rooms: the Opus format
+// parameters ask peers to send and accept stereo, which browsers otherwise
+// downmix to mono.
+func NewStereoWebRTCAPI() *webrtc.API {
+	return newWebRTCAPI(opusFmtp + ";stereo=1;sprop-stereo=1")
+}
+
+func newWebRTCAPI(fmtp string) *webrtc.API {
 	m := &webrtc.MediaEngine{}
 	m.RegisterCodec(webrtc.RTPCodecParameters{
 		RTPCodecCapability: webrtc.RTPCodecCapability{
 			MimeType:    "audio/opus",
 			ClockRate:   48000,
 			Channels:    2,
-			SDPFmtpLine: "minptime=10;useinbandfec=1",
+			SDPFmtpLine: fmtp,
 		},
 		PayloadType: 111,
 	}, webrtc.RTPCodecTypeAudio)
 
-	return webrtc.NewAPI(webrtc.WithMediaEngine(m))
+	ir := &interceptor.Registry{}
+	if err := webrtc.RegisterDefaultInterceptors(m, ir); err != nil {
+		slog.Default().Warn("register default interceptors", slog.String("err", err.Error()))
+	}
+
+	return webrtc.NewAPI(webrtc.WithMediaEngine(m), webrtc.WithInterceptorRegistry(ir))
 }
@@ -1,6 +1,7 @@
 package sfu
 
 import (
+	"strings"
 	"testing"
 	"time"
 
@@ -25,7 +26,7 @@ func TestPeerManager_CreateOffer(t *testing.T) {
 	api := newTestAPI()
 	pm := NewPeerManager(api)
 
-	pc, offer, err := pm.CreatePeerConnection()
+	pc, _, offer, err := pm.CreatePeerConnection()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -39,11 +40,26 @@ func TestPeerManager_CreateOffer(t *testing.T) {
 	}
 }
 
+func TestPeerManager_StereoOffer(t *testing.T) {
+	pm := NewPeerManager(newTestAPI(), WithStereoAPI(NewStereoWebRTCAPI()))
+
+	for _, stereo := range []bool{false, true} {
+		pc, _, offer, err := pm.CreatePeerConnectionWithOptions(RoomOptions{Stereo: stereo})
+		if err != nil {
+			t.Fatal(err)
+		}
+		pc.Close()
+		if got := strings.Contains(offer.SDP, "stereo=1"); got != stereo {
+			t.Fatalf("stereo room %v: offer has stereo=1: %v", stereo, got)
+		}
+	}
+}
+
 func TestPeerManager_SetAnswer(t *testing.T) {
 	api := newTestAPI()
 	pm := NewPeerManager(api)
 
-	sfuPC, offer, err := pm.CreatePeerConnection()
+	sfuPC, _, offer, err := pm.CreatePeerConnection()
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -86,3 +102,70 @@ func TestPeerManager_SetAnswer(t *testing.T) {
 		t.Log("connection not established in 5s (expected in vnet-less test)")
 	}
 }
+
+func TestWebRTCPeer_Stats(t *testing.T) {
+	api := newTestAPI()
+	pm := NewPeerManager(api)
+
+	pc, _, _, err := pm.CreatePeerConnection()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pc.Close()
+
+	track, err := webrtc.NewTrackLocalStaticRTP(
+		webrtc.RTPCodecCapability{MimeType: webrtc.MimeTypeOpus, ClockRate: 48000, Channels: 2},
+		"audio", "source-stream",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sub := &Subscription{Track: track, Cancel: func() {}}
+	sub.packets.Store(10)
+	sub.bytes.Store(1200)
+	sub.missed.Store(2)
+
+	wp := &WebRTCPeer{
+		Peer: &Peer{ID: "peer-1", Name: "Alice"},
+		PC:   pc,
+		Subs: map[string]*Subscription{"peer-2": sub},
+	}
+
+	stats := wp.Stats()
+	if stats.PeerID != "peer-1" {
+		t.Fatalf("peer ID: got %q, want %q", stats.PeerID, "peer-1")
+	}
+	if stats.ConnectionState != webrtc.PeerConnectionStateNew.String() {
+		t.Fatalf("connection state: got %q, want %q", stats.ConnectionState, webrtc.PeerConnectionStateNew.String())
+	}
+	if len(stats.Subscriptions) != 1 {
+		t.Fatalf("subscriptions: got %d, want 1", len(stats.Subscriptions))
+	}
+	got := stats.Subscriptions[0]
+	if got.SourcePeerID != "peer-2" || got.TrackID != "audio" {
+		t.Fatalf("subscription: got source=%q track=%q", got.SourcePeerID, got.TrackID)
+	}
+	if got.Forwarded != (ForwardStats{Packets: 10, Bytes: 1200, Missed: 2}) {
+		t.Fatalf("forwarded: got %+v", got.Forwarded)
+	}
+}
+
+func TestSeqTracker(t *testing.T) {
+	var seq seqTracker
+	for _, tc := range []struct {
+		seq  uint16
+		want uint16
+	}{
+		{100, 0},  // first packet
+		{101, 0},  // in order
+		{104, 2},  // 102 and 103 lost
+		{104, 0},  // duplicate
+		{5000, 0}, // stream reset
+		{65535, 0},
+		{1, 1}, // wraps around, 0 lost
+	} {
+		if got := seq.next(tc.seq); got != tc.want {
+			t.Fatalf("seq %d: got gap %d, want %d", tc.seq, got, tc.want)
+		}
+	}
+}
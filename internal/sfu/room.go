@@ -1,58 +1,184 @@
 package sfu
 
 import (
+	"errors"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 )
 
+// Peer roles.
+const (
+	RoleHost   = "host"   // created the room
+	RoleMember = "member" // joined an existing room
+	RoleRelay  = "relay"  // a link from another node relaying this room
+)
+
+// ErrRoomFull is returned by Join when the room has reached its MaxPeers limit.
+var ErrRoomFull = errors.New("room is full")
+
 // Peer represents a user in a room.
 type Peer struct {
-	ID    string
-	Name  string
-	Muted bool
+	ID     string
+	Name   string
+	Muted  bool
+	Role   string
+	UserID string // authenticated user, if join tokens are required
+	Node   string // node the peer is connected to; "" for this node
+}
+
+// Remote reports whether the peer is connected to another node and only
+// federated into this room by a relay.
+func (p *Peer) Remote() bool {
+	return p.Node != ""
+}
+
+// RoomOptions holds per-room settings chosen at creation time.
+type RoomOptions struct {
+	MaxPeers    int  `json:"maxPeers,omitempty"`    // 0 means unlimited
+	ChatHistory int  `json:"chatHistory,omitempty"` // messages kept; 0 means DefaultChatHistory
+	Stereo      bool `json:"stereo,omitempty"`      // negotiate stereo Opus, for music
 }
 
 // Room is a voice session containing peers.
 type Room struct {
-	Code      string
-	created   time.Time
-	closed    chan struct{}
-	closeOnce sync.Once
+	Code       string
+	Options    RoomOptions
+	Origin     Node // owning node if this is a relayed mirror; zero for local rooms
+	Meta       RoomMeta
+	Persistent bool // kept in the SFU's RoomStore and never garbage-collected
+	created    time.Time
+	closed     chan struct{}
+	closeOnce  sync.Once
 
 	mu    sync.RWMutex
 	peers map[string]*Peer
+	chat  []ChatMessage // oldest first
 }
 
 // NewRoom creates a new room with the given code.
 func NewRoom(code string) *Room {
+	return NewRoomWithOptions(code, RoomOptions{})
+}
+
+// NewRoomWithOptions creates a new room with the given code and options.
+func NewRoomWithOptions(code string, opts RoomOptions) *Room {
 	return &Room{
 		Code:    code,
+		Options: opts,
 		created: time.Now(),
 		closed:  make(chan struct{}),
 		peers:   make(map[string]*Peer),
 	}
 }
 
-// AddPeer creates a new peer with a generated ID and adds it to the room.
+// CreatedAt returns the time the room was created.
+func (r *Room) CreatedAt() time.Time {
+	return r.created
+}
+
+// AddPeer creates a new member peer with a generated ID and adds it to the room.
 func (r *Room) AddPeer(name string) *Peer {
+	return r.AddPeerWithRole(name, RoleMember)
+}
+
+// AddPeerWithRole creates a new peer with the given role and adds it to the room.
+func (r *Room) AddPeerWithRole(name, role string) *Peer {
+	return r.AddUserPeer("", name, role)
+}
+
+// AddUserPeer creates a new peer for an authenticated user and adds it to the room.
+func (r *Room) AddUserPeer(userID, name, role string) *Peer {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.addPeerLocked(userID, name, role)
+}
+
+// Join adds a peer for a user joining the room, like AddUserPeer, unless the
+// room has reached its MaxPeers limit, in which case it returns ErrRoomFull.
+// The check and the add happen under one lock, so concurrent joins cannot
+// overshoot the limit.
+func (r *Room) Join(userID, name, role string) (*Peer, error) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if r.Options.MaxPeers > 0 && len(r.peers) >= r.Options.MaxPeers {
+		return nil, ErrRoomFull
+	}
+	return r.addPeerLocked(userID, name, role), nil
+}
 
+// addPeerLocked creates a peer with a generated ID and adds it to the room.
+// r.mu must be held.
+func (r *Room) addPeerLocked(userID, name, role string) *Peer {
 	peer := &Peer{
-		ID:   uuid.NewString(),
-		Name: name,
+		ID:     uuid.NewString(),
+		Name:   name,
+		Role:   role,
+		UserID: userID,
 	}
 	r.peers[peer.ID] = peer
+	r.countPeerLocked()
 	return peer
 }
 
+// AddRemotePeer adds a peer connected to another node, keeping the ID assigned
+// there. It returns false if a peer with that ID already exists.
+func (r *Room) AddRemotePeer(p Peer) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.peers[p.ID]; exists {
+		return false
+	}
+	r.peers[p.ID] = &p
+	r.countPeerLocked()
+	return true
+}
+
+// countPeerLocked adds a new peer to the gauge, unless the room is closed:
+// Close has already subtracted its peers, and RemovePeer will not subtract
+// the new one. r.mu must be held.
+func (r *Room) countPeerLocked() {
+	select {
+	case <-r.closed:
+	default:
+		peersGauge.Inc()
+	}
+}
+
+// RemoveNodePeers removes every peer connected to the given node and returns
+// them.
+func (r *Room) RemoveNodePeers(node string) []Peer {
+	var removed []Peer
+	for _, p := range r.PeerList() {
+		if p.Node == node {
+			r.RemovePeer(p.ID)
+			removed = append(removed, p)
+		}
+	}
+	return removed
+}
+
+// IsMirror reports whether the room is a local mirror of a room owned by
+// another node.
+func (r *Room) IsMirror() bool {
+	return r.Origin.ID != ""
+}
+
 // RemovePeer removes a peer by ID.
 func (r *Room) RemovePeer(id string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
+	if _, ok := r.peers[id]; !ok {
+		return
+	}
 	delete(r.peers, id)
+	// Peers of a closed room were already subtracted from the gauge by Close.
+	select {
+	case <-r.closed:
+	default:
+		peersGauge.Dec()
+	}
 }
 
 // GetPeer returns a peer by ID.
@@ -81,14 +207,48 @@ func (r *Room) PeerCount() int {
 	return len(r.peers)
 }
 
+// IsFull returns true if the room has reached its MaxPeers limit.
+func (r *Room) IsFull() bool {
+	return r.Options.MaxPeers > 0 && r.PeerCount() >= r.Options.MaxPeers
+}
+
 // IsEmpty returns true if the room has no peers.
 func (r *Room) IsEmpty() bool {
 	return r.PeerCount() == 0
 }
 
+// LocalPeerCount returns the number of peers connected to this node.
+func (r *Room) LocalPeerCount() int {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	n := 0
+	for _, p := range r.peers {
+		if !p.Remote() {
+			n++
+		}
+	}
+	return n
+}
+
+// idle reports whether the room may be garbage-collected: a mirror once no
+// local peer is left, any other non-persistent room once it has no peers at
+// all.
+func (r *Room) idle() bool {
+	if r.Persistent {
+		return false
+	}
+	if r.IsMirror() {
+		return r.LocalPeerCount() == 0
+	}
+	return r.IsEmpty()
+}
+
 // Close marks the room as closed.
 func (r *Room) Close() {
 	r.closeOnce.Do(func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		peersGauge.Add(-int64(len(r.peers)))
 		close(r.closed)
 	})
 }
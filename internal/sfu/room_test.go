@@ -1,6 +1,9 @@
 package sfu
 
 import (
+	"errors"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 )
@@ -100,3 +103,80 @@ func TestRoom_Close(t *testing.T) {
 		t.Fatal("Done channel should be closed after Close()")
 	}
 }
+
+func TestRoom_PeersGaugeAfterClose(t *testing.T) {
+	before := peersGauge.Value()
+	room := NewRoom("TEST-CODE")
+	room.AddPeer("Alice")
+	room.Close()
+
+	// Peers added while the room is torn down are never counted.
+	late := room.AddPeer("Bob")
+	room.AddRemotePeer(Peer{ID: "remote", Name: "Carol", Node: "b"})
+	if got := peersGauge.Value() - before; got != 0 {
+		t.Fatalf("peers gauge delta after close: got %d, want 0", got)
+	}
+	room.RemovePeer(late.ID)
+	room.RemovePeer("remote")
+	if got := peersGauge.Value() - before; got != 0 {
+		t.Fatalf("peers gauge delta after removal: got %d, want 0", got)
+	}
+}
+
+func TestRoom_Roles(t *testing.T) {
+	room := NewRoom("TEST-CODE")
+	host := room.AddPeerWithRole("Alice", RoleHost)
+	member := room.AddPeer("Bob")
+
+	if host.Role != RoleHost {
+		t.Fatalf("host role: got %q, want %q", host.Role, RoleHost)
+	}
+	if member.Role != RoleMember {
+		t.Fatalf("member role: got %q, want %q", member.Role, RoleMember)
+	}
+}
+
+func TestRoom_IsFull(t *testing.T) {
+	room := NewRoomWithOptions("TEST-CODE", RoomOptions{MaxPeers: 2})
+	room.AddPeer("Alice")
+	if room.IsFull() {
+		t.Fatal("room with 1/2 peers should not be full")
+	}
+	room.AddPeer("Bob")
+	if !room.IsFull() {
+		t.Fatal("room with 2/2 peers should be full")
+	}
+
+	unlimited := NewRoom("OPEN-ROOM")
+	unlimited.AddPeer("Alice")
+	if unlimited.IsFull() {
+		t.Fatal("room without MaxPeers should never be full")
+	}
+}
+
+func TestRoom_JoinConcurrent(t *testing.T) {
+	room := NewRoomWithOptions("TEST-CODE", RoomOptions{MaxPeers: 3})
+
+	var wg sync.WaitGroup
+	var joined, full atomic.Int32
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := room.Join("", "peer", RoleMember)
+			switch {
+			case err == nil:
+				joined.Add(1)
+			case errors.Is(err, ErrRoomFull):
+				full.Add(1)
+			default:
+				t.Errorf("join: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if joined.Load() != 3 || full.Load() != 17 || room.PeerCount() != 3 {
+		t.Fatalf("joined %d, refused %d, %d peers in a room for 3", joined.Load(), full.Load(), room.PeerCount())
+	}
+}
@@ -1,18 +1,50 @@
 package sfu
 
-import "crypto/rand"
+import (
+	"crypto/rand"
+	"errors"
+	"regexp"
+	"strings"
+)
 
 const roomCodeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
 
+// Room code lengths, counted in characters without dashes. Generated codes
+// are MinCodeLength to MaxCodeLength long; requested codes may be as short
+// as MinRequestedCodeLength, since their owner chose to make them guessable.
+const (
+	DefaultCodeLength      = 8
+	MinCodeLength          = 4
+	MaxCodeLength          = 32
+	MinRequestedCodeLength = 3
+)
+
+// Errors returned for requested room codes.
+var (
+	ErrInvalidCode = errors.New("room code must be 3-32 letters or digits, optionally separated by single dashes")
+	ErrCodeTaken   = errors.New("room code is already in use")
+)
+
+// slugPattern is the grammar of requested codes: letters and digits in groups
+// joined by single dashes, e.g. "standup" or "team-a-retro". Generated codes
+// match it too.
+var slugPattern = regexp.MustCompile(`^[A-Z0-9]+(-[A-Z0-9]+)*$`)
+
 // GenerateRoomCode returns a random room code in the format XXXX-XXXX.
 // Uses crypto/rand with rejection sampling to avoid modular bias.
 func GenerateRoomCode() string {
-	code := make([]byte, 9) // XXXX-XXXX
-	code[4] = '-'
+	return GenerateRoomCodeN(DefaultCodeLength)
+}
+
+// GenerateRoomCodeN returns a random room code of n characters from the
+// unambiguous alphabet, written in dash-separated groups of four (the last
+// group may be shorter). Each character adds almost 5 bits of entropy.
+func GenerateRoomCodeN(n int) string {
+	n = max(n, 1)
+	code := make([]byte, 0, n+(n-1)/4)
 
 	alphaLen := byte(len(roomCodeAlphabet)) // 31
-	pos := 0
-	for pos < 8 {
+	for pos := 0; pos < n; {
 		var b [1]byte
 		if _, err := rand.Read(b[:]); err != nil {
 			panic("crypto/rand failed: " + err.Error())
@@ -20,12 +52,33 @@ func GenerateRoomCode() string {
 		if b[0] >= 248 {
 			continue
 		}
-		idx := pos
-		if pos >= 4 {
-			idx = pos + 1
+		if pos > 0 && pos%4 == 0 {
+			code = append(code, '-')
 		}
-		code[idx] = roomCodeAlphabet[b[0]%alphaLen]
+		code = append(code, roomCodeAlphabet[b[0]%alphaLen])
 		pos++
 	}
 	return string(code)
 }
+
+// ParseRoomCode checks a requested room code against the slug grammar and
+// returns it upper-cased, the form rooms are displayed with.
+func ParseRoomCode(code string) (string, error) {
+	code = strings.ToUpper(strings.TrimSpace(code))
+	n := len(strings.ReplaceAll(code, "-", ""))
+	if n < MinRequestedCodeLength || n > MaxCodeLength || !slugPattern.MatchString(code) {
+		return "", ErrInvalidCode
+	}
+	return code, nil
+}
+
+// lookalikes maps characters that are easily confused when read aloud or
+// copied by hand to a single representative.
+var lookalikes = strings.NewReplacer("0", "O", "1", "I")
+
+// NormalizeRoomCode returns the form room codes are compared in: trimmed,
+// upper-cased, with 0 read as O and 1 as I. Codes that normalize alike name
+// the same room. It is a lookup key, not meant for display.
+func NormalizeRoomCode(code string) string {
+	return lookalikes.Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
@@ -49,3 +49,55 @@ func TestGenerateRoomCode_Uniqueness(t *testing.T) {
 		seen[code] = true
 	}
 }
+
+func TestGenerateRoomCodeN(t *testing.T) {
+	for n, pattern := range map[int]string{
+		4:  "XXXX",
+		6:  "XXXX-XX",
+		12: "XXXX-XXXX-XXXX",
+	} {
+		code := GenerateRoomCodeN(n)
+		if len(code) != len(pattern) {
+			t.Fatalf("GenerateRoomCodeN(%d) = %q, want the shape %s", n, code, pattern)
+		}
+		for i := range pattern {
+			if (pattern[i] == '-') != (code[i] == '-') {
+				t.Fatalf("GenerateRoomCodeN(%d) = %q, want the shape %s", n, code, pattern)
+			}
+		}
+	}
+}
+
+func TestParseRoomCode(t *testing.T) {
+	for in, want := range map[string]string{
+		"standup":        "STANDUP",
+		" Team-A-Retro ": "TEAM-A-RETRO",
+		"abcd-efgh":      "ABCD-EFGH",
+		"r2d":            "R2D",
+	} {
+		got, err := ParseRoomCode(in)
+		if err != nil || got != want {
+			t.Errorf("ParseRoomCode(%q) = %q, %v; want %q", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "ab", "-standup", "standup-", "stand--up", "stand up", "st@ndup", "ärger", strings.Repeat("A", 33)} {
+		if _, err := ParseRoomCode(in); err != ErrInvalidCode {
+			t.Errorf("ParseRoomCode(%q): got %v, want ErrInvalidCode", in, err)
+		}
+	}
+}
+
+func TestNormalizeRoomCode(t *testing.T) {
+	for _, pair := range [][2]string{
+		{"room-1", "ROOM-I"},
+		{"R00M-I", "ROOM-1"},
+		{" abcd-efgh", "ABCD-EFGH"},
+	} {
+		if NormalizeRoomCode(pair[0]) != NormalizeRoomCode(pair[1]) {
+			t.Errorf("%q and %q should normalize alike", pair[0], pair[1])
+		}
+	}
+	if NormalizeRoomCode("ROOM-A") == NormalizeRoomCode("ROOM-B") {
+		t.Error("distinct codes normalized alike")
+	}
+}
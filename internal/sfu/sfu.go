@@ -2,6 +2,8 @@ package sfu
 
 import (
 	"context"
+	"fmt"
+	"log/slog"
 	"sync"
 	"time"
 )
@@ -10,8 +12,26 @@ import (
 type Config struct {
 	GracePeriod time.Duration
 	GCInterval  time.Duration
+
+	// Directory records room ownership across nodes. Nil means a private
+	// MemoryDirectory, i.e. a single-node deployment.
+	Directory RoomDirectory
+	// Node identifies this instance in Directory.
+	Node Node
+	// Store keeps persistent rooms across restarts. Nil disables persistent
+	// rooms.
+	Store RoomStore
+	// CodeLength is the number of characters in generated room codes; 0
+	// means DefaultCodeLength. It is clamped to MinCodeLength-MaxCodeLength.
+	CodeLength int
 }
 
+// directoryTimeout bounds directory calls made outside a request context.
+const directoryTimeout = 5 * time.Second
+
+// maxClaimAttempts bounds the search for an unclaimed room code.
+const maxClaimAttempts = 10
+
 // DefaultConfig returns sensible defaults.
 func DefaultConfig() Config {
 	return Config{
@@ -27,7 +47,7 @@ type SFU struct {
 	cancel context.CancelFunc
 
 	mu    sync.RWMutex
-	rooms map[string]*Room
+	rooms map[string]*Room // by NormalizeRoomCode
 
 	emptyAt map[string]time.Time
 }
@@ -39,6 +59,13 @@ func New() *SFU {
 
 // NewWithConfig creates an SFU with custom configuration.
 func NewWithConfig(cfg Config) *SFU {
+	if cfg.Directory == nil {
+		cfg.Directory = NewMemoryDirectory()
+	}
+	if cfg.CodeLength <= 0 {
+		cfg.CodeLength = DefaultCodeLength
+	}
+	cfg.CodeLength = min(max(cfg.CodeLength, MinCodeLength), MaxCodeLength)
 	ctx, cancel := context.WithCancel(context.Background())
 	s := &SFU{
 		config:  cfg,
@@ -53,39 +80,205 @@ func NewWithConfig(cfg Config) *SFU {
 
 // CreateRoom creates a new room and returns its code.
 func (s *SFU) CreateRoom() string {
+	return s.CreateRoomWithOptions(RoomOptions{})
+}
+
+// CreateRoomWithOptions creates a new room with the given options and returns
+// its code, or "" if the room directory fails; see CreateRoomContext.
+func (s *SFU) CreateRoomWithOptions(opts RoomOptions) string {
+	ctx, cancel := context.WithTimeout(context.Background(), directoryTimeout)
+	defer cancel()
+	code, err := s.CreateRoomContext(ctx, opts)
+	if err != nil {
+		slog.Default().Error("create room", "err", err)
+		return ""
+	}
+	return code
+}
+
+// CreateRoomContext creates a new room with the given options, claims its
+// code in the room directory and returns the code.
+func (s *SFU) CreateRoomContext(ctx context.Context, opts RoomOptions) (string, error) {
+	return s.CreateRoomWithCode(ctx, "", opts)
+}
+
+// CreateRoomWithCode is like CreateRoomContext but uses the requested code,
+// which must satisfy ParseRoomCode, instead of a random one. It returns
+// ErrInvalidCode or ErrCodeTaken if the code cannot be used. An empty code
+// is generated.
+func (s *SFU) CreateRoomWithCode(ctx context.Context, code string, opts RoomOptions) (string, error) {
+	code, err := s.claimCode(ctx, code)
+	if err != nil {
+		return "", err
+	}
+	s.addRoom(NewRoomWithOptions(code, opts))
+	return code, nil
+}
+
+// claimCode claims a requested code, or a random one if code is empty, in
+// the room directory and returns it in display form.
+func (s *SFU) claimCode(ctx context.Context, code string) (string, error) {
+	if code == "" {
+		return s.claimFreeCode(ctx)
+	}
+	code, err := ParseRoomCode(code)
+	if err != nil {
+		return "", err
+	}
+	if _, exists := s.GetRoom(code); exists {
+		return "", ErrCodeTaken
+	}
+	ok, err := s.config.Directory.Claim(ctx, NormalizeRoomCode(code), s.config.Node)
+	if err != nil {
+		return "", fmt.Errorf("claim room code: %w", err)
+	}
+	if !ok {
+		return "", ErrCodeTaken
+	}
+	return code, nil
+}
+
+// claimFreeCode generates room codes until one is unused here and claimed in
+// the room directory.
+func (s *SFU) claimFreeCode(ctx context.Context) (string, error) {
+	for range maxClaimAttempts {
+		code := GenerateRoomCodeN(s.config.CodeLength)
+		if _, exists := s.GetRoom(code); exists {
+			continue
+		}
+		ok, err := s.config.Directory.Claim(ctx, NormalizeRoomCode(code), s.config.Node)
+		if err != nil {
+			return "", fmt.Errorf("claim room code: %w", err)
+		}
+		if ok {
+			return code, nil
+		}
+		// owned by another node
+	}
+	return "", fmt.Errorf("no free room code after %d attempts", maxClaimAttempts)
+}
+
+// addRoom registers a room whose code this node has claimed.
+func (s *SFU) addRoom(room *Room) {
+	key := NormalizeRoomCode(room.Code)
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	s.rooms[key] = room
+	s.emptyAt[key] = time.Now()
+	s.mu.Unlock()
+	roomsGauge.Inc()
+}
 
-	var code string
-	for {
-		code = GenerateRoomCode()
-		if _, exists := s.rooms[code]; !exists {
-			break
+// MirrorRoom returns the local mirror of a room owned by another node,
+// creating it if needed with the owner's options. A mirror is not claimed in
+// the room directory; its peers are relayed to and from the origin by the
+// signaling layer. It fails if a local room with the same code exists.
+func (s *SFU) MirrorRoom(code string, origin Node, opts RoomOptions) (*Room, error) {
+	if origin.ID == "" || origin.ID == s.config.Node.ID {
+		return nil, fmt.Errorf("room %s: cannot mirror a room of node %q", code, origin.ID)
+	}
+	key := NormalizeRoomCode(code)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if room, ok := s.rooms[key]; ok {
+		if room.Origin != origin {
+			return nil, fmt.Errorf("room %s already exists on this node", code)
 		}
+		return room, nil
 	}
+	room := NewRoomWithOptions(code, opts)
+	room.Origin = origin
+	s.rooms[key] = room
+	s.emptyAt[key] = time.Now()
+	roomsGauge.Inc()
+	return room, nil
+}
 
-	room := NewRoom(code)
-	s.rooms[code] = room
-	s.emptyAt[code] = time.Now()
-	return code
+// Node returns this instance's identity in the room directory.
+func (s *SFU) Node() Node {
+	return s.config.Node
+}
+
+// LookupOwner returns the node that owns a room code according to the room
+// directory. It is used to route clients to rooms hosted on other nodes.
+func (s *SFU) LookupOwner(ctx context.Context, code string) (Node, bool, error) {
+	return s.config.Directory.Lookup(ctx, NormalizeRoomCode(code))
 }
 
-// GetRoom returns a room by code.
+// GetRoom returns a room by code. Lookup is case-insensitive and tolerates
+// lookalike characters; see NormalizeRoomCode.
 func (s *SFU) GetRoom(code string) (*Room, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	r, ok := s.rooms[code]
+	r, ok := s.rooms[NormalizeRoomCode(code)]
 	return r, ok
 }
 
-// Close shuts down the SFU and all rooms.
+// Rooms returns a snapshot of all open rooms.
+func (s *SFU) Rooms() []*Room {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	list := make([]*Room, 0, len(s.rooms))
+	for _, r := range s.rooms {
+		list = append(list, r)
+	}
+	return list
+}
+
+// CloseRoom closes a room and removes it immediately, regardless of peers. A
+// persistent room is also deleted from the store. Returns false if the room
+// does not exist.
+func (s *SFU) CloseRoom(code string) bool {
+	key := NormalizeRoomCode(code)
+	s.mu.Lock()
+	room, ok := s.rooms[key]
+	if !ok {
+		s.mu.Unlock()
+		return false
+	}
+	room.Close()
+	delete(s.rooms, key)
+	delete(s.emptyAt, key)
+	roomsGauge.Dec()
+	s.mu.Unlock()
+
+	if !room.IsMirror() {
+		s.release(key)
+	}
+	if room.Persistent && s.config.Store != nil {
+		s.forget(room.Code)
+	}
+	return true
+}
+
+// release removes this node's directory records for closed rooms, given by
+// normalized code.
+func (s *SFU) release(codes ...string) {
+	ctx, cancel := context.WithTimeout(context.Background(), directoryTimeout)
+	defer cancel()
+	for _, code := range codes {
+		if err := s.config.Directory.Release(ctx, code, s.config.Node); err != nil {
+			slog.Default().Warn("release room code", "code", code, "err", err)
+		}
+	}
+}
+
+// Close shuts down the SFU and all rooms, releasing their codes in the room
+// directory. Persistent rooms stay in the store.
 func (s *SFU) Close() {
 	s.cancel()
 	s.mu.Lock()
-	defer s.mu.Unlock()
-	for _, r := range s.rooms {
+	codes := make([]string, 0, len(s.rooms))
+	for code, r := range s.rooms {
 		r.Close()
+		delete(s.rooms, code)
+		delete(s.emptyAt, code)
+		roomsGauge.Dec()
+		if !r.IsMirror() {
+			codes = append(codes, code)
+		}
 	}
+	s.mu.Unlock()
+	s.release(codes...)
 }
 
 func (s *SFU) gcLoop() {
@@ -97,14 +290,21 @@ func (s *SFU) gcLoop() {
 		case <-s.ctx.Done():
 			return
 		case now := <-ticker.C:
+			var removed, live []string
 			s.mu.Lock()
 			for code, room := range s.rooms {
-				if room.IsEmpty() {
+				if room.idle() {
 					if emptySince, ok := s.emptyAt[code]; ok {
 						if now.Sub(emptySince) > s.config.GracePeriod {
 							room.Close()
 							delete(s.rooms, code)
 							delete(s.emptyAt, code)
+							roomsGauge.Dec()
+							roomsGCTotal.Inc()
+							if !room.IsMirror() {
+								removed = append(removed, code)
+							}
+							continue
 						}
 					} else {
 						s.emptyAt[code] = now
@@ -112,8 +312,20 @@ func (s *SFU) gcLoop() {
 				} else {
 					delete(s.emptyAt, code)
 				}
+				if !room.IsMirror() {
+					live = append(live, code)
+				}
 			}
 			s.mu.Unlock()
+
+			s.release(removed...)
+			if len(live) > 0 {
+				ctx, cancel := context.WithTimeout(s.ctx, directoryTimeout)
+				if err := s.config.Directory.Refresh(ctx, live, s.config.Node); err != nil {
+					slog.Default().Warn("refresh room directory", "rooms", len(live), "err", err)
+				}
+				cancel()
+			}
 		}
 	}
 }
@@ -1,6 +1,9 @@
 package sfu
 
 import (
+	"context"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 )
@@ -67,3 +70,269 @@ func TestSFU_RoomNotGCdWithPeers(t *testing.T) {
 		t.Fatal("room with peers should NOT be garbage collected")
 	}
 }
+
+func TestSFU_RoomMetrics(t *testing.T) {
+	s := NewWithConfig(Config{GracePeriod: 50 * time.Millisecond, GCInterval: 20 * time.Millisecond})
+	defer s.Close()
+
+	rooms, gced, peers := roomsGauge.Value(), roomsGCTotal.Value(), peersGauge.Value()
+
+	code := s.CreateRoom()
+	if got := roomsGauge.Value() - rooms; got != 1 {
+		t.Fatalf("rooms gauge delta: got %d, want 1", got)
+	}
+
+	room, _ := s.GetRoom(code)
+	p := room.AddPeer("Alice")
+	if got := peersGauge.Value() - peers; got != 1 {
+		t.Fatalf("peers gauge delta: got %d, want 1", got)
+	}
+	room.RemovePeer(p.ID)
+	room.RemovePeer(p.ID) // removing twice must not double-count
+	if got := peersGauge.Value() - peers; got != 0 {
+		t.Fatalf("peers gauge delta after leave: got %d, want 0", got)
+	}
+
+	time.Sleep(150 * time.Millisecond)
+
+	if got := roomsGCTotal.Value() - gced; got != 1 {
+		t.Fatalf("rooms GC counter delta: got %d, want 1", got)
+	}
+	if got := roomsGauge.Value() - rooms; got != 0 {
+		t.Fatalf("rooms gauge delta after GC: got %d, want 0", got)
+	}
+}
+
+func TestSFU_CloseRoom(t *testing.T) {
+	s := New()
+	defer s.Close()
+
+	code := s.CreateRoomWithOptions(RoomOptions{MaxPeers: 4})
+	room, _ := s.GetRoom(code)
+	room.AddPeer("Alice")
+
+	if got := len(s.Rooms()); got != 1 {
+		t.Fatalf("rooms: got %d, want 1", got)
+	}
+	if room.Options.MaxPeers != 4 {
+		t.Fatalf("max peers: got %d, want 4", room.Options.MaxPeers)
+	}
+
+	if !s.CloseRoom(code) {
+		t.Fatal("CloseRoom should succeed for an existing room")
+	}
+	if _, ok := s.GetRoom(code); ok {
+		t.Fatal("room should be gone after CloseRoom")
+	}
+	select {
+	case <-room.Done():
+	default:
+		t.Fatal("room should be closed")
+	}
+	if s.CloseRoom(code) {
+		t.Fatal("CloseRoom should fail for a missing room")
+	}
+}
+
+func TestSFU_RoomDirectory(t *testing.T) {
+	dir := NewMemoryDirectory()
+	self := Node{ID: "a", URL: "https://a.example.com"}
+	s := NewWithConfig(Config{GracePeriod: time.Minute, GCInterval: time.Minute, Directory: dir, Node: self})
+	defer s.Close()
+
+	ctx := context.Background()
+	code, err := s.CreateRoomContext(ctx, RoomOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner, ok, err := s.LookupOwner(ctx, code)
+	if err != nil || !ok || owner != self {
+		t.Fatalf("owner of %s: got %+v, %v, %v; want %+v", code, owner, ok, err, self)
+	}
+
+	// A code claimed by another node is never reused.
+	other := Node{ID: "b"}
+	if ok, _ := dir.Claim(ctx, "ZZZZ-ZZZZ", other); !ok {
+		t.Fatal("claim of a free code failed")
+	}
+	if ok, _ := dir.Claim(ctx, code, other); ok {
+		t.Fatal("claimed a code owned by another node")
+	}
+	if err := dir.Release(ctx, code, other); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok, _ := dir.Lookup(ctx, code); !ok {
+		t.Fatal("release by a non-owner removed the record")
+	}
+
+	s.CloseRoom(code)
+	if _, ok, _ := dir.Lookup(ctx, code); ok {
+		t.Fatal("closing the room should release its code")
+	}
+}
+
+func TestSFU_RoomDirectoryReleasedOnGC(t *testing.T) {
+	dir := NewMemoryDirectory()
+	s := NewWithConfig(Config{GracePeriod: 50 * time.Millisecond, GCInterval: 20 * time.Millisecond, Directory: dir})
+	defer s.Close()
+
+	code := s.CreateRoom()
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if _, ok, _ := dir.Lookup(context.Background(), code); !ok {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("garbage-collected room was not released")
+}
+
+// memStore is an in-memory RoomStore.
+type memStore struct {
+	mu      sync.Mutex
+	records map[string]RoomRecord
+}
+
+func newMemStore() *memStore { return &memStore{records: map[string]RoomRecord{}} }
+
+func (m *memStore) Load(ctx context.Context) ([]RoomRecord, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var list []RoomRecord
+	for _, r := range m.records {
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (m *memStore) Save(ctx context.Context, rec RoomRecord) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.records[rec.Code] = rec
+	return nil
+}
+
+func (m *memStore) Delete(ctx context.Context, code string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.records, code)
+	return nil
+}
+
+func TestSFU_PersistentRoom(t *testing.T) {
+	store := newMemStore()
+	cfg := Config{GracePeriod: 50 * time.Millisecond, GCInterval: 20 * time.Millisecond, Store: store}
+	s := NewWithConfig(cfg)
+	ctx := context.Background()
+
+	room, err := s.CreatePersistentRoom(ctx, RoomRecord{
+		Code:     "standup",
+		RoomMeta: RoomMeta{Title: "Daily standup", Owner: "alice"},
+		Options:  RoomOptions{MaxPeers: 8},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if room.Code != "STANDUP" || !room.Persistent || room.Meta.Title != "Daily standup" {
+		t.Fatalf("room: %+v", room)
+	}
+	if _, err := s.CreatePersistentRoom(ctx, RoomRecord{Code: "Standup"}); err != ErrCodeTaken {
+		t.Fatalf("duplicate code: got %v", err)
+	}
+	for _, code := range []string{"ab", "-standup", "stand up", "ÄRGER"} {
+		if _, err := s.CreatePersistentRoom(ctx, RoomRecord{Code: code}); err != ErrInvalidCode {
+			t.Errorf("code %q: got %v, want ErrInvalidCode", code, err)
+		}
+	}
+	generated, err := s.CreatePersistentRoom(ctx, RoomRecord{})
+	if err != nil || len(generated.Code) != 9 {
+		t.Fatalf("generated code: %v, %v", generated, err)
+	}
+
+	// Empty persistent rooms outlive the grace period.
+	time.Sleep(150 * time.Millisecond)
+	if _, ok := s.GetRoom("STANDUP"); !ok {
+		t.Fatal("persistent room was garbage collected")
+	}
+
+	// A restart restores both rooms with their metadata.
+	s.Close()
+	s = NewWithConfig(cfg)
+	defer s.Close()
+	if n, err := s.RestoreRooms(ctx); err != nil || n != 2 {
+		t.Fatalf("restore: %d, %v", n, err)
+	}
+	restored, ok := s.GetRoom("STANDUP")
+	if !ok || restored.Meta != room.Meta || restored.Options != room.Options || !restored.CreatedAt().Equal(room.CreatedAt()) {
+		t.Fatalf("restored room: %+v", restored)
+	}
+
+	// Closing a persistent room deletes it for good.
+	s.CloseRoom("STANDUP")
+	if records, _ := store.Load(ctx); len(records) != 1 || records[0].Code != generated.Code {
+		t.Fatalf("store after close: %+v", records)
+	}
+}
+
+func TestSFU_PersistentRoomOwnedElsewhere(t *testing.T) {
+	dir := NewMemoryDirectory()
+	store := newMemStore()
+	store.Save(context.Background(), RoomRecord{Code: "STANDUP"})
+	dir.Claim(context.Background(), "STANDUP", Node{ID: "other"})
+
+	s := NewWithConfig(Config{GracePeriod: time.Minute, GCInterval: time.Minute, Directory: dir, Node: Node{ID: "self"}, Store: store})
+	defer s.Close()
+	if n, err := s.RestoreRooms(context.Background()); err != nil || n != 0 {
+		t.Fatalf("restore: %d, %v", n, err)
+	}
+	if _, ok := s.GetRoom("STANDUP"); ok {
+		t.Fatal("room hosted by another node was restored")
+	}
+}
+
+func TestSFU_CodeLengthClamped(t *testing.T) {
+	for _, tt := range []struct{ length, want int }{{2, MinCodeLength}, {100, MaxCodeLength}} {
+		s := NewWithConfig(Config{GracePeriod: time.Minute, GCInterval: time.Minute, CodeLength: tt.length})
+		code, err := s.CreateRoomContext(context.Background(), RoomOptions{})
+		s.Close()
+		if n := len(strings.ReplaceAll(code, "-", "")); err != nil || n != tt.want {
+			t.Errorf("length %d: generated %q, %v; want %d characters", tt.length, code, err, tt.want)
+		}
+	}
+}
+
+func TestSFU_CreateRoomWithCode(t *testing.T) {
+	s := NewWithConfig(Config{GracePeriod: time.Minute, GCInterval: time.Minute, CodeLength: 12})
+	defer s.Close()
+	ctx := context.Background()
+
+	code, err := s.CreateRoomWithCode(ctx, "Room-10", RoomOptions{})
+	if err != nil || code != "ROOM-10" {
+		t.Fatalf("create: %q, %v", code, err)
+	}
+	// Lookup ignores case and lookalikes.
+	for _, alias := range []string{"room-10", "ROOM-IO", "r00m-1o"} {
+		if room, ok := s.GetRoom(alias); !ok || room.Code != "ROOM-10" {
+			t.Errorf("GetRoom(%q) did not find the room", alias)
+		}
+	}
+	if _, err := s.CreateRoomWithCode(ctx, "ROOM-IO", RoomOptions{}); err != ErrCodeTaken {
+		t.Fatalf("lookalike code: got %v, want ErrCodeTaken", err)
+	}
+	if _, err := s.CreateRoomWithCode(ctx, "no", RoomOptions{}); err != ErrInvalidCode {
+		t.Fatalf("short code: got %v, want ErrInvalidCode", err)
+	}
+
+	// Generated codes use the configured length.
+	code, err = s.CreateRoomContext(ctx, RoomOptions{})
+	if err != nil || len(code) != len("XXXX-XXXX-XXXX") {
+		t.Fatalf("generated code %q, %v", code, err)
+	}
+
+	if !s.CloseRoom("room-io") {
+		t.Fatal("CloseRoom should accept any spelling")
+	}
+	if _, err := s.CreateRoomWithCode(ctx, "room-10", RoomOptions{}); err != nil {
+		t.Fatalf("code not released on close: %v", err)
+	}
+}
@@ -0,0 +1,149 @@
+package sfu
+
+import (
+	"strings"
+
+	"github.com/pion/webrtc/v4"
+)
+
+// StreamStats holds RTP counters for one direction of an audio stream.
+type StreamStats struct {
+	Packets     uint64  `json:"packets"`
+	Bytes       uint64  `json:"bytes"`
+	PacketsLost int64   `json:"packetsLost"`
+	Jitter      float64 `json:"jitter"` // seconds
+}
+
+// CandidatePair describes the ICE candidate pair carrying a peer's media.
+type CandidatePair struct {
+	Local    string `json:"local"`  // host, srflx, prflx or relay
+	Remote   string `json:"remote"` // host, srflx, prflx or relay
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// ForwardStats holds the counters maintained by a subscription's forwarding loop.
+type ForwardStats struct {
+	Packets uint64 `json:"packets"`
+	Bytes   uint64 `json:"bytes"`
+	Missed  uint64 `json:"missed"` // sequence number gaps seen on the source track
+}
+
+// SubscriptionStats reports one forwarded track from a source peer to the subscriber.
+type SubscriptionStats struct {
+	SourcePeerID string       `json:"sourcePeerId"`
+	TrackID      string       `json:"trackId"`
+	Forwarded    ForwardStats `json:"forwarded"`
+	Outbound     StreamStats  `json:"outbound"`
+	RTT          float64      `json:"rtt"` // seconds, from RTCP receiver reports
+}
+
+// PeerStats is a point-in-time snapshot of a peer's transport and media statistics.
+type PeerStats struct {
+	PeerID          string              `json:"peerId"`
+	ConnectionState string              `json:"connectionState"`
+	CandidatePair   *CandidatePair      `json:"candidatePair,omitempty"`
+	RTT             float64             `json:"rtt"` // seconds, ICE round-trip time
+	Codec           string              `json:"codec,omitempty"`
+	Inbound         StreamStats         `json:"inbound"`
+	Outbound        StreamStats         `json:"outbound"`
+	Subscriptions   []SubscriptionStats `json:"subscriptions"`
+}
+
+// Stats collects a snapshot of the peer's statistics from pion's GetStats and
+// the forwarding loops of its subscriptions. It acquires wp.Mu.
+func (wp *WebRTCPeer) Stats() PeerStats {
+	report := wp.PC.GetStats()
+
+	out := PeerStats{
+		PeerID:          wp.ID,
+		ConnectionState: wp.PC.ConnectionState().String(),
+		Subscriptions:   []SubscriptionStats{},
+	}
+
+	var (
+		candidates   = make(map[string]webrtc.ICECandidateStats)
+		codecs       = make(map[string]string)
+		outbound     = make(map[webrtc.SSRC]webrtc.OutboundRTPStreamStats)
+		remoteIn     = make(map[webrtc.SSRC]webrtc.RemoteInboundRTPStreamStats)
+		selectedPair *webrtc.ICECandidatePairStats
+		inboundCodec string
+	)
+
+	for _, s := range report {
+		switch st := s.(type) {
+		case webrtc.InboundRTPStreamStats:
+			if st.Kind != "audio" {
+				continue
+			}
+			out.Inbound.Packets += uint64(st.PacketsReceived)
+			out.Inbound.Bytes += st.BytesReceived
+			out.Inbound.PacketsLost += int64(st.PacketsLost)
+			out.Inbound.Jitter = max(out.Inbound.Jitter, st.Jitter)
+			inboundCodec = st.CodecID
+		case webrtc.OutboundRTPStreamStats:
+			outbound[st.SSRC] = st
+		case webrtc.RemoteInboundRTPStreamStats:
+			remoteIn[st.SSRC] = st
+		case webrtc.ICECandidatePairStats:
+			if st.Nominated && st.State == webrtc.StatsICECandidatePairStateSucceeded {
+				pair := st
+				selectedPair = &pair
+			}
+		case webrtc.ICECandidateStats:
+			candidates[st.ID] = st
+		case webrtc.CodecStats:
+			codecs[st.ID] = st.MimeType
+		}
+	}
+
+	if selectedPair != nil {
+		local := candidates[selectedPair.LocalCandidateID]
+		remote := candidates[selectedPair.RemoteCandidateID]
+		out.CandidatePair = &CandidatePair{
+			Local:    local.CandidateType.String(),
+			Remote:   remote.CandidateType.String(),
+			Protocol: strings.ToLower(local.Protocol),
+		}
+		out.RTT = selectedPair.CurrentRoundTripTime
+	}
+
+	if mime, ok := codecs[inboundCodec]; ok {
+		out.Codec = mime
+	} else {
+		for _, mime := range codecs {
+			out.Codec = mime
+			break
+		}
+	}
+
+	wp.Mu.Lock()
+	defer wp.Mu.Unlock()
+
+	for srcID, sub := range wp.Subs {
+		ss := SubscriptionStats{
+			SourcePeerID: srcID,
+			TrackID:      sub.Track.ID(),
+			Forwarded:    sub.Stats(),
+		}
+		if sub.Sender != nil {
+			for _, enc := range sub.Sender.GetParameters().Encodings {
+				if o, ok := outbound[enc.SSRC]; ok {
+					ss.Outbound.Packets += uint64(o.PacketsSent)
+					ss.Outbound.Bytes += o.BytesSent
+				}
+				if ri, ok := remoteIn[enc.SSRC]; ok {
+					ss.Outbound.PacketsLost += int64(ri.PacketsLost)
+					ss.Outbound.Jitter = max(ss.Outbound.Jitter, ri.Jitter)
+					ss.RTT = max(ss.RTT, ri.RoundTripTime)
+				}
+			}
+		}
+		out.Outbound.Packets += ss.Outbound.Packets
+		out.Outbound.Bytes += ss.Outbound.Bytes
+		out.Outbound.PacketsLost += ss.Outbound.PacketsLost
+		out.Outbound.Jitter = max(out.Outbound.Jitter, ss.Outbound.Jitter)
+		out.Subscriptions = append(out.Subscriptions, ss)
+	}
+
+	return out
+}
@@ -0,0 +1,111 @@
+package sfu
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+// RoomMeta describes a room to the people joining it.
+type RoomMeta struct {
+	Title       string `json:"title,omitempty"`
+	Description string `json:"description,omitempty"`
+	Owner       string `json:"owner,omitempty"` // user who created the room
+}
+
+// RoomRecord is the stored form of a persistent room.
+type RoomRecord struct {
+	Code string `json:"code"`
+	RoomMeta
+	Options   RoomOptions `json:"options"`
+	CreatedAt time.Time   `json:"createdAt"`
+}
+
+// RoomStore keeps persistent rooms across restarts. Implementations must be
+// safe for concurrent use.
+type RoomStore interface {
+	// Load returns every stored room.
+	Load(ctx context.Context) ([]RoomRecord, error)
+	// Save adds or replaces the record with rec.Code.
+	Save(ctx context.Context, rec RoomRecord) error
+	// Delete removes a record; deleting an unknown code is not an error.
+	Delete(ctx context.Context, code string) error
+}
+
+// ErrNoStore is returned by CreatePersistentRoom if the SFU has no RoomStore.
+var ErrNoStore = errors.New("no room store configured")
+
+// CreatePersistentRoom creates a room that is exempt from garbage collection
+// and saved to the configured RoomStore, so RestoreRooms brings it back after
+// a restart. rec.Code may name the room (e.g. "standup"), as for
+// CreateRoomWithCode; an empty code is generated.
+func (s *SFU) CreatePersistentRoom(ctx context.Context, rec RoomRecord) (*Room, error) {
+	if s.config.Store == nil {
+		return nil, ErrNoStore
+	}
+	code, err := s.claimCode(ctx, rec.Code)
+	if err != nil {
+		return nil, err
+	}
+	rec.Code = code
+	rec.CreatedAt = time.Now().UTC()
+
+	if err := s.config.Store.Save(ctx, rec); err != nil {
+		s.release(NormalizeRoomCode(rec.Code))
+		return nil, fmt.Errorf("save room: %w", err)
+	}
+	room := newPersistentRoom(rec)
+	s.addRoom(room)
+	return room, nil
+}
+
+// RestoreRooms loads the persistent rooms from the configured RoomStore and
+// opens them. Rooms whose code is claimed by another node are skipped. It
+// returns the number of rooms opened.
+func (s *SFU) RestoreRooms(ctx context.Context) (int, error) {
+	if s.config.Store == nil {
+		return 0, nil
+	}
+	records, err := s.config.Store.Load(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("load rooms: %w", err)
+	}
+	n := 0
+	for _, rec := range records {
+		if _, exists := s.GetRoom(rec.Code); exists {
+			continue
+		}
+		ok, err := s.config.Directory.Claim(ctx, NormalizeRoomCode(rec.Code), s.config.Node)
+		if err != nil {
+			return n, fmt.Errorf("claim room %s: %w", rec.Code, err)
+		}
+		if !ok {
+			slog.Default().Warn("persistent room is hosted by another node", "code", rec.Code)
+			continue
+		}
+		s.addRoom(newPersistentRoom(rec))
+		n++
+	}
+	return n, nil
+}
+
+func newPersistentRoom(rec RoomRecord) *Room {
+	room := NewRoomWithOptions(rec.Code, rec.Options)
+	room.Meta = rec.RoomMeta
+	room.Persistent = true
+	if !rec.CreatedAt.IsZero() {
+		room.created = rec.CreatedAt
+	}
+	return room
+}
+
+// forget deletes a closed persistent room from the store.
+func (s *SFU) forget(code string) {
+	ctx, cancel := context.WithTimeout(context.Background(), directoryTimeout)
+	defer cancel()
+	if err := s.config.Store.Delete(ctx, code); err != nil {
+		slog.Default().Warn("delete persistent room", "code", code, "err", err)
+	}
+}
@@ -0,0 +1,139 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+
+	"github.com/coder/websocket"
+	"github.com/golang-jwt/jwt/v5"
+	"go.uber.org/zap"
+
+	"voxlink/internal/auth"
+	"voxlink/internal/sfu"
+)
+
+// JoinClaims are the claims of a join token issued by the embedding
+// application's backend.
+type JoinClaims struct {
+	jwt.RegisteredClaims
+	Name  string   `json:"name,omitempty"`  // display name; overrides the name sent by the client
+	Rooms []string `json:"rooms,omitempty"` // room codes the holder may join; "*" allows any room
+	Role  string   `json:"role,omitempty"`  // sfu.RoleHost may create rooms; defaults to sfu.RoleMember
+}
+
+// CanCreate reports whether the token allows creating rooms.
+func (c *JoinClaims) CanCreate() bool {
+	return c.Role == sfu.RoleHost
+}
+
+// AllowsRoom reports whether the token allows joining the room with the given
+// code. Codes are compared as sfu.NormalizeRoomCode does.
+func (c *JoinClaims) AllowsRoom(code string) bool {
+	code = sfu.NormalizeRoomCode(code)
+	return slices.ContainsFunc(c.Rooms, func(allowed string) bool {
+		return allowed == "*" || sfu.NormalizeRoomCode(allowed) == code
+	})
+}
+
+// role returns the room role granted by the token.
+func (c *JoinClaims) role() string {
+	if c.Role == sfu.RoleHost {
+		return sfu.RoleHost
+	}
+	return sfu.RoleMember
+}
+
+// WithJoinVerifier requires every WebSocket client to present a join token,
+// either as the "token" query parameter of /ws or in an auth message sent
+// before anything else. Tokens are verified offline with v.
+func WithJoinVerifier(v *auth.JWTVerifier) HandlerOption {
+	return func(h *Handler) {
+		h.joinVerifier = v
+	}
+}
+
+func (h *Handler) verifyJoinToken(token string) (*JoinClaims, error) {
+	var claims JoinClaims
+	if err := h.joinVerifier.Verify(token, &claims); err != nil {
+		return nil, err
+	}
+	return &claims, nil
+}
+
+// handleAuth verifies a join token sent as the first message. An invalid
+// token closes the connection.
+func (h *Handler) handleAuth(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	if h.joinVerifier == nil {
+		h.sendError(ctx, client, "authentication is not enabled")
+		return
+	}
+	if client.identity != nil {
+		h.sendError(ctx, client, "already authenticated")
+		return
+	}
+	var msg AuthPayload
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.sendError(ctx, client, "invalid auth payload")
+		return
+	}
+	claims, err := h.verifyJoinToken(msg.Token)
+	if err != nil {
+		h.logger.Info("join token rejected", zap.Error(err))
+		h.sendError(ctx, client, "invalid token")
+		client.conn.Close(websocket.StatusPolicyViolation, "invalid token")
+		return
+	}
+	client.identity = claims
+	h.sendAuthOK(ctx, client)
+}
+
+func (h *Handler) sendAuthOK(ctx context.Context, client *clientConn) {
+	env, _ := NewEnvelope(MsgAuthOK, AuthOKPayload{
+		UserID: client.identity.Subject,
+		Name:   client.identity.Name,
+		Role:   client.identity.role(),
+	})
+	client.send(ctx, env)
+}
+
+// authorizeCreate checks whether the client may create a room and resolves
+// its display name and role. It sends an error and returns false if not.
+func (h *Handler) authorizeCreate(ctx context.Context, client *clientConn, name string) (string, string, bool) {
+	if h.joinVerifier == nil {
+		return name, sfu.RoleHost, true
+	}
+	if !client.identity.CanCreate() {
+		h.sendError(ctx, client, "not allowed to create rooms")
+		return "", "", false
+	}
+	return client.identity.displayName(name), sfu.RoleHost, true
+}
+
+// authorizeJoin checks whether the client may join the room and resolves its
+// display name and role. It sends an error and returns false if not.
+func (h *Handler) authorizeJoin(ctx context.Context, client *clientConn, code, name string) (string, string, bool) {
+	if h.joinVerifier == nil {
+		return name, sfu.RoleMember, true
+	}
+	if !client.identity.AllowsRoom(code) {
+		h.sendError(ctx, client, "not allowed to join this room")
+		return "", "", false
+	}
+	return client.identity.displayName(name), client.identity.role(), true
+}
+
+func (c *JoinClaims) displayName(requested string) string {
+	if c.Name != "" {
+		return c.Name
+	}
+	return requested
+}
+
+// userID returns the authenticated user ID of a client, or "".
+func (c *clientConn) userID() string {
+	if c.identity == nil {
+		return ""
+	}
+	return c.identity.Subject
+}
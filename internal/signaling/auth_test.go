@@ -0,0 +1,252 @@
+package signaling
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/golang-jwt/jwt/v5"
+
+	"voxlink/internal/auth"
+	"voxlink/internal/sfu"
+)
+
+var joinSecret = []byte("join-token-test-secret")
+
+func joinTestServer(t *testing.T, s *sfu.SFU) *httptest.Server {
+	t.Helper()
+	jwks, _ := json.Marshal(map[string]any{"keys": []auth.JWK{{
+		Kty: "oct",
+		Kid: "join",
+		K:   base64.RawURLEncoding.EncodeToString(joinSecret),
+	}}})
+	ks, err := auth.ParseJWKS(jwks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	v := auth.NewJWTVerifier(ks, auth.WithIssuer("backend"))
+	srv := httptest.NewServer(NewHandler(s, nil, WithJoinVerifier(v)))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func joinToken(t *testing.T, sub, name, role string, rooms ...string) string {
+	t.Helper()
+	claims := JoinClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   sub,
+			Issuer:    "backend",
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		Name:  name,
+		Rooms: rooms,
+		Role:  role,
+	}
+	tok := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	tok.Header["kid"] = "join"
+	s, err := tok.SignedString(joinSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func dialWS(t *testing.T, ctx context.Context, srv *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	conn, _, err := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws"+query, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.CloseNow() })
+	return conn
+}
+
+func roundTrip(t *testing.T, ctx context.Context, conn *websocket.Conn, msgType string, payload any) Envelope {
+	t.Helper()
+	env, _ := NewEnvelope(msgType, payload)
+	if err := wsjson.Write(ctx, conn, env); err != nil {
+		t.Fatal(err)
+	}
+	var resp Envelope
+	if err := wsjson.Read(ctx, conn, &resp); err != nil {
+		t.Fatal(err)
+	}
+	return resp
+}
+
+func TestJoinAuth_QueryToken(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := joinTestServer(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alice := dialWS(t, ctx, srv, "?token="+joinToken(t, "u-alice", "Alice (verified)", sfu.RoleHost))
+	var ok Envelope
+	if err := wsjson.Read(ctx, alice, &ok); err != nil {
+		t.Fatal(err)
+	}
+	var okPayload AuthOKPayload
+	json.Unmarshal(ok.Payload, &okPayload)
+	if ok.Type != MsgAuthOK || okPayload.UserID != "u-alice" || okPayload.Role != sfu.RoleHost {
+		t.Fatalf("auth-ok: got %s %+v", ok.Type, okPayload)
+	}
+
+	resp := roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Mallory"})
+	if resp.Type != MsgRoomCreated {
+		t.Fatalf("type: got %q, want %q", resp.Type, MsgRoomCreated)
+	}
+	var created RoomCreatedPayload
+	json.Unmarshal(resp.Payload, &created)
+	room, _ := s.GetRoom(created.Code)
+	peer, _ := room.GetPeer(created.PeerID)
+	if peer.Name != "Alice (verified)" || peer.UserID != "u-alice" || peer.Role != sfu.RoleHost {
+		t.Fatalf("peer: got %+v", peer)
+	}
+}
+
+func TestJoinAuth_InvalidQueryToken(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := joinTestServer(t, s)
+
+	resp, err := http.Get(srv.URL + "/ws?token=not-a-jwt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("status: got %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestJoinAuth_FirstMessage(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := joinTestServer(t, s)
+	code := s.CreateRoom()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bob := dialWS(t, ctx, srv, "")
+	if resp := roundTrip(t, ctx, bob, MsgAuth, AuthPayload{Token: joinToken(t, "u-bob", "", "", strings.ToLower(code))}); resp.Type != MsgAuthOK {
+		t.Fatalf("auth: got %q, want %q", resp.Type, MsgAuthOK)
+	}
+
+	// Members may not create rooms.
+	if resp := roundTrip(t, ctx, bob, MsgCreateRoom, CreateRoomPayload{Name: "Bob"}); resp.Type != MsgError {
+		t.Fatalf("create: got %q, want %q", resp.Type, MsgError)
+	}
+	// Room codes are matched case-insensitively; the client's name is kept
+	// when the token carries none.
+	resp := roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Bob"})
+	if resp.Type != MsgRoomJoined {
+		t.Fatalf("join: got %q, want %q", resp.Type, MsgRoomJoined)
+	}
+	var joined RoomJoinedPayload
+	json.Unmarshal(resp.Payload, &joined)
+	room, _ := s.GetRoom(code)
+	peer, _ := room.GetPeer(joined.PeerID)
+	if peer.Name != "Bob" || peer.UserID != "u-bob" || peer.Role != sfu.RoleMember {
+		t.Fatalf("peer: got %+v", peer)
+	}
+}
+
+func TestJoinAuth_RoomNotAllowed(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := joinTestServer(t, s)
+	code := s.CreateRoom()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn := dialWS(t, ctx, srv, "?token="+joinToken(t, "u-eve", "Eve", "", "OTHR-ROOM"))
+	var ok Envelope
+	wsjson.Read(ctx, conn, &ok)
+
+	if resp := roundTrip(t, ctx, conn, MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Eve"}); resp.Type != MsgError {
+		t.Fatalf("join: got %q, want %q", resp.Type, MsgError)
+	}
+	room, _ := s.GetRoom(code)
+	if n := len(room.PeerList()); n != 0 {
+		t.Fatalf("peers: got %d, want 0", n)
+	}
+}
+
+func TestJoinAuth_Required(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := joinTestServer(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn := dialWS(t, ctx, srv, "")
+	if resp := roundTrip(t, ctx, conn, MsgCreateRoom, CreateRoomPayload{Name: "Anon"}); resp.Type != MsgError {
+		t.Fatalf("type: got %q, want %q", resp.Type, MsgError)
+	}
+	var env Envelope
+	err := wsjson.Read(ctx, conn, &env)
+	if websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+		t.Fatalf("close: got %v, want policy violation", err)
+	}
+	if n := len(s.Rooms()); n != 0 {
+		t.Fatalf("rooms: got %d, want 0", n)
+	}
+}
+
+func TestJoinClaims_AllowsRoom(t *testing.T) {
+	c := JoinClaims{Rooms: []string{"ABCD-EFGH"}}
+	if !c.AllowsRoom("abcd-efgh") {
+		t.Error("room codes should match case-insensitively")
+	}
+	if c.AllowsRoom("WXYZ-WXYZ") {
+		t.Error("unlisted room should not be allowed")
+	}
+	if !(&JoinClaims{Rooms: []string{"*"}}).AllowsRoom("WXYZ-WXYZ") {
+		t.Error("wildcard should allow any room")
+	}
+}
+
+func TestJoinAuth_RejoinCreatedRoom(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := joinTestServer(t, s)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A host token with create rights and no room grants.
+	token := joinToken(t, "u-alice", "Alice", sfu.RoleHost)
+	alice := dialWS(t, ctx, srv, "?token="+token)
+	var ok Envelope
+	wsjson.Read(ctx, alice, &ok)
+	resp := roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice"})
+	var created RoomCreatedPayload
+	json.Unmarshal(resp.Payload, &created)
+	// Alice's network drops; her old socket is not closed yet when she
+	// reconnects.
+
+	// Someone else may not take over the peer.
+	eve := dialWS(t, ctx, srv, "?token="+joinToken(t, "u-eve", "Eve", sfu.RoleHost))
+	wsjson.Read(ctx, eve, &ok)
+	if resp := roundTrip(t, ctx, eve, MsgRejoin, RejoinPayload{Code: created.Code, PeerID: created.PeerID}); resp.Type != MsgError {
+		t.Fatalf("rejoin as another user: got %q, want %q", resp.Type, MsgError)
+	}
+
+	again := dialWS(t, ctx, srv, "?token="+token)
+	wsjson.Read(ctx, again, &ok)
+	if resp := roundTrip(t, ctx, again, MsgRejoin, RejoinPayload{Code: created.Code, PeerID: created.PeerID}); resp.Type != MsgRoomJoined {
+		t.Fatalf("rejoin: got %q, want %q", resp.Type, MsgRoomJoined)
+	}
+}
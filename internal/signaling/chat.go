@@ -0,0 +1,155 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+
+	"go.uber.org/zap"
+
+	"voxlink/internal/sfu"
+)
+
+// Chat messages live on the sfu.Room that owns them. In a mirrored room the
+// edge node forwards its peers' chat requests to the owner over the relay
+// link and follows the owner's chat-message and chat-deleted broadcasts; the
+// owner's errors name the peer they are for, and the edge passes them on.
+
+func (h *Handler) handleChatSend(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	var msg ChatSendPayload
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.sendError(ctx, client, "invalid chat-send payload")
+		return
+	}
+	room, author, ok := h.chatRoom(ctx, client, msg.PeerID)
+	if !ok {
+		return
+	}
+	if room.IsMirror() {
+		if err := sfu.CheckChatText(msg.Text); err != nil {
+			h.sendError(ctx, client, err.Error())
+			return
+		}
+		msg.PeerID = author
+		h.relayUpstream(room.Code, MsgChatSend, msg)
+		return
+	}
+	m, err := room.PostChat(author, msg.Text)
+	if err != nil {
+		h.chatError(ctx, client, author, err)
+		return
+	}
+	env, _ := NewEnvelope(MsgChatMessage, toChatPayload(m))
+	h.broadcastToRoom(ctx, room.Code, "", env)
+}
+
+func (h *Handler) handleChatEdit(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	var msg ChatEditPayload
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.sendError(ctx, client, "invalid chat-edit payload")
+		return
+	}
+	room, author, ok := h.chatRoom(ctx, client, msg.PeerID)
+	if !ok {
+		return
+	}
+	if room.IsMirror() {
+		if err := sfu.CheckChatText(msg.Text); err != nil {
+			h.sendError(ctx, client, err.Error())
+			return
+		}
+		msg.PeerID = author
+		h.relayUpstream(room.Code, MsgChatEdit, msg)
+		return
+	}
+	m, err := room.EditChat(author, msg.ID, msg.Text)
+	if err != nil {
+		h.chatError(ctx, client, author, err)
+		return
+	}
+	env, _ := NewEnvelope(MsgChatMessage, toChatPayload(m))
+	h.broadcastToRoom(ctx, room.Code, "", env)
+}
+
+func (h *Handler) handleChatDelete(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	var msg ChatDeletePayload
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.sendError(ctx, client, "invalid chat-delete payload")
+		return
+	}
+	room, author, ok := h.chatRoom(ctx, client, msg.PeerID)
+	if !ok {
+		return
+	}
+	if room.IsMirror() {
+		msg.PeerID = author
+		h.relayUpstream(room.Code, MsgChatDelete, msg)
+		return
+	}
+	if err := room.DeleteChat(author, msg.ID); err != nil {
+		h.chatError(ctx, client, author, err)
+		return
+	}
+	h.logger.Debug("chat message deleted", zap.String("room", room.Code), zap.String("id", msg.ID), zap.String("by", author))
+	env, _ := NewEnvelope(MsgChatDeleted, ChatDeletedPayload{ID: msg.ID, By: author})
+	h.broadcastToRoom(ctx, room.Code, "", env)
+}
+
+// chatRoom resolves the room of a chat request and the peer it acts for: the
+// client's own peer, or for a relay link the named peer of the linked node.
+// Errors are reported to the client.
+func (h *Handler) chatRoom(ctx context.Context, client *clientConn, claimed string) (*sfu.Room, string, bool) {
+	room, ok := h.sfu.GetRoom(client.roomCode)
+	if client.roomCode == "" || !ok {
+		h.sendError(ctx, client, "not in a room")
+		return nil, "", false
+	}
+	if client.relay == nil {
+		return room, client.peerID, true
+	}
+	if peer, ok := room.GetPeer(claimed); ok && peer.Node == client.relay.node {
+		return room, claimed, true
+	}
+	h.sendErrorPayload(ctx, client, ErrorPayload{Message: "unknown chat author", PeerID: claimed})
+	return nil, "", false
+}
+
+// chatError reports a refused chat request. On a relay link the error names
+// the author, so that the linked node can pass it on to that peer.
+func (h *Handler) chatError(ctx context.Context, client *clientConn, author string, err error) {
+	p := ErrorPayload{Message: "chat failed"}
+	switch {
+	case errors.Is(err, sfu.ErrChatEmpty), errors.Is(err, sfu.ErrChatTooLong),
+		errors.Is(err, sfu.ErrChatNotFound), errors.Is(err, sfu.ErrChatForbidden):
+		p.Message = err.Error()
+	}
+	if client.relay != nil {
+		p.PeerID = author
+	}
+	h.sendErrorPayload(ctx, client, p)
+}
+
+func toChatPayload(m sfu.ChatMessage) ChatMessagePayload {
+	p := ChatMessagePayload{ID: m.ID, PeerID: m.PeerID, Name: m.Name, Text: m.Text, SentAt: m.SentAt}
+	if !m.EditedAt.IsZero() {
+		edited := m.EditedAt
+		p.EditedAt = &edited
+	}
+	return p
+}
+
+func fromChatPayload(p ChatMessagePayload) sfu.ChatMessage {
+	m := sfu.ChatMessage{ID: p.ID, PeerID: p.PeerID, Name: p.Name, Text: p.Text, SentAt: p.SentAt}
+	if p.EditedAt != nil {
+		m.EditedAt = *p.EditedAt
+	}
+	return m
+}
+
+func toChatList(msgs []sfu.ChatMessage) []ChatMessagePayload {
+	list := make([]ChatMessagePayload, len(msgs))
+	for i, m := range msgs {
+		list[i] = toChatPayload(m)
+	}
+	return list
+}
@@ -0,0 +1,189 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket/wsjson"
+
+	"voxlink/internal/sfu"
+)
+
+func readChat(t *testing.T, env Envelope) ChatMessagePayload {
+	t.Helper()
+	var m ChatMessagePayload
+	if err := json.Unmarshal(env.Payload, &m); err != nil {
+		t.Fatal(err)
+	}
+	return m
+}
+
+func TestServer_Chat(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := httptest.NewServer(NewHandler(s, nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alice := dialWS(t, ctx, srv, "")
+	var created RoomCreatedPayload
+	json.Unmarshal(roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice"}).Payload, &created)
+	bob := dialWS(t, ctx, srv, "")
+	var joined RoomJoinedPayload
+	json.Unmarshal(roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: "Bob"}).Payload, &joined)
+	readType(t, ctx, alice, MsgPeerJoined)
+
+	// Bob's message reaches everyone, Bob included, with a server-assigned ID.
+	env, _ := NewEnvelope(MsgChatSend, ChatSendPayload{Text: "agenda: https://example.com", PeerID: created.PeerID})
+	wsjson.Write(ctx, bob, env)
+	sent := readChat(t, readType(t, ctx, alice, MsgChatMessage))
+	if sent.ID == "" || sent.PeerID != joined.PeerID || sent.Name != "Bob" || sent.SentAt.IsZero() {
+		t.Fatalf("alice got %+v (a client cannot post as another peer)", sent)
+	}
+	if echo := readChat(t, readType(t, ctx, bob, MsgChatMessage)); echo.ID != sent.ID {
+		t.Fatalf("bob got %+v", echo)
+	}
+
+	// Late joiners get the history.
+	carol := dialWS(t, ctx, srv, "")
+	var carolJoined RoomJoinedPayload
+	json.Unmarshal(roundTrip(t, ctx, carol, MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: "Carol"}).Payload, &carolJoined)
+	if len(carolJoined.Chat) != 1 || carolJoined.Chat[0].ID != sent.ID {
+		t.Fatalf("carol's history: %+v", carolJoined.Chat)
+	}
+
+	// Only the author edits.
+	if resp := roundTrip(t, ctx, carol, MsgChatEdit, ChatEditPayload{ID: sent.ID, Text: "mine now"}); resp.Type != MsgError {
+		t.Fatalf("edit by carol: got %s", resp.Type)
+	}
+	env, _ = NewEnvelope(MsgChatEdit, ChatEditPayload{ID: sent.ID, Text: "agenda: https://example.com/v2"})
+	wsjson.Write(ctx, bob, env)
+	edited := readChat(t, readType(t, ctx, carol, MsgChatMessage))
+	if edited.ID != sent.ID || edited.EditedAt == nil || !strings.HasSuffix(edited.Text, "/v2") {
+		t.Fatalf("carol got %+v", edited)
+	}
+
+	// Limits are enforced.
+	if resp := roundTrip(t, ctx, carol, MsgChatSend, ChatSendPayload{Text: strings.Repeat("x", sfu.MaxChatLength+1)}); resp.Type != MsgError {
+		t.Fatalf("long message: got %s", resp.Type)
+	}
+
+	// The host moderates.
+	env, _ = NewEnvelope(MsgChatDelete, ChatDeletePayload{ID: sent.ID})
+	wsjson.Write(ctx, alice, env)
+	var deleted ChatDeletedPayload
+	json.Unmarshal(readType(t, ctx, carol, MsgChatDeleted).Payload, &deleted)
+	if deleted.ID != sent.ID || deleted.By != created.PeerID {
+		t.Fatalf("carol got %+v", deleted)
+	}
+	room, _ := s.GetRoom(created.Code)
+	if len(room.ChatHistory()) != 0 {
+		t.Fatal("deleted message still in history")
+	}
+}
+
+func TestServer_ChatOutsideRoom(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	srv := httptest.NewServer(NewHandler(s, nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialWS(t, ctx, srv, "")
+	if resp := roundTrip(t, ctx, conn, MsgChatSend, ChatSendPayload{Text: "hi"}); resp.Type != MsgError {
+		t.Fatalf("got %s", resp.Type)
+	}
+}
+
+func TestRelay_Chat(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	a, _, srvA := relayTestNode(t, "a", dir)
+	b, _, srvB := relayTestNode(t, "b", dir, WithCascade())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alice := dialWS(t, ctx, srvA, "")
+	var created RoomCreatedPayload
+	json.Unmarshal(roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice"}).Payload, &created)
+	env, _ := NewEnvelope(MsgChatSend, ChatSendPayload{Text: "before bob"})
+	wsjson.Write(ctx, alice, env)
+	readType(t, ctx, alice, MsgChatMessage)
+
+	// The mirror starts with the owner's history.
+	bob := dialWS(t, ctx, srvB, "")
+	var joined RoomJoinedPayload
+	json.Unmarshal(roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: "Bob"}).Payload, &joined)
+	if len(joined.Chat) != 1 || joined.Chat[0].Text != "before bob" {
+		t.Fatalf("bob's history: %+v", joined.Chat)
+	}
+	readType(t, ctx, alice, MsgPeerJoined)
+
+	// Bob's message is posted on the owner and comes back to him.
+	env, _ = NewEnvelope(MsgChatSend, ChatSendPayload{Text: "from b"})
+	wsjson.Write(ctx, bob, env)
+	m := readChat(t, readType(t, ctx, alice, MsgChatMessage))
+	if m.PeerID != joined.PeerID || m.Name != "Bob" {
+		t.Fatalf("alice got %+v", m)
+	}
+	if echo := readChat(t, readType(t, ctx, bob, MsgChatMessage)); echo.ID != m.ID {
+		t.Fatalf("bob got %+v", echo)
+	}
+	ownerRoom, _ := a.GetRoom(created.Code)
+	mirror, _ := b.GetRoom(created.Code)
+	if len(ownerRoom.ChatHistory()) != 2 || len(mirror.ChatHistory()) != 2 {
+		t.Fatalf("histories: owner %d, mirror %d", len(ownerRoom.ChatHistory()), len(mirror.ChatHistory()))
+	}
+
+	// Bob deletes his message through the link.
+	env, _ = NewEnvelope(MsgChatDelete, ChatDeletePayload{ID: m.ID})
+	wsjson.Write(ctx, bob, env)
+	readType(t, ctx, alice, MsgChatDeleted)
+	readType(t, ctx, bob, MsgChatDeleted)
+	if len(ownerRoom.ChatHistory()) != 1 || len(mirror.ChatHistory()) != 1 {
+		t.Fatal("deletion did not reach both histories")
+	}
+}
+
+func TestRelay_ChatRejected(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	_, _, srvA := relayTestNode(t, "a", dir)
+	_, _, srvB := relayTestNode(t, "b", dir, WithCascade())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alice := dialWS(t, ctx, srvA, "")
+	var created RoomCreatedPayload
+	json.Unmarshal(roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice"}).Payload, &created)
+	env, _ := NewEnvelope(MsgChatSend, ChatSendPayload{Text: "alice's"})
+	wsjson.Write(ctx, alice, env)
+	m := readChat(t, readType(t, ctx, alice, MsgChatMessage))
+
+	bob := dialWS(t, ctx, srvB, "")
+	roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: "Bob"})
+
+	// The owner refuses Bob's edit of Alice's message; the edge passes the
+	// error on to Bob.
+	env, _ = NewEnvelope(MsgChatEdit, ChatEditPayload{ID: m.ID, Text: "bob's"})
+	wsjson.Write(ctx, bob, env)
+	var e ErrorPayload
+	json.Unmarshal(readType(t, ctx, bob, MsgError).Payload, &e)
+	if e.Message != sfu.ErrChatForbidden.Error() || e.PeerID != "" {
+		t.Fatalf("bob got %+v", e)
+	}
+
+	env, _ = NewEnvelope(MsgChatDelete, ChatDeletePayload{ID: "no-such-message"})
+	wsjson.Write(ctx, bob, env)
+	json.Unmarshal(readType(t, ctx, bob, MsgError).Payload, &e)
+	if e.Message != sfu.ErrChatNotFound.Error() {
+		t.Fatalf("bob got %+v", e)
+	}
+}
@@ -0,0 +1,64 @@
+package signaling
+
+import (
+	"encoding/json"
+	"slices"
+
+	"go.uber.org/zap"
+
+	"voxlink/internal/sfu"
+)
+
+// Application messages travel over each peer's WebRTC data channels instead
+// of the WebSocket. They reach the room's peers on this node; peers behind a
+// relay link are not reached.
+
+// serveData forwards the messages a peer sends on its data channels.
+func (h *Handler) serveData(wp *sfu.WebRTCPeer, roomCode string) {
+	wp.Data.OnMessage(func(ordered bool, data []byte) {
+		if !wp.Data.Allow() {
+			dataMessagesTotal.With("rate_limited").Inc()
+			return
+		}
+		msg, err := sfu.ParseDataMessage(wp.ID, data)
+		if err != nil {
+			dataMessagesTotal.With("rejected").Inc()
+			h.logger.Debug("drop data message", zap.String("peer", wp.ID), zap.Error(err))
+			return
+		}
+		h.forwardData(roomCode, ordered, msg)
+	})
+}
+
+// forwardData delivers msg to its recipients in the room, or to every other
+// peer if it names none, on the channel kind it arrived on.
+func (h *Handler) forwardData(roomCode string, ordered bool, msg sfu.DataMessage) {
+	room, ok := h.sfu.GetRoom(roomCode)
+	if !ok {
+		return
+	}
+	out, err := json.Marshal(msg)
+	if err != nil {
+		h.logger.Error("marshal data message", zap.Error(err))
+		return
+	}
+	for _, peer := range room.PeerList() {
+		if peer.ID == msg.From || peer.Role == sfu.RoleRelay {
+			continue
+		}
+		if len(msg.To) > 0 && !slices.Contains(msg.To, peer.ID) {
+			continue
+		}
+		h.mu.RLock()
+		wp, ok := h.webrtcPeers[peer.ID]
+		h.mu.RUnlock()
+		if !ok || wp.Data == nil {
+			continue
+		}
+		if err := wp.Data.Send(ordered, out); err != nil {
+			dataMessagesTotal.With("undelivered").Inc()
+			continue
+		}
+		dataMessagesTotal.With("forwarded").Inc()
+	}
+}
@@ -0,0 +1,167 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/pion/webrtc/v4"
+
+	"voxlink/internal/sfu"
+)
+
+// dataPeer is a WebRTC client that answers the SFU's offer and collects the
+// messages arriving on its data channels.
+type dataPeer struct {
+	pc       *webrtc.PeerConnection
+	channels chan *webrtc.DataChannel
+	received chan received
+}
+
+type received struct {
+	label string
+	msg   sfu.DataMessage
+}
+
+// connectData negotiates conn's peer connection; conn must have just joined
+// a room. It reads conn until the test ends.
+func connectData(t *testing.T, ctx context.Context, conn *websocket.Conn) *dataPeer {
+	t.Helper()
+	pc, err := newDataTestAPI(t).NewPeerConnection(webrtc.Configuration{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { pc.Close() })
+
+	p := &dataPeer{pc: pc, channels: make(chan *webrtc.DataChannel, 2), received: make(chan received, 16)}
+	pc.OnDataChannel(func(dc *webrtc.DataChannel) {
+		dc.OnOpen(func() { p.channels <- dc })
+		dc.OnMessage(func(m webrtc.DataChannelMessage) {
+			var msg sfu.DataMessage
+			json.Unmarshal(m.Data, &msg)
+			p.received <- received{dc.Label(), msg}
+		})
+	})
+
+	go func() {
+		for {
+			var env Envelope
+			if err := wsjson.Read(ctx, conn, &env); err != nil {
+				return
+			}
+			switch env.Type {
+			case MsgOffer:
+				var offer OfferPayload
+				json.Unmarshal(env.Payload, &offer)
+				pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: offer.SDP})
+				answer, _ := pc.CreateAnswer(nil)
+				gathered := webrtc.GatheringCompletePromise(pc)
+				pc.SetLocalDescription(answer)
+				<-gathered
+				reply, _ := NewEnvelope(MsgAnswer, AnswerPayload{SDP: pc.LocalDescription().SDP})
+				wsjson.Write(ctx, conn, reply)
+			case MsgICECandidate:
+				var c ICECandidatePayload
+				json.Unmarshal(env.Payload, &c)
+				pc.AddICECandidate(webrtc.ICECandidateInit{Candidate: c.Candidate})
+			}
+		}
+	}()
+	return p
+}
+
+func newDataTestAPI(t *testing.T) *webrtc.API {
+	t.Helper()
+	m := &webrtc.MediaEngine{}
+	if err := m.RegisterDefaultCodecs(); err != nil {
+		t.Fatal(err)
+	}
+	return webrtc.NewAPI(webrtc.WithMediaEngine(m))
+}
+
+// open waits for both data channels to open and returns them by label.
+func (p *dataPeer) open(t *testing.T, ctx context.Context) map[string]*webrtc.DataChannel {
+	t.Helper()
+	open := make(map[string]*webrtc.DataChannel)
+	for len(open) < 2 {
+		select {
+		case dc := <-p.channels:
+			open[dc.Label()] = dc
+		case <-ctx.Done():
+			t.Fatal("data channels did not open")
+		}
+	}
+	return open
+}
+
+func (p *dataPeer) next(t *testing.T, ctx context.Context) received {
+	t.Helper()
+	select {
+	case r := <-p.received:
+		return r
+	case <-ctx.Done():
+		t.Fatal("no data message received")
+		return received{}
+	}
+}
+
+func TestServer_DataChannelRelay(t *testing.T) {
+	if testing.Short() {
+		t.Skip("negotiates WebRTC connections")
+	}
+	s := sfu.New()
+	defer s.Close()
+	pm := sfu.NewPeerManager(newDataTestAPI(t), sfu.WithICEServers(nil), sfu.WithDataRateLimit(0.01, 2))
+	srv := httptest.NewServer(NewHandler(s, nil, WithPeerManager(pm), WithStatsInterval(0)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Second)
+	defer cancel()
+
+	conn := dialWS(t, ctx, srv, "")
+	var created RoomCreatedPayload
+	json.Unmarshal(roundTrip(t, ctx, conn, MsgCreateRoom, CreateRoomPayload{Name: "Alice"}).Payload, &created)
+	alice, aliceID := connectData(t, ctx, conn), created.PeerID
+	join := func(name string) (*dataPeer, string) {
+		conn := dialWS(t, ctx, srv, "")
+		var joined RoomJoinedPayload
+		json.Unmarshal(roundTrip(t, ctx, conn, MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: name}).Payload, &joined)
+		return connectData(t, ctx, conn), joined.PeerID
+	}
+	bob, bobID := join("Bob")
+	carol, _ := join("Carol")
+
+	aliceChannels := alice.open(t, ctx)
+	aliceData, aliceFast := aliceChannels[sfu.DataChannelOrdered], aliceChannels[sfu.DataChannelUnordered]
+	bob.open(t, ctx)
+	carol.open(t, ctx)
+
+	// A broadcast reaches everyone else, stamped with the sender.
+	aliceData.SendText(`{"from":"someone-else","payload":{"reaction":"tada"}}`)
+	for _, p := range []*dataPeer{bob, carol} {
+		r := p.next(t, ctx)
+		if r.label != sfu.DataChannelOrdered || r.msg.From != aliceID || string(r.msg.Payload) != `{"reaction":"tada"}` {
+			t.Fatalf("got %+v, want alice's reaction on the ordered channel", r)
+		}
+	}
+
+	// A targeted message on the unordered channel reaches only its recipient,
+	// on the same kind of channel.
+	aliceFast.SendText(`{"to":["` + bobID + `"],"payload":{"x":10,"y":20}}`)
+	if r := bob.next(t, ctx); r.label != sfu.DataChannelUnordered || string(r.msg.Payload) != `{"x":10,"y":20}` {
+		t.Fatalf("bob got %+v", r)
+	}
+
+	// Carol was not addressed, and Alice has used her burst of two, so the
+	// next message is dropped.
+	aliceData.SendText(`{"payload":"flood"}`)
+	select {
+	case r := <-carol.received:
+		t.Fatalf("carol got %+v", r)
+	case <-time.After(300 * time.Millisecond):
+	}
+}
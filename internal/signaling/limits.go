@@ -0,0 +1,342 @@
+package signaling
+
+import (
+	"context"
+	"net/http"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+	"golang.org/x/time/rate"
+
+	"voxlink/internal/ratelimit"
+)
+
+// Limits configures abuse protection on the signaling WebSocket. Messages of
+// a type with no rule are not limited. Clients are identified by the remote
+// address of their connection, or behind TrustedProxies by the address those
+// forwarded for; IPv6 clients are grouped by /64, the network one subscriber
+// normally picks addresses from. Relay links between nodes are exempt.
+type Limits struct {
+	PerConn        map[string]ratelimit.Rule // message type → limit per WebSocket
+	PerIP          map[string]ratelimit.Rule // message type → limit per client IP
+	MaxRoomsPerIP  int                       // open rooms created from one IP; 0 is unlimited
+	BanThreshold   int                       // violations within BanWindow that ban the IP; 0 never bans
+	BanWindow      time.Duration
+	BanDuration    time.Duration
+	TrustedProxies []netip.Prefix // reverse proxies whose X-Forwarded-For is believed
+}
+
+// DefaultLimits returns limits that leave room for many users behind one
+// NAT while stopping scripted floods.
+func DefaultLimits() Limits {
+	return Limits{
+		PerConn: map[string]ratelimit.Rule{
+			MsgCreateRoom:   {Rate: 0.2, Burst: 3},
+			MsgJoinRoom:     {Rate: 0.5, Burst: 5},
+			MsgRejoin:       {Rate: 0.5, Burst: 5},
+			MsgICECandidate: {Rate: 20, Burst: 100},
+			MsgChatSend:     {Rate: 2, Burst: 10},
+			MsgChatEdit:     {Rate: 1, Burst: 5},
+			MsgChatDelete:   {Rate: 1, Burst: 5},
+			MsgMute:         {Rate: 2, Burst: 10},
+		},
+		PerIP: map[string]ratelimit.Rule{
+			MsgCreateRoom: {Rate: 0.5, Burst: 10},
+			MsgJoinRoom:   {Rate: 2, Burst: 30},
+			MsgChatSend:   {Rate: 20, Burst: 100},
+		},
+		MaxRoomsPerIP: 10,
+		BanThreshold:  30,
+		BanWindow:     time.Minute,
+		BanDuration:   10 * time.Minute,
+	}
+}
+
+// Error codes of refused messages, sent in ErrorPayload.Code.
+const (
+	ErrCodeRateLimited = "rate_limited"
+	ErrCodeRoomLimit   = "room_limit"
+	ErrCodeBanned      = "banned"
+)
+
+// WithLimits enables rate limits, the per-IP room limit and the ban list.
+// Without it the handler trusts its clients.
+func WithLimits(l Limits) HandlerOption {
+	return func(h *Handler) {
+		g := &guard{
+			limits:  l,
+			perIP:   make(map[string]*ratelimit.Keyed, len(l.PerIP)),
+			bans:    ratelimit.NewBanList(l.BanThreshold, l.BanWindow, l.BanDuration),
+			roomsOf: make(map[string][]string),
+		}
+		for msgType, rule := range l.PerIP {
+			g.perIP[msgType] = ratelimit.NewKeyed(rule)
+		}
+		h.guard = g
+	}
+}
+
+// guard holds the state of the handler's Limits.
+type guard struct {
+	limits Limits
+	perIP  map[string]*ratelimit.Keyed // message type → buckets by IP
+	bans   *ratelimit.BanList
+
+	mu      sync.Mutex
+	roomsOf map[string][]string // IP → codes of rooms created from it; "" while being created
+}
+
+// ipv6ClientPrefix is the prefix length IPv6 clients are grouped by.
+const ipv6ClientPrefix = 64
+
+// clientIP returns the key the limits identify the request's client by. It
+// is the remote address, unless that is a trusted proxy: then it is the
+// rightmost X-Forwarded-For address that is not, as proxies append the
+// address they received a request from and earlier entries can be forged.
+func (h *Handler) clientIP(r *http.Request) string {
+	var trusted []netip.Prefix
+	if h.guard != nil {
+		trusted = h.guard.limits.TrustedProxies
+	}
+	ap, err := netip.ParseAddrPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	addr := ap.Addr().Unmap()
+	if isTrusted(addr, trusted) {
+		hops := strings.Split(strings.Join(r.Header.Values("X-Forwarded-For"), ","), ",")
+		for i := len(hops) - 1; i >= 0; i-- {
+			hop, err := netip.ParseAddr(strings.TrimSpace(hops[i]))
+			if err != nil {
+				break
+			}
+			addr = hop.Unmap()
+			if !isTrusted(addr, trusted) {
+				break
+			}
+		}
+	}
+	return clientKey(addr)
+}
+
+func isTrusted(addr netip.Addr, trusted []netip.Prefix) bool {
+	return slices.ContainsFunc(trusted, func(p netip.Prefix) bool { return p.Contains(addr) })
+}
+
+// clientKey returns the key of a client address: the address itself for
+// IPv4, its /64 for IPv6.
+func clientKey(addr netip.Addr) string {
+	addr = addr.Unmap().WithZone("")
+	if addr.Is6() {
+		p, _ := addr.Prefix(ipv6ClientPrefix)
+		return p.String()
+	}
+	return addr.String()
+}
+
+// banKey returns the key an IP given to Ban or Unban is limited by.
+func banKey(ip string) string {
+	if addr, err := netip.ParseAddr(ip); err == nil {
+		return clientKey(addr)
+	}
+	return ip
+}
+
+// checkBanned refuses the WebSocket upgrade of a banned IP. It reports
+// whether the request may proceed.
+func (h *Handler) checkBanned(w http.ResponseWriter, ip string) bool {
+	if h.guard == nil {
+		return true
+	}
+	until, banned := h.guard.bans.Banned(ip)
+	if !banned {
+		return true
+	}
+	limitedTotal.With("connect", ErrCodeBanned).Inc()
+	w.Header().Set("Retry-After", retryAfterSeconds(time.Until(until)))
+	http.Error(w, "too many requests; try again later", http.StatusTooManyRequests)
+	return false
+}
+
+// admit applies the rate limits to a message and reports whether to handle
+// it. A refused message is answered with a rate_limited error and counts as
+// a violation; a client whose IP collects too many is disconnected, along
+// with the IP's other connections.
+func (h *Handler) admit(ctx context.Context, client *clientConn, msgType string) bool {
+	g := h.guard
+	if g == nil || client.relay != nil {
+		return true
+	}
+	now := time.Now()
+	wait := time.Duration(0)
+	if rule, ok := g.limits.PerConn[msgType]; ok {
+		if client.limiters == nil {
+			client.limiters = make(map[string]*rate.Limiter)
+		}
+		l, ok := client.limiters[msgType]
+		if !ok {
+			l = rule.NewLimiter()
+			client.limiters[msgType] = l
+		}
+		if allowed, d := ratelimit.Allow(l, now); !allowed {
+			wait = d
+		}
+	}
+	if keyed, ok := g.perIP[msgType]; ok && wait == 0 {
+		if allowed, d := keyed.Allow(client.ip); !allowed {
+			wait = d
+		}
+	}
+	if wait == 0 {
+		return true
+	}
+
+	limitedTotal.With(metricLabel(msgType), ErrCodeRateLimited).Inc()
+	if g.bans.Strike(client.ip) {
+		h.banned(client.ip)
+		return false
+	}
+	h.sendErrorPayload(ctx, client, ErrorPayload{
+		Message:      "too many " + msgType + " messages; slow down",
+		Code:         ErrCodeRateLimited,
+		RetryAfterMs: wait.Milliseconds(),
+	})
+	return false
+}
+
+// allowRoom reserves a room for the client's IP and reports whether it got
+// one; rooms that have closed since no longer count. The caller must follow
+// up with roomCreated, or releaseRoom if creating the room fails, so that
+// concurrent creates cannot overshoot the limit.
+func (h *Handler) allowRoom(ctx context.Context, client *clientConn) bool {
+	g := h.guard
+	if g == nil || g.limits.MaxRoomsPerIP <= 0 {
+		return true
+	}
+	g.mu.Lock()
+	open := slices.DeleteFunc(g.roomsOf[client.ip], func(code string) bool {
+		if code == "" {
+			return false // reserved by a create in progress
+		}
+		_, ok := h.sfu.GetRoom(code)
+		return !ok
+	})
+	allowed := len(open) < g.limits.MaxRoomsPerIP
+	if allowed {
+		open = append(open, "")
+	}
+	if len(open) == 0 {
+		delete(g.roomsOf, client.ip)
+	} else {
+		g.roomsOf[client.ip] = open
+	}
+	g.mu.Unlock()
+
+	if allowed {
+		return true
+	}
+	limitedTotal.With(metricLabel(MsgCreateRoom), ErrCodeRoomLimit).Inc()
+	h.sendErrorPayload(ctx, client, ErrorPayload{
+		Message: "too many open rooms; close one before creating another",
+		Code:    ErrCodeRoomLimit,
+	})
+	return false
+}
+
+// roomCreated fills the client's reservation with the room it created.
+func (h *Handler) roomCreated(client *clientConn, code string) {
+	h.settleRoom(client, code)
+}
+
+// releaseRoom gives up the client's reservation after a failed create.
+func (h *Handler) releaseRoom(client *clientConn) {
+	h.settleRoom(client, "")
+}
+
+// settleRoom replaces a reservation of the client's IP with code, or drops
+// it if code is empty.
+func (h *Handler) settleRoom(client *clientConn, code string) {
+	g := h.guard
+	if g == nil || g.limits.MaxRoomsPerIP <= 0 {
+		return
+	}
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	rooms := g.roomsOf[client.ip]
+	i := slices.Index(rooms, "")
+	if i < 0 {
+		return
+	}
+	if code != "" {
+		rooms[i] = code
+		return
+	}
+	rooms = slices.Delete(rooms, i, i+1)
+	if len(rooms) == 0 {
+		delete(g.roomsOf, client.ip)
+	} else {
+		g.roomsOf[client.ip] = rooms
+	}
+}
+
+// Ban bans a client IP, or for IPv6 its /64, for d, or for the configured ban
+// duration if d is zero, and disconnects its clients. It returns the end of the ban, or the
+// zero time if limits are not enabled.
+func (h *Handler) Ban(ip string, d time.Duration) time.Time {
+	if h.guard == nil {
+		return time.Time{}
+	}
+	ip = banKey(ip)
+	until := h.guard.bans.Ban(ip, d)
+	h.banned(ip)
+	return until
+}
+
+// Unban lifts the ban of a client IP and reports whether it was banned.
+func (h *Handler) Unban(ip string) bool {
+	if h.guard == nil {
+		return false
+	}
+	return h.guard.bans.Unban(banKey(ip))
+}
+
+// Bans returns the banned client IPs and IPv6 /64s and when their bans end.
+func (h *Handler) Bans() map[string]time.Time {
+	if h.guard == nil {
+		return nil
+	}
+	return h.guard.bans.Bans()
+}
+
+// banned disconnects every client of a newly banned IP.
+func (h *Handler) banned(ip string) {
+	bansTotal.Inc()
+	h.logger.Warn("client IP banned", zap.String("ip", ip))
+
+	env, _ := NewEnvelope(MsgError, ErrorPayload{Message: "banned for abuse", Code: ErrCodeBanned})
+	h.mu.RLock()
+	var victims []*clientConn
+	for c := range h.conns {
+		if c.ip == ip {
+			victims = append(victims, c)
+		}
+	}
+	h.mu.RUnlock()
+	for _, c := range victims {
+		go func() {
+			c.send(context.Background(), env)
+			c.conn.Close(websocket.StatusPolicyViolation, "banned")
+		}()
+	}
+}
+
+// retryAfterSeconds formats d for a Retry-After header, rounding up.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.Itoa(int((d + time.Second - 1) / time.Second))
+}
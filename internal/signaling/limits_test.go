@@ -0,0 +1,249 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"voxlink/internal/ratelimit"
+	"voxlink/internal/sfu"
+)
+
+func readError(t *testing.T, ctx context.Context, conn *websocket.Conn) ErrorPayload {
+	t.Helper()
+	var p ErrorPayload
+	json.Unmarshal(readType(t, ctx, conn, MsgError).Payload, &p)
+	return p
+}
+
+func sendMutes(t *testing.T, ctx context.Context, conn *websocket.Conn, n int) {
+	t.Helper()
+	env, _ := NewEnvelope(MsgMute, MutePayload{Muted: true})
+	for range n {
+		if err := wsjson.Write(ctx, conn, env); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestLimits_PerConnection(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithLimits(Limits{
+		PerConn: map[string]ratelimit.Rule{MsgMute: {Rate: 0.001, Burst: 2}},
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	conn := dialWS(t, ctx, srv, "")
+	before := limitedTotal.With(MsgMute, ErrCodeRateLimited).Value()
+	sendMutes(t, ctx, conn, 3) // outside a room, allowed mutes get no reply
+	p := readError(t, ctx, conn)
+	if p.Code != ErrCodeRateLimited || p.RetryAfterMs <= 0 {
+		t.Fatalf("got %+v, want a rate_limited error with a retry delay", p)
+	}
+	if got := limitedTotal.With(MsgMute, ErrCodeRateLimited).Value() - before; got != 1 {
+		t.Fatalf("limited metric: got %d, want 1", got)
+	}
+
+	// Other message types and other connections are unaffected.
+	if resp := roundTrip(t, ctx, conn, MsgCreateRoom, CreateRoomPayload{Name: "Alice"}); resp.Type != MsgRoomCreated {
+		t.Fatalf("create-room after a limited mute: got %s", resp.Type)
+	}
+	other := dialWS(t, ctx, srv, "")
+	sendMutes(t, ctx, other, 2)
+	if resp := roundTrip(t, ctx, other, MsgCreateRoom, CreateRoomPayload{Name: "Bob"}); resp.Type != MsgRoomCreated {
+		t.Fatalf("second connection: got %s", resp.Type)
+	}
+}
+
+func TestLimits_PerIP(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithLimits(Limits{
+		PerIP: map[string]ratelimit.Rule{MsgMute: {Rate: 0.001, Burst: 2}},
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	sendMutes(t, ctx, dialWS(t, ctx, srv, ""), 2)
+	conn := dialWS(t, ctx, srv, "")
+	sendMutes(t, ctx, conn, 1)
+	if p := readError(t, ctx, conn); p.Code != ErrCodeRateLimited {
+		t.Fatalf("got %+v, want the IP's bucket to be shared", p)
+	}
+}
+
+func TestLimits_RoomsPerIP(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithLimits(Limits{MaxRoomsPerIP: 1}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	var created RoomCreatedPayload
+	json.Unmarshal(roundTrip(t, ctx, dialWS(t, ctx, srv, ""), MsgCreateRoom, CreateRoomPayload{Name: "Alice"}).Payload, &created)
+
+	conn := dialWS(t, ctx, srv, "")
+	resp := roundTrip(t, ctx, conn, MsgCreateRoom, CreateRoomPayload{Name: "Bob"})
+	var p ErrorPayload
+	json.Unmarshal(resp.Payload, &p)
+	if resp.Type != MsgError || p.Code != ErrCodeRoomLimit {
+		t.Fatalf("second room: got %s %+v, want a room_limit error", resp.Type, p)
+	}
+
+	// Once the first room is gone, the IP may create another.
+	h.CloseRoom(created.Code, "done")
+	if resp := roundTrip(t, ctx, conn, MsgCreateRoom, CreateRoomPayload{Name: "Bob"}); resp.Type != MsgRoomCreated {
+		t.Fatalf("after closing the first room: got %s", resp.Type)
+	}
+}
+
+func TestLimits_RoomsPerIPConcurrent(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithLimits(Limits{MaxRoomsPerIP: 1}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A create that fails gives its reservation back.
+	conn := dialWS(t, ctx, srv, "")
+	if resp := roundTrip(t, ctx, conn, MsgCreateRoom, CreateRoomPayload{Name: "Alice", Code: "no"}); resp.Type != MsgError {
+		t.Fatalf("invalid code: got %s", resp.Type)
+	}
+
+	conns := make([]*websocket.Conn, 8)
+	for i := range conns {
+		conns[i] = dialWS(t, ctx, srv, "")
+	}
+	results := make(chan string, len(conns))
+	for _, c := range conns {
+		go func() {
+			env, _ := NewEnvelope(MsgCreateRoom, CreateRoomPayload{Name: "Bob"})
+			wsjson.Write(ctx, c, env)
+			var resp Envelope
+			wsjson.Read(ctx, c, &resp)
+			results <- resp.Type
+		}()
+	}
+	created := 0
+	for range conns {
+		if <-results == MsgRoomCreated {
+			created++
+		}
+	}
+	if created != 1 {
+		t.Fatalf("%d rooms created at once, want 1", created)
+	}
+}
+
+func TestLimits_Ban(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithLimits(Limits{
+		PerConn:      map[string]ratelimit.Rule{MsgMute: {Rate: 0.001, Burst: 1}},
+		BanThreshold: 2,
+		BanWindow:    time.Minute,
+		BanDuration:  time.Hour,
+	}))
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	bystander := dialWS(t, ctx, srv, "")
+	conn := dialWS(t, ctx, srv, "")
+	sendMutes(t, ctx, conn, 3) // two violations
+	if p := readError(t, ctx, conn); p.Code != ErrCodeRateLimited {
+		t.Fatalf("first violation: got %+v", p)
+	}
+	if p := readError(t, ctx, conn); p.Code != ErrCodeBanned {
+		t.Fatalf("second violation: got %+v, want banned", p)
+	}
+	if _, _, err := conn.Read(ctx); websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+		t.Fatalf("banned client: got %v, want a policy violation close", err)
+	}
+	// The IP's other connections go too, and it cannot reconnect.
+	if p := readError(t, ctx, bystander); p.Code != ErrCodeBanned {
+		t.Fatalf("other connection of the IP: got %+v", p)
+	}
+	_, resp, err := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") == "" {
+		t.Fatalf("reconnect while banned: got %v, %v", resp, err)
+	}
+	if _, ok := h.Bans()["127.0.0.1"]; !ok {
+		t.Fatalf("bans: %v", h.Bans())
+	}
+
+	if !h.Unban("127.0.0.1") {
+		t.Fatal("Unban reported no ban")
+	}
+	dialWS(t, ctx, srv, "")
+}
+
+func TestClientIP(t *testing.T) {
+	proxies := []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8"), netip.MustParsePrefix("fd00::/8")}
+	tests := []struct {
+		name    string
+		trusted []netip.Prefix
+		remote  string
+		xff     []string
+		want    string
+	}{
+		{"direct", proxies, "203.0.113.7:5000", nil, "203.0.113.7"},
+		{"untrusted proxy", nil, "10.0.0.2:5000", []string{"203.0.113.7"}, "10.0.0.2"},
+		{"forged by client", proxies, "203.0.113.7:5000", []string{"198.51.100.1"}, "203.0.113.7"},
+		{"trusted proxy", proxies, "10.0.0.2:5000", []string{"198.51.100.1, 203.0.113.7"}, "203.0.113.7"},
+		{"proxy chain", proxies, "10.0.0.2:5000", []string{"203.0.113.7", "10.1.1.1"}, "203.0.113.7"},
+		{"garbage hop", proxies, "10.0.0.2:5000", []string{"not-an-ip"}, "10.0.0.2"},
+		{"mapped IPv4", nil, "[::ffff:203.0.113.7]:5000", nil, "203.0.113.7"},
+		{"IPv6 /64", nil, "[2001:db8:1:2:aaaa::5]:5000", nil, "2001:db8:1:2::/64"},
+		{"forwarded IPv6", proxies, "[fd00::1]:5000", []string{"2001:db8:1:2::7"}, "2001:db8:1:2::/64"},
+	}
+	s := sfu.New()
+	defer s.Close()
+	for _, tt := range tests {
+		h := NewHandler(s, nil, WithLimits(Limits{TrustedProxies: tt.trusted}))
+		r := httptest.NewRequest(http.MethodGet, "/ws", nil)
+		r.RemoteAddr = tt.remote
+		for _, v := range tt.xff {
+			r.Header.Add("X-Forwarded-For", v)
+		}
+		if got := h.clientIP(r); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBan_GroupsIPv6(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithLimits(DefaultLimits()))
+	h.Ban("2001:db8:1:2::5", time.Minute)
+	if _, banned := h.Bans()["2001:db8:1:2::/64"]; !banned {
+		t.Fatalf("bans: %v, want the /64", h.Bans())
+	}
+	if !h.Unban("2001:db8:1:2:ffff::1") {
+		t.Fatal("unbanning another address of the /64 failed")
+	}
+}
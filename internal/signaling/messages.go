@@ -1,6 +1,11 @@
 package signaling
 
-import "encoding/json"
+import (
+	"encoding/json"
+	"time"
+
+	"voxlink/internal/sfu"
+)
 
 const (
 	MsgCreateRoom   = "create-room"
@@ -10,12 +15,28 @@ const (
 	MsgRejoin       = "rejoin"
 	MsgLeave        = "leave"
 	MsgMute         = "mute"
+	MsgAuth         = "auth"
 	MsgRoomCreated  = "room-created"
 	MsgRoomJoined   = "room-joined"
 	MsgPeerJoined   = "peer-joined"
 	MsgPeerLeft     = "peer-left"
 	MsgOffer        = "offer"
 	MsgPeerMuted    = "peer-muted"
+	MsgStats        = "stats"
+	MsgRoomClosed   = "room-closed"
+	MsgKicked       = "kicked"
+	MsgAuthOK       = "auth-ok"
+	MsgShutdown     = "server-shutdown"
+	MsgRedirect     = "redirect"
+	MsgRelayJoin    = "relay-join"
+	MsgRelayJoined  = "relay-joined"
+	MsgRelayOffer   = "relay-offer"
+	MsgRelayAnswer  = "relay-answer"
+	MsgChatSend     = "chat-send"
+	MsgChatEdit     = "chat-edit"
+	MsgChatDelete   = "chat-delete"
+	MsgChatMessage  = "chat-message"
+	MsgChatDeleted  = "chat-deleted"
 	MsgError        = "error"
 )
 
@@ -25,13 +46,18 @@ type Envelope struct {
 }
 
 type PeerInfo struct {
-	ID    string `json:"id"`
-	Name  string `json:"name"`
-	Muted bool   `json:"muted,omitempty"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Muted  bool   `json:"muted,omitempty"`
+	Role   string `json:"role,omitempty"`
+	UserID string `json:"userId,omitempty"`
+	Node   string `json:"node,omitempty"` // node the peer is connected to, if relayed
 }
 
 type CreateRoomPayload struct {
-	Name string `json:"name"`
+	Name   string `json:"name"`
+	Code   string `json:"code,omitempty"`   // requested code; random if empty
+	Stereo bool   `json:"stereo,omitempty"` // stereo audio, for music
 }
 
 type JoinRoomPayload struct {
@@ -62,17 +88,24 @@ type RoomCreatedPayload struct {
 	Code   string     `json:"code"`
 	PeerID string     `json:"peerId"`
 	Peers  []PeerInfo `json:"peers"`
+	Stereo bool       `json:"stereo,omitempty"`
 }
 
 type RoomJoinedPayload struct {
-	Code   string     `json:"code"`
-	PeerID string     `json:"peerId"`
-	Peers  []PeerInfo `json:"peers"`
+	Code   string               `json:"code"`
+	Title  string               `json:"title,omitempty"` // set for persistent rooms
+	PeerID string               `json:"peerId"`
+	Peers  []PeerInfo           `json:"peers"`
+	Chat   []ChatMessagePayload `json:"chat,omitempty"` // recent messages, oldest first
+	Stereo bool                 `json:"stereo,omitempty"`
 }
 
 type PeerJoinedPayload struct {
-	ID   string `json:"id"`
-	Name string `json:"name"`
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Role   string `json:"role,omitempty"`
+	UserID string `json:"userId,omitempty"`
+	Node   string `json:"node,omitempty"`
 }
 
 type PeerLeftPayload struct {
@@ -88,8 +121,100 @@ type PeerMutedPayload struct {
 	Muted bool   `json:"muted"`
 }
 
+type RoomClosedPayload struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+type KickedPayload struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+// ShutdownPayload announces that the server is going away. ReconnectURL and
+// RetryAfterMs optionally tell clients where and when to reconnect.
+type ShutdownPayload struct {
+	Reason       string `json:"reason,omitempty"`
+	ReconnectURL string `json:"reconnectUrl,omitempty"`
+	RetryAfterMs int64  `json:"retryAfterMs,omitempty"`
+}
+
+// RedirectPayload tells a client that room Code is hosted by another node.
+// The client reconnects to URL and repeats its join-room or rejoin there.
+type RedirectPayload struct {
+	Code   string `json:"code"`
+	URL    string `json:"url"`
+	NodeID string `json:"nodeId,omitempty"`
+}
+
+// RelayJoinPayload opens a relay link: node NodeID asks the room's owner to
+// relay room Code to it. Secret is the cluster's shared relay secret.
+type RelayJoinPayload struct {
+	Code   string `json:"code"`
+	NodeID string `json:"nodeId"`
+	Secret string `json:"secret"`
+}
+
+// RelayJoinedPayload accepts a relay link and lists the room's peers, each
+// tagged with the node it is connected to.
+type RelayJoinedPayload struct {
+	Code    string               `json:"code"`
+	NodeID  string               `json:"nodeId"`
+	Options sfu.RoomOptions      `json:"options"` // applied to the mirror
+	Peers   []PeerInfo           `json:"peers"`
+	Chat    []ChatMessagePayload `json:"chat,omitempty"`
+}
+
+// ChatSendPayload posts a message. PeerID is only set by relay links, which
+// send on behalf of their node's peers; the same holds for the edit and
+// delete payloads.
+type ChatSendPayload struct {
+	Text   string `json:"text"`
+	PeerID string `json:"peerId,omitempty"`
+}
+
+type ChatEditPayload struct {
+	ID     string `json:"id"`
+	Text   string `json:"text"`
+	PeerID string `json:"peerId,omitempty"`
+}
+
+type ChatDeletePayload struct {
+	ID     string `json:"id"`
+	PeerID string `json:"peerId,omitempty"`
+}
+
+// ChatMessagePayload announces a new or edited message to everyone in the
+// room, its author included.
+type ChatMessagePayload struct {
+	ID       string     `json:"id"`
+	PeerID   string     `json:"peerId"`
+	Name     string     `json:"name"`
+	Text     string     `json:"text"`
+	SentAt   time.Time  `json:"sentAt"`
+	EditedAt *time.Time `json:"editedAt,omitempty"`
+}
+
+type ChatDeletedPayload struct {
+	ID string `json:"id"`
+	By string `json:"by"` // peer who deleted the message
+}
+
+// AuthPayload carries a join token for servers that require one.
+type AuthPayload struct {
+	Token string `json:"token"`
+}
+
+// AuthOKPayload confirms the identity established by a join token.
+type AuthOKPayload struct {
+	UserID string `json:"userId"`
+	Name   string `json:"name,omitempty"`
+	Role   string `json:"role"`
+}
+
 type ErrorPayload struct {
-	Message string `json:"message"`
+	Message      string `json:"message"`
+	Code         string `json:"code,omitempty"`         // machine-readable reason, e.g. ErrCodeRateLimited
+	RetryAfterMs int64  `json:"retryAfterMs,omitempty"` // when a refused message may be retried
+	PeerID       string `json:"peerId,omitempty"`       // on a relay link: the linked node's peer the error is for
 }
 
 func NewEnvelope(msgType string, payload any) (Envelope, error) {
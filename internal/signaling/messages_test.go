@@ -78,6 +78,11 @@ func TestAllMessageTypesRoundTrip(t *testing.T) {
 		{"peer-left", MsgPeerLeft, PeerLeftPayload{ID: "uuid-2"}},
 		{"offer", MsgOffer, OfferPayload{SDP: "v=0\r\n..."}},
 		{"peer-muted", MsgPeerMuted, PeerMutedPayload{ID: "uuid-1", Muted: true}},
+		{"redirect", MsgRedirect, RedirectPayload{Code: "ABCD-1234", URL: "https://node2.example.com", NodeID: "node2"}},
+		{"relay-join", MsgRelayJoin, RelayJoinPayload{Code: "ABCD-1234", NodeID: "node2", Secret: "s3cret"}},
+		{"relay-joined", MsgRelayJoined, RelayJoinedPayload{Code: "ABCD-1234", NodeID: "node1", Peers: []PeerInfo{{ID: "uuid-1", Name: "Alice", Node: "node1"}}}},
+		{"relay-offer", MsgRelayOffer, OfferPayload{SDP: "v=0\r\n..."}},
+		{"relay-answer", MsgRelayAnswer, AnswerPayload{SDP: "v=0\r\n..."}},
 		{"error", MsgError, ErrorPayload{Message: "room not found"}},
 	}
 	for _, tc := range cases {
@@ -0,0 +1,46 @@
+package signaling
+
+import "voxlink/internal/metrics"
+
+var (
+	wsConnections = metrics.NewGauge("voxlink_websocket_connections",
+		"Number of open signaling WebSocket connections.")
+	messagesTotal = metrics.NewCounterVec("voxlink_signaling_messages_total",
+		"Signaling messages received, by type.", "type")
+	handlerDuration = metrics.NewHistogramVec("voxlink_signaling_handler_duration_seconds",
+		"Time spent handling a signaling message, by type.", nil, "type")
+	limitedTotal = metrics.NewCounterVec("voxlink_signaling_limited_total",
+		"Signaling messages and connections refused by abuse protection, by message type and reason.", "type", "reason")
+	bansTotal = metrics.NewCounter("voxlink_signaling_bans_total",
+		"Client IPs banned for abuse.")
+	dataMessagesTotal = metrics.NewCounterVec("voxlink_data_messages_total",
+		"Data channel message deliveries, by result: forwarded, undelivered, rate_limited or rejected.", "result")
+)
+
+// knownTypes lists the client message types used as metric labels. Anything
+// else is reported as "unknown" to bound label cardinality.
+var knownTypes = map[string]bool{
+	MsgCreateRoom:   true,
+	MsgJoinRoom:     true,
+	MsgAnswer:       true,
+	MsgICECandidate: true,
+	MsgRejoin:       true,
+	MsgLeave:        true,
+	MsgMute:         true,
+	MsgAuth:         true,
+	MsgRelayJoin:    true,
+	MsgRelayOffer:   true,
+	MsgChatSend:     true,
+	MsgChatEdit:     true,
+	MsgChatDelete:   true,
+	MsgPeerJoined:   true, // relay links forward their peers' events
+	MsgPeerLeft:     true,
+	MsgPeerMuted:    true,
+}
+
+func metricLabel(msgType string) string {
+	if knownTypes[msgType] {
+		return msgType
+	}
+	return "unknown"
+}
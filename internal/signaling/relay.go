@@ -0,0 +1,748 @@
+package signaling
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+	"github.com/google/uuid"
+	"github.com/pion/webrtc/v4"
+	"go.uber.org/zap"
+
+	"voxlink/internal/sfu"
+)
+
+// Cascading: a room can span nodes. A node that receives a join for a room
+// owned by another node (see WithCascade) creates a local mirror of the room
+// and opens a relay link to the owner: a WebSocket to the owner's /ws on
+// which it sends relay-join. Over the link
+//
+//   - the owner lists the room's peers (relay-joined) and forwards peer-joined,
+//     peer-left and peer-muted; the edge sends the same events for its own
+//     peers, so every node sees every participant;
+//   - the owner sends its audio as it would to any subscriber, on a
+//     PeerConnection it offers; tracks carry the source peer ID as stream ID;
+//   - the edge sends its peers' audio on a second PeerConnection it offers
+//     with relay-offer.
+//
+// Links only ever connect a mirror to the room's owner, and neither side
+// sends a peer's events or audio back to the node the peer is connected to,
+// so nothing loops.
+
+// relayDialTimeout bounds opening a relay link, including relay-joined.
+const relayDialTimeout = 10 * time.Second
+
+// WithRelaySecret accepts relay links from nodes presenting secret, and is the
+// secret presented by this node's own links. All nodes of a cluster share it.
+func WithRelaySecret(secret string) HandlerOption {
+	return func(h *Handler) {
+		h.relaySecret = secret
+	}
+}
+
+// WithCascade makes a join-room for a room owned by another node join a local
+// mirror relayed from the owner, instead of redirecting the client there.
+// It requires WithRelaySecret.
+func WithCascade() HandlerOption {
+	return func(h *Handler) {
+		h.cascade = true
+	}
+}
+
+// relayState is the owner's side of a relay link.
+type relayState struct {
+	node string // the linked node
+
+	mu sync.Mutex
+	up *webrtc.PeerConnection // receives the linked node's audio
+}
+
+func (h *Handler) handleRelayJoin(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	var msg RelayJoinPayload
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.sendError(ctx, client, "invalid relay-join payload")
+		return
+	}
+	if h.relaySecret == "" || subtle.ConstantTimeCompare([]byte(msg.Secret), []byte(h.relaySecret)) != 1 {
+		h.logger.Warn("relay link rejected", zap.String("node", msg.NodeID))
+		h.sendError(ctx, client, "relay not authorized")
+		client.conn.Close(websocket.StatusPolicyViolation, "relay not authorized")
+		return
+	}
+	if client.peerID != "" {
+		h.sendError(ctx, client, "already in a room")
+		return
+	}
+	if msg.NodeID == "" || msg.NodeID == h.sfu.Node().ID {
+		h.sendError(ctx, client, "invalid node id")
+		return
+	}
+	if h.isDraining() {
+		h.sendError(ctx, client, "server is shutting down")
+		return
+	}
+	// Only the owner relays a room; mirrors never link onwards.
+	room, ok := h.sfu.GetRoom(msg.Code)
+	if !ok || room.IsMirror() {
+		h.sendError(ctx, client, "room not found")
+		return
+	}
+	msg.Code = room.Code
+
+	id := uuid.NewString()
+	room.AddRemotePeer(sfu.Peer{ID: id, Name: "relay " + msg.NodeID, Role: sfu.RoleRelay, Node: msg.NodeID})
+	link, _ := room.GetPeer(id)
+	client.peerID = link.ID
+	client.roomCode = msg.Code
+	client.relay = &relayState{node: msg.NodeID}
+
+	h.mu.Lock()
+	h.clients[link.ID] = client
+	h.mu.Unlock()
+
+	self := h.sfu.Node().ID
+	peers := make([]PeerInfo, 0)
+	for _, p := range toPeerInfoList(room.PeerList(), link.ID) {
+		if p.Node == msg.NodeID {
+			continue
+		}
+		if p.Node == "" {
+			p.Node = self
+		}
+		peers = append(peers, p)
+	}
+
+	h.logger.Info("relay link opened", zap.String("room", msg.Code), zap.String("node", msg.NodeID))
+
+	env, _ := NewEnvelope(MsgRelayJoined, RelayJoinedPayload{
+		Code: msg.Code, NodeID: self, Options: room.Options, Peers: peers, Chat: toChatList(room.ChatHistory()),
+	})
+	client.send(ctx, env)
+
+	h.setupPeerConnection(ctx, client, link, msg.Code)
+}
+
+// handleRelayEvent applies a peer event sent by a relay link for one of the
+// linked node's peers and passes it on to the rest of the room.
+func (h *Handler) handleRelayEvent(ctx context.Context, client *clientConn, env Envelope) {
+	room, ok := h.sfu.GetRoom(client.roomCode)
+	if !ok {
+		return
+	}
+	node := client.relay.node
+
+	switch env.Type {
+	case MsgPeerJoined:
+		var p PeerJoinedPayload
+		if err := json.Unmarshal(env.Payload, &p); err != nil || p.ID == "" || p.Role == sfu.RoleRelay {
+			return
+		}
+		// A link speaks only for its own node's peers.
+		p.Node = node
+		if !room.AddRemotePeer(sfu.Peer{ID: p.ID, Name: p.Name, Role: p.Role, UserID: p.UserID, Node: node}) {
+			return
+		}
+		env, _ = NewEnvelope(MsgPeerJoined, p)
+	case MsgPeerLeft:
+		var p PeerLeftPayload
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			return
+		}
+		if peer, ok := room.GetPeer(p.ID); !ok || peer.Node != node || peer.Role == sfu.RoleRelay {
+			return
+		}
+		room.RemovePeer(p.ID)
+		h.removeSubscriptionsForPeer(p.ID, client.roomCode)
+	case MsgPeerMuted:
+		var p PeerMutedPayload
+		if err := json.Unmarshal(env.Payload, &p); err != nil {
+			return
+		}
+		peer, ok := room.GetPeer(p.ID)
+		if !ok || peer.Node != node {
+			return
+		}
+		peer.Muted = p.Muted
+	}
+	h.broadcastToRoom(ctx, client.roomCode, client.peerID, env)
+}
+
+// handleRelayOffer answers the PeerConnection on which a relay link sends its
+// node's audio. Tracks are forwarded as if published by the peer named by
+// their stream ID.
+func (h *Handler) handleRelayOffer(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	if client.relay == nil || h.peerManager == nil {
+		h.sendError(ctx, client, "unexpected relay-offer")
+		return
+	}
+	var msg OfferPayload
+	if err := json.Unmarshal(payload, &msg); err != nil {
+		h.sendError(ctx, client, "invalid relay-offer payload")
+		return
+	}
+
+	rs := client.relay
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if rs.up == nil {
+		pc, err := h.peerManager.NewPeerConnection()
+		if err != nil {
+			h.logger.Error("relay peer connection", zap.String("node", rs.node), zap.Error(err))
+			h.sendError(ctx, client, "failed to create WebRTC connection")
+			return
+		}
+		code, node := client.roomCode, rs.node
+		pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+			source := track.StreamID()
+			room, ok := h.sfu.GetRoom(code)
+			if !ok {
+				return
+			}
+			if peer, ok := room.GetPeer(source); !ok || peer.Node != node {
+				h.logger.Warn("relayed track for unknown peer", zap.String("node", node), zap.String("peer", source))
+				return
+			}
+			h.subscribeRoomPeers(ctx, source, code, track)
+		})
+		rs.up = pc
+	}
+
+	sdp, err := answerOffer(ctx, rs.up, msg.SDP)
+	if err != nil {
+		h.logger.Error("answer relay offer", zap.String("node", rs.node), zap.Error(err))
+		h.sendError(ctx, client, "failed to answer relay offer")
+		return
+	}
+	env, _ := NewEnvelope(MsgRelayAnswer, AnswerPayload{SDP: sdp})
+	client.send(ctx, env)
+}
+
+// closeRelayState cleans up after a relay link disconnects: the linked node's
+// peers leave the room.
+func (h *Handler) closeRelayState(ctx context.Context, client *clientConn) {
+	rs := client.relay
+	rs.mu.Lock()
+	if rs.up != nil {
+		rs.up.Close()
+	}
+	rs.mu.Unlock()
+
+	h.logger.Info("relay link closed", zap.String("room", client.roomCode), zap.String("node", rs.node))
+
+	room, ok := h.sfu.GetRoom(client.roomCode)
+	if !ok {
+		return
+	}
+	for _, p := range room.RemoveNodePeers(rs.node) {
+		h.removeSubscriptionsForPeer(p.ID, client.roomCode)
+		if !h.isDraining() {
+			env, _ := NewEnvelope(MsgPeerLeft, PeerLeftPayload{ID: p.ID})
+			h.broadcastToRoom(ctx, client.roomCode, client.peerID, env)
+		}
+	}
+}
+
+// answerOffer applies a remote offer and returns the local answer, with all
+// ICE candidates included since relay PeerConnections do not trickle.
+func answerOffer(ctx context.Context, pc *webrtc.PeerConnection, sdp string) (string, error) {
+	if err := pc.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeOffer, SDP: sdp}); err != nil {
+		return "", fmt.Errorf("set remote description: %w", err)
+	}
+	answer, err := pc.CreateAnswer(nil)
+	if err != nil {
+		return "", fmt.Errorf("create answer: %w", err)
+	}
+	return setLocalComplete(ctx, pc, answer)
+}
+
+// setLocalComplete sets the local description and waits for ICE gathering.
+func setLocalComplete(ctx context.Context, pc *webrtc.PeerConnection, desc webrtc.SessionDescription) (string, error) {
+	gathered := webrtc.GatheringCompletePromise(pc)
+	if err := pc.SetLocalDescription(desc); err != nil {
+		return "", fmt.Errorf("set local description: %w", err)
+	}
+	select {
+	case <-gathered:
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+	return pc.LocalDescription().SDP, nil
+}
+
+// relayError is a refusal reported by the room's owner.
+type relayError string
+
+func (e relayError) Error() string { return string(e) }
+
+// relayLink is an edge node's link to the owner of a mirrored room.
+type relayLink struct {
+	h      *Handler
+	code   string
+	origin sfu.Node
+	room   *sfu.Room
+	conn   *clientConn
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	downMu sync.Mutex
+	down   *webrtc.PeerConnection // receives the owner's audio
+
+	upMu        sync.Mutex
+	up          *webrtc.PeerConnection       // sends this node's audio
+	subs        map[string]*sfu.Subscription // local peer ID → upstream subscription
+	negotiating bool                         // relay-offer awaiting its answer
+	renegotiate bool                         // tracks were added meanwhile
+}
+
+// relayRoom returns the local mirror of a room owned by another node, opening
+// a relay link to the owner if needed. Errors are reported to the client.
+func (h *Handler) relayRoom(ctx context.Context, client *clientConn, code string) (*sfu.Room, bool) {
+	owner, ok := h.remoteOwner(ctx, client, code)
+	if !ok {
+		return nil, false
+	}
+	link, err := h.openRelay(ctx, code, owner)
+	if err != nil {
+		h.logger.Warn("open relay link", zap.String("room", code), zap.String("node", owner.ID), zap.Error(err))
+		var refused relayError
+		if errors.As(err, &refused) {
+			h.sendError(ctx, client, refused.Error())
+		} else {
+			h.sendError(ctx, client, "could not reach the room's host")
+		}
+		return nil, false
+	}
+	return link.room, true
+}
+
+func (h *Handler) openRelay(ctx context.Context, code string, owner sfu.Node) (*relayLink, error) {
+	key := sfu.NormalizeRoomCode(code)
+	h.relayMu.Lock()
+	defer h.relayMu.Unlock()
+	if link := h.relayLinkFor(key); link != nil {
+		return link, nil
+	}
+
+	u, err := relayURL(owner.URL)
+	if err != nil {
+		return nil, err
+	}
+	dialCtx, cancel := context.WithTimeout(ctx, relayDialTimeout)
+	defer cancel()
+	conn, _, err := websocket.Dial(dialCtx, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	conn.SetReadLimit(65536)
+	c := &clientConn{conn: conn}
+
+	env, _ := NewEnvelope(MsgRelayJoin, RelayJoinPayload{Code: code, NodeID: h.sfu.Node().ID, Secret: h.relaySecret})
+	var resp Envelope
+	if err := c.send(dialCtx, env); err == nil {
+		err = wsjson.Read(dialCtx, conn, &resp)
+	}
+	if err != nil {
+		conn.CloseNow()
+		return nil, err
+	}
+	var joined RelayJoinedPayload
+	switch resp.Type {
+	case MsgRelayJoined:
+		if err := json.Unmarshal(resp.Payload, &joined); err != nil {
+			conn.CloseNow()
+			return nil, fmt.Errorf("invalid relay-joined payload: %w", err)
+		}
+	case MsgError:
+		var e ErrorPayload
+		json.Unmarshal(resp.Payload, &e)
+		conn.Close(websocket.StatusNormalClosure, "")
+		return nil, relayError(e.Message)
+	default:
+		conn.CloseNow()
+		return nil, fmt.Errorf("unexpected %s reply to relay-join", resp.Type)
+	}
+
+	code = joined.Code // the owner's spelling
+	room, err := h.sfu.MirrorRoom(code, owner, joined.Options)
+	if err != nil {
+		conn.Close(websocket.StatusNormalClosure, "")
+		return nil, err
+	}
+
+	linkCtx, linkCancel := context.WithCancel(context.Background())
+	link := &relayLink{
+		h:      h,
+		code:   code,
+		origin: owner,
+		room:   room,
+		conn:   c,
+		ctx:    linkCtx,
+		cancel: linkCancel,
+		subs:   make(map[string]*sfu.Subscription),
+	}
+	for _, p := range joined.Peers {
+		link.peerJoined(PeerJoinedPayload{ID: p.ID, Name: p.Name, Role: p.Role, UserID: p.UserID, Node: p.Node})
+	}
+	for _, m := range joined.Chat {
+		room.PutChat(fromChatPayload(m))
+	}
+
+	h.mu.Lock()
+	h.relays[key] = link
+	h.mu.Unlock()
+
+	h.logger.Info("relay link opened", zap.String("room", code), zap.String("node", owner.ID))
+	go link.run()
+	return link, nil
+}
+
+// relayLinkFor returns the outbound link of a mirrored room, whatever the
+// spelling of its code.
+func (h *Handler) relayLinkFor(code string) *relayLink {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.relays[sfu.NormalizeRoomCode(code)]
+}
+
+// relayUpstream sends a local peer event of a mirrored room to its owner.
+func (h *Handler) relayUpstream(code, msgType string, payload any) {
+	link := h.relayLinkFor(code)
+	if link == nil {
+		return
+	}
+	env, _ := NewEnvelope(msgType, payload)
+	if err := link.conn.send(link.ctx, env); err != nil {
+		h.logger.Debug("relay upstream", zap.String("room", code), zap.String("type", msgType), zap.Error(err))
+	}
+}
+
+// relayPublish sends a local peer's track of a mirrored room to its owner.
+func (h *Handler) relayPublish(code, peerID string, track *webrtc.TrackRemote) {
+	if link := h.relayLinkFor(code); link != nil && h.peerManager != nil {
+		link.publish(peerID, track)
+	}
+}
+
+// relayUnpublish stops sending a local peer's track to the room's owner.
+func (h *Handler) relayUnpublish(code, peerID string) {
+	link := h.relayLinkFor(code)
+	if link == nil {
+		return
+	}
+	link.upMu.Lock()
+	defer link.upMu.Unlock()
+	if sub, ok := link.subs[peerID]; ok {
+		sub.Cancel()
+		delete(link.subs, peerID)
+	}
+}
+
+// closeRelays closes every outbound relay link.
+func (h *Handler) closeRelays() {
+	h.mu.RLock()
+	links := make([]*relayLink, 0, len(h.relays))
+	for _, l := range h.relays {
+		links = append(links, l)
+	}
+	h.mu.RUnlock()
+	for _, l := range links {
+		l.cancel()
+	}
+}
+
+// relayURL turns a node's public base URL into its WebSocket endpoint.
+func relayURL(base string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", fmt.Errorf("node url: %w", err)
+	}
+	switch u.Scheme {
+	case "https", "wss":
+		u.Scheme = "wss"
+	case "http", "ws":
+		u.Scheme = "ws"
+	default:
+		return "", fmt.Errorf("node url %q: unsupported scheme", base)
+	}
+	u.Path = strings.TrimSuffix(u.Path, "/") + "/ws"
+	u.RawQuery = ""
+	return u.String(), nil
+}
+
+// run reads the owner's messages until the link or the mirror room closes,
+// then closes the other.
+func (l *relayLink) run() {
+	defer l.close()
+	go func() {
+		select {
+		case <-l.room.Done():
+		case <-l.ctx.Done():
+		}
+		l.conn.conn.Close(websocket.StatusNormalClosure, "room closed")
+	}()
+
+	for {
+		var env Envelope
+		if err := wsjson.Read(l.ctx, l.conn.conn, &env); err != nil {
+			l.h.logger.Info("relay link ended", zap.String("room", l.code), zap.String("node", l.origin.ID), zap.Error(err))
+			return
+		}
+		if !l.handle(env) {
+			return
+		}
+	}
+}
+
+// handle processes one message from the owner and reports whether the link
+// stays open.
+func (l *relayLink) handle(env Envelope) bool {
+	switch env.Type {
+	case MsgPeerJoined:
+		var p PeerJoinedPayload
+		if json.Unmarshal(env.Payload, &p) == nil {
+			l.peerJoined(p)
+		}
+	case MsgPeerLeft:
+		var p PeerLeftPayload
+		if json.Unmarshal(env.Payload, &p) == nil {
+			l.peerLeft(p.ID)
+		}
+	case MsgPeerMuted:
+		var p PeerMutedPayload
+		if json.Unmarshal(env.Payload, &p) != nil {
+			break
+		}
+		if peer, ok := l.room.GetPeer(p.ID); ok && peer.Remote() {
+			peer.Muted = p.Muted
+			l.h.broadcastToRoom(l.ctx, l.code, p.ID, env)
+		}
+	case MsgChatMessage:
+		var p ChatMessagePayload
+		if json.Unmarshal(env.Payload, &p) == nil {
+			l.room.PutChat(fromChatPayload(p))
+			l.h.broadcastToRoom(l.ctx, l.code, "", env)
+		}
+	case MsgChatDeleted:
+		var p ChatDeletedPayload
+		if json.Unmarshal(env.Payload, &p) == nil {
+			l.room.RemoveChat(p.ID)
+			l.h.broadcastToRoom(l.ctx, l.code, "", env)
+		}
+	case MsgOffer:
+		var p OfferPayload
+		if json.Unmarshal(env.Payload, &p) == nil {
+			l.answerDown(p.SDP)
+		}
+	case MsgICECandidate:
+		var p ICECandidatePayload
+		if json.Unmarshal(env.Payload, &p) != nil {
+			break
+		}
+		l.downMu.Lock()
+		if l.down != nil {
+			if err := l.down.AddICECandidate(webrtc.ICECandidateInit{Candidate: p.Candidate}); err != nil {
+				l.h.logger.Warn("relay ICE candidate", zap.String("room", l.code), zap.Error(err))
+			}
+		}
+		l.downMu.Unlock()
+	case MsgRelayAnswer:
+		var p AnswerPayload
+		if json.Unmarshal(env.Payload, &p) == nil {
+			l.upAnswered(p.SDP)
+		}
+	case MsgRoomClosed:
+		var p RoomClosedPayload
+		json.Unmarshal(env.Payload, &p)
+		l.h.CloseRoom(l.code, p.Reason)
+		return false
+	case MsgKicked, MsgShutdown:
+		l.h.CloseRoom(l.code, "the room's host went away")
+		return false
+	case MsgError:
+		var p ErrorPayload
+		json.Unmarshal(env.Payload, &p)
+		if p.PeerID != "" && l.errorToPeer(p) {
+			break
+		}
+		l.h.logger.Warn("relay link error", zap.String("room", l.code), zap.String("message", p.Message))
+	}
+	return true
+}
+
+// errorToPeer passes an error the owner reported for one of this node's
+// peers, such as a refused chat message, on to that peer's client. Returns
+// false if the peer is not connected here.
+func (l *relayLink) errorToPeer(p ErrorPayload) bool {
+	if peer, ok := l.room.GetPeer(p.PeerID); !ok || peer.Remote() {
+		return false
+	}
+	l.h.mu.RLock()
+	c, ok := l.h.clients[p.PeerID]
+	l.h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+	p.PeerID = ""
+	env, _ := NewEnvelope(MsgError, p)
+	go c.send(l.ctx, env)
+	return true
+}
+
+// peerJoined adds a peer of another node to the mirror and announces it to
+// local clients. Events about this node's own peers are ignored.
+func (l *relayLink) peerJoined(p PeerJoinedPayload) {
+	if p.Node == "" {
+		p.Node = l.origin.ID
+	}
+	if p.Node == l.h.sfu.Node().ID || p.Role == sfu.RoleRelay {
+		return
+	}
+	if !l.room.AddRemotePeer(sfu.Peer{ID: p.ID, Name: p.Name, Role: p.Role, UserID: p.UserID, Node: p.Node}) {
+		return
+	}
+	env, _ := NewEnvelope(MsgPeerJoined, p)
+	l.h.broadcastToRoom(l.ctx, l.code, p.ID, env)
+}
+
+func (l *relayLink) peerLeft(id string) {
+	peer, ok := l.room.GetPeer(id)
+	if !ok || !peer.Remote() {
+		return
+	}
+	l.room.RemovePeer(id)
+	l.h.removeSubscriptionsForPeer(id, l.code)
+	env, _ := NewEnvelope(MsgPeerLeft, PeerLeftPayload{ID: id})
+	l.h.broadcastToRoom(l.ctx, l.code, id, env)
+}
+
+// answerDown answers the owner's offer for the PeerConnection carrying the
+// room's audio to this node, which forwards it to local peers.
+func (l *relayLink) answerDown(sdp string) {
+	pm := l.h.peerManager
+	if pm == nil {
+		return
+	}
+	l.downMu.Lock()
+	defer l.downMu.Unlock()
+	if l.down == nil {
+		pc, err := pm.NewPeerConnection()
+		if err != nil {
+			l.h.logger.Error("relay peer connection", zap.String("room", l.code), zap.Error(err))
+			return
+		}
+		pc.OnTrack(func(track *webrtc.TrackRemote, _ *webrtc.RTPReceiver) {
+			l.h.subscribeRoomPeers(l.ctx, track.StreamID(), l.code, track)
+		})
+		l.down = pc
+	}
+	answer, err := answerOffer(l.ctx, l.down, sdp)
+	if err != nil {
+		l.h.logger.Error("answer relay offer", zap.String("room", l.code), zap.Error(err))
+		return
+	}
+	env, _ := NewEnvelope(MsgAnswer, AnswerPayload{SDP: answer})
+	l.conn.send(l.ctx, env)
+}
+
+// publish adds a local peer's track to the upstream PeerConnection.
+func (l *relayLink) publish(peerID string, track *webrtc.TrackRemote) {
+	l.upMu.Lock()
+	defer l.upMu.Unlock()
+	if _, exists := l.subs[peerID]; exists {
+		return
+	}
+	if l.up == nil {
+		pc, err := l.h.peerManager.NewPeerConnection()
+		if err != nil {
+			l.h.logger.Error("relay peer connection", zap.String("room", l.code), zap.Error(err))
+			return
+		}
+		l.up = pc
+	}
+	sub, err := l.h.peerManager.SubscribeToTrackAs(l.ctx, track, l.up, peerID)
+	if err != nil {
+		l.h.logger.Error("relay track", zap.String("room", l.code), zap.String("peer", peerID), zap.Error(err))
+		return
+	}
+	l.subs[peerID] = sub
+	l.offerUpLocked()
+}
+
+// offerUpLocked sends a relay-offer, or defers it until the outstanding one
+// is answered. l.upMu must be held.
+func (l *relayLink) offerUpLocked() {
+	if l.negotiating {
+		l.renegotiate = true
+		return
+	}
+	offer, err := l.up.CreateOffer(nil)
+	if err != nil {
+		l.h.logger.Error("relay create offer", zap.String("room", l.code), zap.Error(err))
+		return
+	}
+	sdp, err := setLocalComplete(l.ctx, l.up, offer)
+	if err != nil {
+		l.h.logger.Error("relay offer", zap.String("room", l.code), zap.Error(err))
+		return
+	}
+	l.negotiating, l.renegotiate = true, false
+	env, _ := NewEnvelope(MsgRelayOffer, OfferPayload{SDP: sdp})
+	l.conn.send(l.ctx, env)
+}
+
+func (l *relayLink) upAnswered(sdp string) {
+	l.upMu.Lock()
+	defer l.upMu.Unlock()
+	if l.up == nil || !l.negotiating {
+		return
+	}
+	if err := l.up.SetRemoteDescription(webrtc.SessionDescription{Type: webrtc.SDPTypeAnswer, SDP: sdp}); err != nil {
+		l.h.logger.Error("relay set answer", zap.String("room", l.code), zap.Error(err))
+	}
+	l.negotiating = false
+	if l.renegotiate {
+		l.offerUpLocked()
+	}
+}
+
+// close tears the link down; if the mirror room is still open, its local
+// peers are disconnected since the room can no longer be relayed.
+func (l *relayLink) close() {
+	l.cancel()
+	l.conn.conn.CloseNow()
+
+	h := l.h
+	h.mu.Lock()
+	if key := sfu.NormalizeRoomCode(l.code); h.relays[key] == l {
+		delete(h.relays, key)
+	}
+	h.mu.Unlock()
+
+	l.upMu.Lock()
+	for id, sub := range l.subs {
+		sub.Cancel()
+		delete(l.subs, id)
+	}
+	if l.up != nil {
+		l.up.Close()
+	}
+	l.upMu.Unlock()
+	l.downMu.Lock()
+	if l.down != nil {
+		l.down.Close()
+	}
+	l.downMu.Unlock()
+
+	if room, ok := h.sfu.GetRoom(l.code); ok && room == l.room {
+		h.CloseRoom(l.code, "lost connection to the room's host")
+	}
+	h.logger.Info("relay link closed", zap.String("room", l.code), zap.String("node", l.origin.ID))
+}
@@ -0,0 +1,272 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"voxlink/internal/sfu"
+)
+
+const testRelaySecret = "relay-test-secret"
+
+// relayTestNode starts a node sharing dir; its public URL is its test server.
+func relayTestNode(t *testing.T, id string, dir sfu.RoomDirectory, opts ...HandlerOption) (*sfu.SFU, *Handler, *httptest.Server) {
+	t.Helper()
+	var h *Handler
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { h.ServeHTTP(w, r) }))
+	t.Cleanup(srv.Close)
+	s := sfu.NewWithConfig(sfu.Config{
+		GracePeriod: time.Minute,
+		GCInterval:  time.Minute,
+		Directory:   dir,
+		Node:        sfu.Node{ID: id, URL: srv.URL},
+	})
+	t.Cleanup(s.Close)
+	h = NewHandler(s, nil, append([]HandlerOption{WithRelaySecret(testRelaySecret)}, opts...)...)
+	return s, h, srv
+}
+
+// readType reads messages until one of the given type arrives.
+func readType(t *testing.T, ctx context.Context, conn *websocket.Conn, msgType string) Envelope {
+	t.Helper()
+	for {
+		var env Envelope
+		if err := wsjson.Read(ctx, conn, &env); err != nil {
+			t.Fatalf("waiting for %s: %v", msgType, err)
+		}
+		if env.Type == msgType {
+			return env
+		}
+	}
+}
+
+func TestRelay_PeerFederation(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	_, _, srvA := relayTestNode(t, "a", dir)
+	b, _, srvB := relayTestNode(t, "b", dir, WithCascade())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	alice := dialWS(t, ctx, srvA, "")
+	created := roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice"})
+	var rc RoomCreatedPayload
+	json.Unmarshal(created.Payload, &rc)
+
+	// Bob joins through node b and sees Alice on node a.
+	bob := dialWS(t, ctx, srvB, "")
+	resp := roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: rc.Code, Name: "Bob"})
+	if resp.Type != MsgRoomJoined {
+		t.Fatalf("bob join: got %s %s", resp.Type, resp.Payload)
+	}
+	var joined RoomJoinedPayload
+	json.Unmarshal(resp.Payload, &joined)
+	if len(joined.Peers) != 1 || joined.Peers[0].ID != rc.PeerID || joined.Peers[0].Node != "a" {
+		t.Fatalf("bob's peer list: %+v", joined.Peers)
+	}
+	if room, ok := b.GetRoom(rc.Code); !ok || room.Origin.ID != "a" {
+		t.Fatal("node b should host a mirror of the room")
+	}
+
+	// Alice hears about Bob, tagged with his node; the relay link itself is hidden.
+	env := readType(t, ctx, alice, MsgPeerJoined)
+	var pj PeerJoinedPayload
+	json.Unmarshal(env.Payload, &pj)
+	if pj.ID != joined.PeerID || pj.Name != "Bob" || pj.Node != "b" {
+		t.Fatalf("alice got peer-joined %+v", pj)
+	}
+
+	// Carol joins on node a; Bob hears about her.
+	carol := dialWS(t, ctx, srvA, "")
+	roundTrip(t, ctx, carol, MsgJoinRoom, JoinRoomPayload{Code: rc.Code, Name: "Carol"})
+	env = readType(t, ctx, bob, MsgPeerJoined)
+	json.Unmarshal(env.Payload, &pj)
+	if pj.Name != "Carol" || pj.Node != "a" {
+		t.Fatalf("bob got peer-joined %+v", pj)
+	}
+	readType(t, ctx, alice, MsgPeerJoined) // Carol
+
+	// Mute state crosses the link.
+	env, _ = NewEnvelope(MsgMute, MutePayload{Muted: true})
+	wsjson.Write(ctx, bob, env)
+	env = readType(t, ctx, alice, MsgPeerMuted)
+	var pm PeerMutedPayload
+	json.Unmarshal(env.Payload, &pm)
+	if pm.ID != joined.PeerID || !pm.Muted {
+		t.Fatalf("alice got peer-muted %+v", pm)
+	}
+
+	// Bob leaves; Alice sees him go.
+	bob.Close(websocket.StatusNormalClosure, "")
+	env = readType(t, ctx, alice, MsgPeerLeft)
+	var pl PeerLeftPayload
+	json.Unmarshal(env.Payload, &pl)
+	if pl.ID != joined.PeerID {
+		t.Fatalf("alice got peer-left %+v", pl)
+	}
+}
+
+func TestRelay_MirrorKeepsRoomOptions(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	pm := sfu.NewPeerManager(sfu.NewWebRTCAPI(), sfu.WithStereoAPI(sfu.NewStereoWebRTCAPI()))
+	a, _, _ := relayTestNode(t, "a", dir)
+	b, _, srvB := relayTestNode(t, "b", dir, WithCascade(), WithPeerManager(pm))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code, err := a.CreateRoomContext(ctx, sfu.RoomOptions{Stereo: true, MaxPeers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	bob := dialWS(t, ctx, srvB, "")
+	resp := roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Bob"})
+	var joined RoomJoinedPayload
+	json.Unmarshal(resp.Payload, &joined)
+	if resp.Type != MsgRoomJoined || !joined.Stereo {
+		t.Fatalf("join: got %s %s", resp.Type, resp.Payload)
+	}
+	if room, _ := b.GetRoom(code); room.Options != (sfu.RoomOptions{Stereo: true, MaxPeers: 4}) {
+		t.Fatalf("mirror options: %+v", room.Options)
+	}
+
+	// Bob is offered stereo Opus by the node he is connected to.
+	env := readType(t, ctx, bob, MsgOffer)
+	var offer OfferPayload
+	json.Unmarshal(env.Payload, &offer)
+	if !strings.Contains(offer.SDP, "stereo=1") {
+		t.Fatalf("offer lacks stereo=1:\n%s", offer.SDP)
+	}
+}
+
+func TestRelay_OneLinkPerRoom(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	a, _, srvA := relayTestNode(t, "a", dir)
+	_, edge, srvB := relayTestNode(t, "b", dir, WithCascade())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code, err := a.CreateRoomWithCode(ctx, "ABC-0", sfu.RoomOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Two spellings of the code, joined at once, share one link.
+	done := make(chan string, 2)
+	for _, spelling := range []string{"ABC-0", "abc-O"} {
+		conn := dialWS(t, ctx, srvB, "")
+		go func() {
+			env, _ := NewEnvelope(MsgJoinRoom, JoinRoomPayload{Code: spelling, Name: "Bob"})
+			wsjson.Write(ctx, conn, env)
+			var resp Envelope
+			wsjson.Read(ctx, conn, &resp)
+			done <- resp.Type
+		}()
+	}
+	for range 2 {
+		if got := <-done; got != MsgRoomJoined {
+			t.Fatalf("join: got %s", got)
+		}
+	}
+	link, err := edge.openRelay(ctx, "Abc-o", sfu.Node{ID: "a", URL: srvA.URL})
+	if err != nil || link != edge.relayLinkFor(code) {
+		t.Fatalf("a third spelling got another link: %v", err)
+	}
+
+	room, _ := a.GetRoom(code)
+	relays := 0
+	for _, p := range room.PeerList() {
+		if p.Role == sfu.RoleRelay {
+			relays++
+		}
+	}
+	if relays != 1 {
+		t.Fatalf("owner has %d relay links, want 1", relays)
+	}
+}
+
+func TestRelay_OwnerClosesRoom(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	a, owner, _ := relayTestNode(t, "a", dir)
+	b, _, srvB := relayTestNode(t, "b", dir, WithCascade())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	code := a.CreateRoom()
+	bob := dialWS(t, ctx, srvB, "")
+	if resp := roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Bob"}); resp.Type != MsgRoomJoined {
+		t.Fatalf("join: got %s %s", resp.Type, resp.Payload)
+	}
+
+	// Closing the room on its owner closes the mirror and its clients.
+	owner.CloseRoom(code, "meeting over")
+	env := readType(t, ctx, bob, MsgRoomClosed)
+	var rc RoomClosedPayload
+	json.Unmarshal(env.Payload, &rc)
+	if rc.Reason != "meeting over" {
+		t.Fatalf("room-closed reason: %q", rc.Reason)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, ok := b.GetRoom(code); !ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("mirror room survived its origin")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestRelay_JoinRejected(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	a, _, srvA := relayTestNode(t, "a", dir)
+	_, _, srvB := relayTestNode(t, "b", dir, WithCascade())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	code := a.CreateRoom()
+
+	// Wrong secret.
+	conn := dialWS(t, ctx, srvA, "")
+	resp := roundTrip(t, ctx, conn, MsgRelayJoin, RelayJoinPayload{Code: code, NodeID: "x", Secret: "wrong"})
+	if resp.Type != MsgError {
+		t.Fatalf("wrong secret: got %s", resp.Type)
+	}
+
+	// Mirrors do not relay onwards, so links cannot form chains or loops.
+	bob := dialWS(t, ctx, srvB, "")
+	roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Bob"})
+	conn = dialWS(t, ctx, srvB, "")
+	resp = roundTrip(t, ctx, conn, MsgRelayJoin, RelayJoinPayload{Code: code, NodeID: "c", Secret: testRelaySecret})
+	var e ErrorPayload
+	json.Unmarshal(resp.Payload, &e)
+	if resp.Type != MsgError || e.Message != "room not found" {
+		t.Fatalf("relay from mirror: got %s %s", resp.Type, resp.Payload)
+	}
+}
+
+func TestRelayURL(t *testing.T) {
+	for base, want := range map[string]string{
+		"https://node1.example.com":     "wss://node1.example.com/ws",
+		"http://10.0.0.2:8080/":         "ws://10.0.0.2:8080/ws",
+		"https://example.com/voice?x=1": "wss://example.com/voice/ws",
+	} {
+		got, err := relayURL(base)
+		if err != nil || got != want {
+			t.Errorf("relayURL(%q) = %q, %v; want %q", base, got, err, want)
+		}
+	}
+	if _, err := relayURL("ftp://example.com"); err == nil {
+		t.Error("expected an error for an unsupported scheme")
+	}
+}
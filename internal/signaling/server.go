@@ -3,6 +3,7 @@ package signaling
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"sync"
 	"time"
@@ -11,15 +12,24 @@ import (
 	"github.com/coder/websocket/wsjson"
 	"github.com/pion/webrtc/v4"
 	"go.uber.org/zap"
+	"golang.org/x/time/rate"
 
+	"voxlink/internal/auth"
+	"voxlink/internal/origin"
 	"voxlink/internal/sfu"
 )
 
 type clientConn struct {
 	conn     *websocket.Conn
+	ip       string // remote address, for per-IP limits
 	peerID   string
 	roomCode string
 	mu       sync.Mutex
+
+	stopStats context.CancelFunc       // stops the periodic stats push, if running
+	identity  *JoinClaims              // verified join token; nil until authenticated
+	relay     *relayState              // set if the client is another node's relay link
+	limiters  map[string]*rate.Limiter // per-connection limits by message type; read loop only
 }
 
 func (c *clientConn) send(ctx context.Context, env Envelope) error {
@@ -30,35 +40,59 @@ func (c *clientConn) send(ctx context.Context, env Envelope) error {
 	return wsjson.Write(ctx, c.conn, env)
 }
 
+// DefaultStatsInterval is how often connection statistics are pushed to clients.
+const DefaultStatsInterval = 5 * time.Second
+
 // Handler manages signaling state.
 type Handler struct {
-	sfu         *sfu.SFU
-	peerManager *sfu.PeerManager
-	logger      *zap.Logger
+	sfu           *sfu.SFU
+	peerManager   *sfu.PeerManager
+	logger        *zap.Logger
+	mux           *http.ServeMux
+	statsInterval time.Duration
+	joinVerifier  *auth.JWTVerifier // nil: join tokens are not required
+	origins       *origin.Policy    // nil: same-origin browsers only
+	relaySecret   string            // shared by the cluster's nodes; "" disables relay links
+	cascade       bool              // relay rooms of other nodes instead of redirecting
+	guard         *guard            // nil: no rate limits or bans
+
+	relayMu sync.Mutex            // serializes opening relay links
+	relays  map[string]*relayLink // normalized room code → outbound link to the room's owner
 
 	mu          sync.RWMutex
 	clients     map[string]*clientConn
 	webrtcPeers map[string]*sfu.WebRTCPeer // peerID → WebRTCPeer
+	conns       map[*clientConn]struct{}   // every open WebSocket, in a room or not
+	draining    bool                       // set by Shutdown; no new connections or joins
+	active      sync.WaitGroup             // running connection handlers
 }
 
 // NewHandler creates a signaling handler backed by the given SFU.
 // peerManager may be nil (e.g. in tests) — WebRTC setup is skipped when nil.
-func NewHandler(s *sfu.SFU, logger *zap.Logger, opts ...HandlerOption) http.Handler {
+func NewHandler(s *sfu.SFU, logger *zap.Logger, opts ...HandlerOption) *Handler {
 	if logger == nil {
 		logger, _ = zap.NewDevelopment()
 	}
 	h := &Handler{
-		sfu:         s,
-		logger:      logger,
-		clients:     make(map[string]*clientConn),
-		webrtcPeers: make(map[string]*sfu.WebRTCPeer),
+		sfu:           s,
+		logger:        logger,
+		statsInterval: DefaultStatsInterval,
+		clients:       make(map[string]*clientConn),
+		webrtcPeers:   make(map[string]*sfu.WebRTCPeer),
+		conns:         make(map[*clientConn]struct{}),
+		relays:        make(map[string]*relayLink),
 	}
 	for _, opt := range opts {
 		opt(h)
 	}
-	mux := http.NewServeMux()
-	mux.HandleFunc("/ws", h.handleWS)
-	return mux
+	h.mux = http.NewServeMux()
+	h.mux.HandleFunc("/ws", h.handleWS)
+	return h
+}
+
+// ServeHTTP implements http.Handler.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	h.mux.ServeHTTP(w, r)
 }
 
 // HandlerOption configures optional Handler fields.
@@ -71,9 +105,143 @@ func WithPeerManager(pm *sfu.PeerManager) HandlerOption {
 	}
 }
 
+// WithStatsInterval sets how often connection statistics are pushed to each
+// client with a WebRTC connection. Zero disables the push.
+func WithStatsInterval(d time.Duration) HandlerOption {
+	return func(h *Handler) {
+		h.statsInterval = d
+	}
+}
+
+// WithOriginPolicy sets which browser origins may open a WebSocket. Without
+// it only same-origin pages (and non-browser clients) can connect.
+func WithOriginPolicy(p *origin.Policy) HandlerOption {
+	return func(h *Handler) {
+		h.origins = p
+	}
+}
+
+// PeerStats returns a statistics snapshot for a peer with an active WebRTC
+// connection in the given room.
+func (h *Handler) PeerStats(roomCode, peerID string) (sfu.PeerStats, bool) {
+	room, ok := h.sfu.GetRoom(roomCode)
+	if !ok {
+		return sfu.PeerStats{}, false
+	}
+	if _, ok := room.GetPeer(peerID); !ok {
+		return sfu.PeerStats{}, false
+	}
+	h.mu.RLock()
+	wp, ok := h.webrtcPeers[peerID]
+	h.mu.RUnlock()
+	if !ok {
+		return sfu.PeerStats{}, false
+	}
+	return wp.Stats(), true
+}
+
+// PeerConnectionState returns the WebRTC connection state of a peer, or ""
+// if the peer has no PeerConnection.
+func (h *Handler) PeerConnectionState(peerID string) string {
+	h.mu.RLock()
+	wp, ok := h.webrtcPeers[peerID]
+	h.mu.RUnlock()
+	if !ok {
+		return ""
+	}
+	return wp.PC.ConnectionState().String()
+}
+
+// CloseRoom sends room-closed to every client in the room, disconnects them
+// and removes the room. Returns false if the room does not exist.
+func (h *Handler) CloseRoom(code, reason string) bool {
+	room, ok := h.sfu.GetRoom(code)
+	if !ok {
+		return false
+	}
+
+	env, _ := NewEnvelope(MsgRoomClosed, RoomClosedPayload{Reason: reason})
+	for _, p := range room.PeerList() {
+		h.disconnectClient(p.ID, env, websocket.StatusGoingAway, reason)
+	}
+	h.sfu.CloseRoom(code)
+
+	h.logger.Info("room closed", zap.String("code", code), zap.String("reason", reason))
+	return true
+}
+
+// KickPeer sends kicked to a peer and disconnects it; the remaining peers
+// receive peer-left. Returns false if the room or peer does not exist.
+func (h *Handler) KickPeer(code, peerID, reason string) bool {
+	room, ok := h.sfu.GetRoom(code)
+	if !ok {
+		return false
+	}
+	if _, ok := room.GetPeer(peerID); !ok {
+		return false
+	}
+
+	env, _ := NewEnvelope(MsgKicked, KickedPayload{Reason: reason})
+	if !h.disconnectClient(peerID, env, websocket.StatusPolicyViolation, reason) {
+		// No live connection to clean up after; remove the peer directly.
+		room.RemovePeer(peerID)
+		left, _ := NewEnvelope(MsgPeerLeft, PeerLeftPayload{ID: peerID})
+		h.broadcastToRoom(context.Background(), code, peerID, left)
+	}
+
+	h.logger.Info("peer kicked", zap.String("room", code), zap.String("peer", peerID), zap.String("reason", reason))
+	return true
+}
+
+// disconnectClient sends env to a peer's client and then closes its WebSocket.
+// The client's read loop performs the usual disconnect cleanup. Returns false
+// if the peer has no connected client.
+func (h *Handler) disconnectClient(peerID string, env Envelope, status websocket.StatusCode, reason string) bool {
+	h.mu.RLock()
+	c, ok := h.clients[peerID]
+	h.mu.RUnlock()
+	if !ok {
+		return false
+	}
+
+	// Close reasons are limited to 123 bytes by the WebSocket protocol.
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	go func() {
+		ctx := context.Background()
+		if err := c.send(ctx, env); err != nil {
+			h.logger.Debug("send disconnect notice", zap.String("peer", peerID), zap.Error(err))
+		}
+		c.conn.Close(status, reason)
+	}()
+	return true
+}
+
 func (h *Handler) handleWS(w http.ResponseWriter, r *http.Request) {
+	if h.isDraining() {
+		http.Error(w, "server is shutting down", http.StatusServiceUnavailable)
+		return
+	}
+	ip := h.clientIP(r)
+	if !h.checkBanned(w, ip) {
+		return
+	}
+
+	var identity *JoinClaims
+	if token := r.URL.Query().Get("token"); token != "" && h.joinVerifier != nil {
+		claims, err := h.verifyJoinToken(token)
+		if err != nil {
+			h.logger.Info("join token rejected", zap.String("remote", r.RemoteAddr), zap.Error(err))
+			http.Error(w, "invalid token", http.StatusUnauthorized)
+			return
+		}
+		identity = claims
+	}
+
 	conn, err := websocket.Accept(w, r, &websocket.AcceptOptions{
-		InsecureSkipVerify: true,
+		InsecureSkipVerify: h.origins.Permissive(),
+		OriginPatterns:     h.origins.Patterns(),
 	})
 	if err != nil {
 		h.logger.Error("websocket accept", zap.Error(err))
@@ -81,11 +249,23 @@ func (h *Handler) handleWS(w http.ResponseWriter, r *http.Request) {
 	}
 	defer conn.CloseNow()
 
+	wsConnections.Inc()
+	defer wsConnections.Dec()
+
 	conn.SetReadLimit(65536)
 	ctx := r.Context()
-	client := &clientConn{conn: conn}
+	client := &clientConn{conn: conn, ip: ip, identity: identity}
 
-	h.logger.Info("client connected", zap.String("remote", r.RemoteAddr))
+	if !h.track(client) {
+		conn.Close(websocket.StatusGoingAway, "server is shutting down")
+		return
+	}
+	defer h.untrack(client)
+
+	h.logger.Info("client connected", zap.String("remote", r.RemoteAddr), zap.String("user", client.userID()))
+	if identity != nil {
+		h.sendAuthOK(ctx, client)
+	}
 
 	for {
 		var env Envelope
@@ -101,7 +281,24 @@ func (h *Handler) handleWS(w http.ResponseWriter, r *http.Request) {
 
 		h.logger.Debug("recv", zap.String("type", env.Type), zap.String("peer", client.peerID))
 
+		start := time.Now()
+		label := metricLabel(env.Type)
+		messagesTotal.With(label).Inc()
+
+		if h.joinVerifier != nil && client.identity == nil && client.relay == nil &&
+			env.Type != MsgAuth && env.Type != MsgRelayJoin {
+			h.sendError(ctx, client, "authentication required")
+			conn.Close(websocket.StatusPolicyViolation, "authentication required")
+			return
+		}
+		if !h.admit(ctx, client, env.Type) {
+			handlerDuration.With(label).Observe(time.Since(start).Seconds())
+			continue
+		}
+
 		switch env.Type {
+		case MsgAuth:
+			h.handleAuth(ctx, client, env.Payload)
 		case MsgCreateRoom:
 			h.handleCreateRoom(ctx, client, env.Payload)
 		case MsgJoinRoom:
@@ -116,22 +313,61 @@ func (h *Handler) handleWS(w http.ResponseWriter, r *http.Request) {
 			h.handleLeave(ctx, client)
 		case MsgMute:
 			h.handleMute(ctx, client, env.Payload)
+		case MsgRelayJoin:
+			h.handleRelayJoin(ctx, client, env.Payload)
+		case MsgRelayOffer:
+			h.handleRelayOffer(ctx, client, env.Payload)
+		case MsgChatSend:
+			h.handleChatSend(ctx, client, env.Payload)
+		case MsgChatEdit:
+			h.handleChatEdit(ctx, client, env.Payload)
+		case MsgChatDelete:
+			h.handleChatDelete(ctx, client, env.Payload)
+		case MsgPeerJoined, MsgPeerLeft, MsgPeerMuted:
+			if client.relay == nil {
+				h.sendError(ctx, client, "unknown message type: "+env.Type)
+				break
+			}
+			h.handleRelayEvent(ctx, client, env)
 		default:
 			h.sendError(ctx, client, "unknown message type: "+env.Type)
 		}
+
+		handlerDuration.With(label).Observe(time.Since(start).Seconds())
 	}
 }
 
 func (h *Handler) handleCreateRoom(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	if h.isDraining() {
+		h.sendError(ctx, client, "server is shutting down")
+		return
+	}
 	var msg CreateRoomPayload
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		h.sendError(ctx, client, "invalid create-room payload")
 		return
 	}
 
-	code := h.sfu.CreateRoom()
+	name, role, ok := h.authorizeCreate(ctx, client, msg.Name)
+	if !ok || !h.allowRoom(ctx, client) {
+		return
+	}
+
+	code, err := h.sfu.CreateRoomWithCode(ctx, msg.Code, sfu.RoomOptions{Stereo: msg.Stereo})
+	switch {
+	case errors.Is(err, sfu.ErrInvalidCode), errors.Is(err, sfu.ErrCodeTaken):
+		h.releaseRoom(client)
+		h.sendError(ctx, client, err.Error())
+		return
+	case err != nil:
+		h.releaseRoom(client)
+		h.logger.Error("create room", zap.Error(err))
+		h.sendError(ctx, client, "failed to create room")
+		return
+	}
+	h.roomCreated(client, code)
 	room, _ := h.sfu.GetRoom(code)
-	peer := room.AddPeer(msg.Name)
+	peer := room.AddUserPeer(client.userID(), name, role)
 
 	client.peerID = peer.ID
 	client.roomCode = code
@@ -140,12 +376,13 @@ func (h *Handler) handleCreateRoom(ctx context.Context, client *clientConn, payl
 	h.clients[peer.ID] = client
 	h.mu.Unlock()
 
-	h.logger.Info("room created", zap.String("code", code), zap.String("peer", peer.ID), zap.String("name", msg.Name))
+	h.logger.Info("room created", zap.String("code", code), zap.String("peer", peer.ID), zap.String("name", peer.Name))
 
 	env, _ := NewEnvelope(MsgRoomCreated, RoomCreatedPayload{
 		Code:   code,
 		PeerID: peer.ID,
 		Peers:  []PeerInfo{},
+		Stereo: room.Options.Stereo,
 	})
 	client.send(ctx, env)
 
@@ -154,20 +391,37 @@ func (h *Handler) handleCreateRoom(ctx context.Context, client *clientConn, payl
 }
 
 func (h *Handler) handleJoinRoom(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	if h.isDraining() {
+		h.sendError(ctx, client, "server is shutting down")
+		return
+	}
 	var msg JoinRoomPayload
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		h.sendError(ctx, client, "invalid join-room payload")
 		return
 	}
 
-	room, ok := h.sfu.GetRoom(msg.Code)
+	name, role, ok := h.authorizeJoin(ctx, client, msg.Code, msg.Name)
 	if !ok {
-		h.sendError(ctx, client, "room not found")
 		return
 	}
 
-	existingPeers := room.PeerList()
-	peer := room.AddPeer(msg.Name)
+	room, ok := h.sfu.GetRoom(msg.Code)
+	if !ok && h.cascade {
+		room, ok = h.relayRoom(ctx, client, msg.Code)
+		if !ok {
+			return
+		}
+	} else if !ok {
+		h.routeToOwner(ctx, client, msg.Code)
+		return
+	}
+	msg.Code = room.Code // as created, whatever spelling the client used
+	peer, err := room.Join(client.userID(), name, role)
+	if err != nil {
+		h.sendError(ctx, client, err.Error())
+		return
+	}
 	client.peerID = peer.ID
 	client.roomCode = msg.Code
 
@@ -175,25 +429,30 @@ func (h *Handler) handleJoinRoom(ctx context.Context, client *clientConn, payloa
 	h.clients[peer.ID] = client
 	h.mu.Unlock()
 
-	peerInfos := make([]PeerInfo, 0, len(existingPeers))
-	for _, p := range existingPeers {
-		peerInfos = append(peerInfos, PeerInfo{ID: p.ID, Name: p.Name, Muted: p.Muted})
-	}
+	peerInfos := toPeerInfoList(room.PeerList(), peer.ID)
 
-	h.logger.Info("peer joined", zap.String("room", msg.Code), zap.String("peer", peer.ID), zap.String("name", msg.Name))
+	h.logger.Info("peer joined", zap.String("room", msg.Code), zap.String("peer", peer.ID), zap.String("name", peer.Name))
 
 	joinedEnv, _ := NewEnvelope(MsgRoomJoined, RoomJoinedPayload{
 		Code:   msg.Code,
+		Title:  room.Meta.Title,
 		PeerID: peer.ID,
 		Peers:  peerInfos,
+		Chat:   toChatList(room.ChatHistory()),
+		Stereo: room.Options.Stereo,
 	})
 	client.send(ctx, joinedEnv)
 
-	notifEnv, _ := NewEnvelope(MsgPeerJoined, PeerJoinedPayload{
-		ID:   peer.ID,
-		Name: peer.Name,
-	})
+	joined := PeerJoinedPayload{
+		ID:     peer.ID,
+		Name:   peer.Name,
+		Role:   peer.Role,
+		UserID: peer.UserID,
+	}
+	notifEnv, _ := NewEnvelope(MsgPeerJoined, joined)
 	h.broadcastToRoom(ctx, msg.Code, peer.ID, notifEnv)
+	joined.Node = h.sfu.Node().ID
+	h.relayUpstream(msg.Code, MsgPeerJoined, joined)
 
 	// Set up WebRTC PeerConnection for the joining peer.
 	h.setupPeerConnection(ctx, client, peer, msg.Code)
@@ -220,6 +479,7 @@ func (h *Handler) handleMute(ctx context.Context, client *clientConn, payload js
 	}
 	env, _ := NewEnvelope(MsgPeerMuted, PeerMutedPayload{ID: client.peerID, Muted: msg.Muted})
 	h.broadcastToRoom(ctx, client.roomCode, client.peerID, env)
+	h.relayUpstream(client.roomCode, MsgPeerMuted, PeerMutedPayload{ID: client.peerID, Muted: msg.Muted})
 }
 
 func (h *Handler) handleAnswer(ctx context.Context, client *clientConn, payload json.RawMessage) {
@@ -297,6 +557,10 @@ func (h *Handler) handleICECandidate(ctx context.Context, client *clientConn, pa
 }
 
 func (h *Handler) handleRejoin(ctx context.Context, client *clientConn, payload json.RawMessage) {
+	if h.isDraining() {
+		h.sendError(ctx, client, "server is shutting down")
+		return
+	}
 	var msg RejoinPayload
 	if err := json.Unmarshal(payload, &msg); err != nil {
 		h.sendError(ctx, client, "invalid rejoin payload")
@@ -304,21 +568,30 @@ func (h *Handler) handleRejoin(ctx context.Context, client *clientConn, payload
 	}
 	room, ok := h.sfu.GetRoom(msg.Code)
 	if !ok {
-		h.sendError(ctx, client, "room not found")
+		h.routeToOwner(ctx, client, msg.Code)
 		return
 	}
+	msg.Code = room.Code
 	peer, ok := room.GetPeer(msg.PeerID)
 	if !ok {
 		h.sendError(ctx, client, "peer not found or grace period expired")
 		return
 	}
+	// The peer was admitted when it created or joined the room; matching
+	// its user is all a rejoin needs. Hosts of rooms they created may hold
+	// no room grant at all.
+	if h.joinVerifier != nil && peer.UserID != client.userID() {
+		h.sendError(ctx, client, "not allowed to rejoin as this peer")
+		return
+	}
 	client.peerID = peer.ID
 	client.roomCode = msg.Code
 	h.mu.Lock()
 	h.clients[peer.ID] = client
 	h.mu.Unlock()
 	env, _ := NewEnvelope(MsgRoomJoined, RoomJoinedPayload{
-		Code: msg.Code, PeerID: peer.ID, Peers: toPeerInfoList(room.PeerList(), peer.ID),
+		Code: msg.Code, Title: room.Meta.Title, PeerID: peer.ID, Peers: toPeerInfoList(room.PeerList(), peer.ID),
+		Chat: toChatList(room.ChatHistory()), Stereo: room.Options.Stereo,
 	})
 	client.send(ctx, env)
 
@@ -326,13 +599,42 @@ func (h *Handler) handleRejoin(ctx context.Context, client *clientConn, payload
 	h.setupPeerConnection(ctx, client, peer, msg.Code)
 }
 
+// routeToOwner handles a join or rejoin for a room this node does not host:
+// if the room directory names another node as its owner the client is
+// redirected there, otherwise the room does not exist.
+func (h *Handler) routeToOwner(ctx context.Context, client *clientConn, code string) {
+	owner, ok := h.remoteOwner(ctx, client, code)
+	if !ok {
+		return
+	}
+	h.logger.Info("redirecting to room owner", zap.String("room", code), zap.String("node", owner.ID))
+	env, _ := NewEnvelope(MsgRedirect, RedirectPayload{Code: code, URL: owner.URL, NodeID: owner.ID})
+	client.send(ctx, env)
+}
+
+// remoteOwner looks up the node hosting a room that is not on this node. If
+// there is none it reports the error to the client and returns false.
+func (h *Handler) remoteOwner(ctx context.Context, client *clientConn, code string) (sfu.Node, bool) {
+	owner, ok, err := h.sfu.LookupOwner(ctx, code)
+	if err != nil {
+		h.logger.Error("room directory lookup", zap.String("room", code), zap.Error(err))
+		h.sendError(ctx, client, "room lookup failed")
+		return sfu.Node{}, false
+	}
+	if !ok || owner.ID == h.sfu.Node().ID || owner.URL == "" {
+		h.sendError(ctx, client, "room not found")
+		return sfu.Node{}, false
+	}
+	return owner, true
+}
+
 func toPeerInfoList(peers []sfu.Peer, excludeID string) []PeerInfo {
 	out := make([]PeerInfo, 0, len(peers))
 	for _, p := range peers {
-		if p.ID == excludeID {
+		if p.ID == excludeID || p.Role == sfu.RoleRelay {
 			continue
 		}
-		out = append(out, PeerInfo{ID: p.ID, Name: p.Name, Muted: p.Muted})
+		out = append(out, PeerInfo{ID: p.ID, Name: p.Name, Muted: p.Muted, Role: p.Role, UserID: p.UserID, Node: p.Node})
 	}
 	return out
 }
@@ -345,6 +647,11 @@ func (h *Handler) handleDisconnect(ctx context.Context, client *clientConn) {
 	peerID := client.peerID
 	roomCode := client.roomCode
 
+	if client.stopStats != nil {
+		client.stopStats()
+		client.stopStats = nil
+	}
+
 	// Close WebRTC PeerConnection and cancel all subscriptions.
 	h.mu.Lock()
 	wp, hasWP := h.webrtcPeers[peerID]
@@ -371,8 +678,15 @@ func (h *Handler) handleDisconnect(ctx context.Context, client *clientConn) {
 		room.RemovePeer(peerID)
 	}
 
-	env, _ := NewEnvelope(MsgPeerLeft, PeerLeftPayload{ID: peerID})
-	h.broadcastToRoom(ctx, roomCode, peerID, env)
+	if client.relay != nil {
+		h.closeRelayState(ctx, client)
+	} else if !h.isDraining() {
+		// During shutdown every client is going away; skip the peer-left noise.
+		env, _ := NewEnvelope(MsgPeerLeft, PeerLeftPayload{ID: peerID})
+		h.broadcastToRoom(ctx, roomCode, peerID, env)
+	}
+	h.relayUnpublish(roomCode, peerID)
+	h.relayUpstream(roomCode, MsgPeerLeft, PeerLeftPayload{ID: peerID})
 
 	client.peerID = ""
 	client.roomCode = ""
@@ -386,7 +700,11 @@ func (h *Handler) setupPeerConnection(ctx context.Context, client *clientConn, p
 		return
 	}
 
-	pc, offer, err := h.peerManager.CreatePeerConnection()
+	var opts sfu.RoomOptions
+	if room, ok := h.sfu.GetRoom(roomCode); ok {
+		opts = room.Options
+	}
+	pc, data, offer, err := h.peerManager.CreatePeerConnectionWithOptions(opts)
 	if err != nil {
 		h.logger.Error("create peer connection", zap.String("peer", peer.ID), zap.Error(err))
 		h.sendError(ctx, client, "failed to create WebRTC connection")
@@ -396,12 +714,14 @@ func (h *Handler) setupPeerConnection(ctx context.Context, client *clientConn, p
 	wp := &sfu.WebRTCPeer{
 		Peer: peer,
 		PC:   pc,
+		Data: data,
 		Subs: make(map[string]*sfu.Subscription),
 	}
 
 	h.mu.Lock()
 	h.webrtcPeers[peer.ID] = wp
 	h.mu.Unlock()
+	h.serveData(wp, roomCode)
 
 	peerID := peer.ID
 
@@ -438,12 +758,59 @@ func (h *Handler) setupPeerConnection(ctx context.Context, client *clientConn, p
 			zap.String("codec", remoteTrack.Codec().MimeType),
 		)
 
-		// Forward this track to every other WebRTC peer in the same room.
+		// Forward this track to every other WebRTC peer in the same room,
+		// and to the room's owner if the room is relayed from another node.
 		h.subscribeRoomPeers(ctx, peerID, roomCode, remoteTrack)
+		h.relayPublish(roomCode, peerID, remoteTrack)
 	})
 
 	// Send the SDP offer to the client.
 	h.sendOffer(ctx, client, peerID, offer)
+
+	h.startStatsPush(ctx, client, peerID)
+}
+
+// startStatsPush periodically sends the peer's connection statistics to the
+// client until the client leaves or the connection ends. Any previous push for
+// the same client is stopped first.
+func (h *Handler) startStatsPush(ctx context.Context, client *clientConn, peerID string) {
+	if h.statsInterval <= 0 {
+		return
+	}
+	if client.stopStats != nil {
+		client.stopStats()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	client.stopStats = cancel
+
+	go func() {
+		ticker := time.NewTicker(h.statsInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+
+			h.mu.RLock()
+			wp, ok := h.webrtcPeers[peerID]
+			h.mu.RUnlock()
+			if !ok {
+				return
+			}
+
+			env, err := NewEnvelope(MsgStats, wp.Stats())
+			if err != nil {
+				h.logger.Error("marshal stats", zap.String("peer", peerID), zap.Error(err))
+				continue
+			}
+			if err := client.send(ctx, env); err != nil {
+				h.logger.Debug("send stats", zap.String("peer", peerID), zap.Error(err))
+				return
+			}
+		}
+	}()
 }
 
 // sendOffer sends an SDP offer to a client via WebSocket.
@@ -466,11 +833,20 @@ func (h *Handler) subscribeRoomPeers(ctx context.Context, sourcePeerID, roomCode
 	if !ok {
 		return
 	}
+	source, ok := room.GetPeer(sourcePeerID)
+	if !ok {
+		return
+	}
+	sourceNode := source.Node
 
 	for _, peer := range room.PeerList() {
 		if peer.ID == sourcePeerID {
 			continue
 		}
+		// Never send a track back to the node it came from.
+		if peer.Role == sfu.RoleRelay && peer.Node == sourceNode {
+			continue
+		}
 
 		subscriberID := peer.ID
 
@@ -489,7 +865,14 @@ func (h *Handler) subscribeRoomPeers(ctx context.Context, sourcePeerID, roomCode
 			continue
 		}
 
-		sub, err := h.peerManager.SubscribeToTrack(ctx, remoteTrack, subWP.PC)
+		var sub *sfu.Subscription
+		var err error
+		if peer.Role == sfu.RoleRelay {
+			// Relays attribute tracks by stream ID.
+			sub, err = h.peerManager.SubscribeToTrackAs(ctx, remoteTrack, subWP.PC, sourcePeerID)
+		} else {
+			sub, err = h.peerManager.SubscribeToTrack(ctx, remoteTrack, subWP.PC)
+		}
 		if err != nil {
 			subWP.Mu.Unlock()
 			h.logger.Error("subscribe to track",
@@ -568,6 +951,9 @@ func (h *Handler) broadcastToRoom(ctx context.Context, roomCode, excludeID strin
 	if !ok {
 		return
 	}
+	// The sends outlive the caller, whose context often belongs to a client
+	// that is just disconnecting.
+	ctx = context.WithoutCancel(ctx)
 	h.mu.RLock()
 	defer h.mu.RUnlock()
 	for _, peer := range room.PeerList() {
@@ -581,6 +967,10 @@ func (h *Handler) broadcastToRoom(ctx context.Context, roomCode, excludeID strin
 }
 
 func (h *Handler) sendError(ctx context.Context, client *clientConn, msg string) {
-	env, _ := NewEnvelope(MsgError, ErrorPayload{Message: msg})
+	h.sendErrorPayload(ctx, client, ErrorPayload{Message: msg})
+}
+
+func (h *Handler) sendErrorPayload(ctx context.Context, client *clientConn, p ErrorPayload) {
+	env, _ := NewEnvelope(MsgError, p)
 	client.send(ctx, env)
 }
@@ -3,6 +3,7 @@ package signaling
 import (
 	"context"
 	"encoding/json"
+	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
@@ -10,6 +11,7 @@ import (
 	"github.com/coder/websocket"
 	"github.com/coder/websocket/wsjson"
 
+	"voxlink/internal/origin"
 	"voxlink/internal/sfu"
 )
 
@@ -103,6 +105,48 @@ func TestServer_JoinRoom(t *testing.T) {
 	}
 }
 
+func TestServer_StereoRoom(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	srv := httptest.NewServer(NewHandler(s, nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alice, _, _ := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	defer alice.CloseNow()
+
+	env, _ := NewEnvelope(MsgCreateRoom, CreateRoomPayload{Name: "Alice", Stereo: true})
+	wsjson.Write(ctx, alice, env)
+
+	var aliceResp Envelope
+	wsjson.Read(ctx, alice, &aliceResp)
+	var created RoomCreatedPayload
+	json.Unmarshal(aliceResp.Payload, &created)
+	if !created.Stereo {
+		t.Fatal("room-created: stereo not set")
+	}
+	if room, _ := s.GetRoom(created.Code); !room.Options.Stereo {
+		t.Fatal("room options: stereo not set")
+	}
+
+	bob, _, _ := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	defer bob.CloseNow()
+
+	joinEnv, _ := NewEnvelope(MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: "Bob"})
+	wsjson.Write(ctx, bob, joinEnv)
+
+	var bobResp Envelope
+	wsjson.Read(ctx, bob, &bobResp)
+	var joined RoomJoinedPayload
+	json.Unmarshal(bobResp.Payload, &joined)
+	if !joined.Stereo {
+		t.Fatal("room-joined: stereo not set")
+	}
+}
+
 func TestServer_JoinNonexistentRoom(t *testing.T) {
 	s := sfu.New()
 	defer s.Close()
@@ -125,3 +169,240 @@ func TestServer_JoinNonexistentRoom(t *testing.T) {
 		t.Fatalf("type: got %q, want %q", resp.Type, MsgError)
 	}
 }
+
+func TestServer_RequestedCode(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	srv := httptest.NewServer(NewHandler(s, nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alice := dialWS(t, ctx, srv, "")
+	resp := roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice", Code: "lobby-1"})
+	var created RoomCreatedPayload
+	json.Unmarshal(resp.Payload, &created)
+	if resp.Type != MsgRoomCreated || created.Code != "LOBBY-1" {
+		t.Fatalf("create: got %s %s", resp.Type, resp.Payload)
+	}
+
+	// The code is taken, whatever its spelling.
+	other := dialWS(t, ctx, srv, "")
+	if resp := roundTrip(t, ctx, other, MsgCreateRoom, CreateRoomPayload{Name: "Eve", Code: "LOBBY-I"}); resp.Type != MsgError {
+		t.Fatalf("duplicate code: got %s", resp.Type)
+	}
+
+	// Joining by a lookalike spelling reports the room's own code.
+	bob := dialWS(t, ctx, srv, "")
+	resp = roundTrip(t, ctx, bob, MsgJoinRoom, JoinRoomPayload{Code: "lobby-i", Name: "Bob"})
+	var joined RoomJoinedPayload
+	json.Unmarshal(resp.Payload, &joined)
+	if resp.Type != MsgRoomJoined || joined.Code != "LOBBY-1" {
+		t.Fatalf("join: got %s %s", resp.Type, resp.Payload)
+	}
+}
+
+func TestServer_RedirectToOwner(t *testing.T) {
+	dir := sfu.NewMemoryDirectory()
+	nodeA := sfu.Node{ID: "a", URL: "https://a.example.com"}
+	a := sfu.NewWithConfig(sfu.Config{GracePeriod: time.Minute, GCInterval: time.Minute, Directory: dir, Node: nodeA})
+	defer a.Close()
+	b := sfu.NewWithConfig(sfu.Config{GracePeriod: time.Minute, GCInterval: time.Minute, Directory: dir, Node: sfu.Node{ID: "b"}})
+	defer b.Close()
+
+	code := a.CreateRoom()
+
+	srv := httptest.NewServer(NewHandler(b, nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	conn := dialWS(t, ctx, srv, "")
+
+	for _, tc := range []struct {
+		msgType string
+		payload any
+	}{
+		{MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Bob"}},
+		{MsgRejoin, RejoinPayload{Code: code, PeerID: "p1"}},
+	} {
+		resp := roundTrip(t, ctx, conn, tc.msgType, tc.payload)
+		if resp.Type != MsgRedirect {
+			t.Fatalf("%s: type: got %q (%s), want %q", tc.msgType, resp.Type, resp.Payload, MsgRedirect)
+		}
+		var p RedirectPayload
+		json.Unmarshal(resp.Payload, &p)
+		if p.Code != code || p.URL != nodeA.URL || p.NodeID != nodeA.ID {
+			t.Fatalf("%s: redirect: got %+v", tc.msgType, p)
+		}
+	}
+
+	// Once the owner closes the room, other nodes report it as missing.
+	a.CloseRoom(code)
+	if resp := roundTrip(t, ctx, conn, MsgJoinRoom, JoinRoomPayload{Code: code, Name: "Bob"}); resp.Type != MsgError {
+		t.Fatalf("after close: type: got %q, want %q", resp.Type, MsgError)
+	}
+}
+
+func TestServer_MessageMetrics(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	srv := httptest.NewServer(NewHandler(s, nil))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	created := messagesTotal.With(MsgCreateRoom).Value()
+	unknown := messagesTotal.With("unknown").Value()
+
+	conn, _, err := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.CloseNow()
+
+	env, _ := NewEnvelope(MsgCreateRoom, CreateRoomPayload{Name: "Alice"})
+	wsjson.Write(ctx, conn, env)
+	var resp Envelope
+	wsjson.Read(ctx, conn, &resp)
+
+	bogus, _ := NewEnvelope("no-such-type", struct{}{})
+	wsjson.Write(ctx, conn, bogus)
+	wsjson.Read(ctx, conn, &resp)
+
+	if got := messagesTotal.With(MsgCreateRoom).Value() - created; got != 1 {
+		t.Fatalf("create-room messages: got %d, want 1", got)
+	}
+	if got := messagesTotal.With("unknown").Value() - unknown; got != 1 {
+		t.Fatalf("unknown messages: got %d, want 1", got)
+	}
+	if wsConnections.Value() < 1 {
+		t.Fatalf("websocket connections: got %d, want >= 1", wsConnections.Value())
+	}
+}
+
+func TestServer_KickPeer(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alice, _, _ := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	defer alice.CloseNow()
+	env, _ := NewEnvelope(MsgCreateRoom, CreateRoomPayload{Name: "Alice"})
+	wsjson.Write(ctx, alice, env)
+	var resp Envelope
+	wsjson.Read(ctx, alice, &resp)
+	var created RoomCreatedPayload
+	json.Unmarshal(resp.Payload, &created)
+
+	bob, _, _ := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	defer bob.CloseNow()
+	joinEnv, _ := NewEnvelope(MsgJoinRoom, JoinRoomPayload{Code: created.Code, Name: "Bob"})
+	wsjson.Write(ctx, bob, joinEnv)
+	var joined RoomJoinedPayload
+	wsjson.Read(ctx, bob, &resp)
+	json.Unmarshal(resp.Payload, &joined)
+	wsjson.Read(ctx, alice, &resp) // peer-joined
+
+	if !h.KickPeer(created.Code, joined.PeerID, "spam") {
+		t.Fatal("KickPeer should succeed")
+	}
+
+	wsjson.Read(ctx, bob, &resp)
+	if resp.Type != MsgKicked {
+		t.Fatalf("bob: got %q, want %q", resp.Type, MsgKicked)
+	}
+	var kicked KickedPayload
+	json.Unmarshal(resp.Payload, &kicked)
+	if kicked.Reason != "spam" {
+		t.Fatalf("reason: got %q, want %q", kicked.Reason, "spam")
+	}
+	if err := wsjson.Read(ctx, bob, &resp); websocket.CloseStatus(err) != websocket.StatusPolicyViolation {
+		t.Fatalf("close status: got %v, want %v", websocket.CloseStatus(err), websocket.StatusPolicyViolation)
+	}
+
+	wsjson.Read(ctx, alice, &resp)
+	if resp.Type != MsgPeerLeft {
+		t.Fatalf("alice: got %q, want %q", resp.Type, MsgPeerLeft)
+	}
+
+	if h.KickPeer(created.Code, "nobody", "") {
+		t.Fatal("KickPeer should fail for an unknown peer")
+	}
+}
+
+func TestServer_CloseRoom(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	alice, _, _ := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", nil)
+	defer alice.CloseNow()
+	env, _ := NewEnvelope(MsgCreateRoom, CreateRoomPayload{Name: "Alice"})
+	wsjson.Write(ctx, alice, env)
+	var resp Envelope
+	wsjson.Read(ctx, alice, &resp)
+	var created RoomCreatedPayload
+	json.Unmarshal(resp.Payload, &created)
+
+	if !h.CloseRoom(created.Code, "maintenance") {
+		t.Fatal("CloseRoom should succeed")
+	}
+
+	wsjson.Read(ctx, alice, &resp)
+	if resp.Type != MsgRoomClosed {
+		t.Fatalf("alice: got %q, want %q", resp.Type, MsgRoomClosed)
+	}
+	if _, ok := s.GetRoom(created.Code); ok {
+		t.Fatal("room should be removed")
+	}
+
+	// The server closes the connection after the notice.
+	if err := wsjson.Read(ctx, alice, &resp); websocket.CloseStatus(err) != websocket.StatusGoingAway {
+		t.Fatalf("close status: got %v, want %v", websocket.CloseStatus(err), websocket.StatusGoingAway)
+	}
+}
+
+func TestServer_OriginPolicy(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	p, _ := origin.NewPolicy([]string{"*.example.com"})
+	srv := httptest.NewServer(NewHandler(s, nil, WithOriginPolicy(p)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	dial := func(o string) error {
+		conn, _, err := websocket.Dial(ctx, "ws"+srv.URL[4:]+"/ws", &websocket.DialOptions{
+			HTTPHeader: http.Header{"Origin": {o}},
+		})
+		if err == nil {
+			conn.CloseNow()
+		}
+		return err
+	}
+	if err := dial("https://app.example.com"); err != nil {
+		t.Fatalf("allowed origin rejected: %v", err)
+	}
+	if err := dial("https://evil.com"); err == nil {
+		t.Fatal("disallowed origin accepted")
+	}
+}
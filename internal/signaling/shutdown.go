@@ -0,0 +1,118 @@
+package signaling
+
+import (
+	"context"
+
+	"github.com/coder/websocket"
+	"go.uber.org/zap"
+
+	"voxlink/internal/sfu"
+)
+
+// Shutdown drains the handler: it stops accepting connections, joins and
+// room creation, sends notice to every connected client as a server-shutdown
+// message, closes their WebSockets and waits for their connection handlers
+// to close their PeerConnections and subscriptions. Relay links to other
+// nodes are closed last. If ctx expires first, the remaining connections are
+// closed forcibly and ctx.Err() is returned.
+func (h *Handler) Shutdown(ctx context.Context, notice ShutdownPayload) error {
+	defer h.closeRelays()
+
+	h.mu.Lock()
+	h.draining = true
+	conns := make([]*clientConn, 0, len(h.conns))
+	for c := range h.conns {
+		conns = append(conns, c)
+	}
+	h.mu.Unlock()
+
+	h.logger.Info("draining signaling connections", zap.Int("connections", len(conns)))
+
+	env, _ := NewEnvelope(MsgShutdown, notice)
+	reason := notice.Reason
+	if reason == "" {
+		reason = "server shutting down"
+	}
+	// Close reasons are limited to 123 bytes by the WebSocket protocol.
+	if len(reason) > 123 {
+		reason = reason[:123]
+	}
+	for _, c := range conns {
+		go func() {
+			if err := c.send(ctx, env); err != nil {
+				// The read loop owns c.peerID; log the IP, which never changes.
+				h.logger.Debug("send shutdown notice", zap.String("ip", c.ip), zap.Error(err))
+			}
+			c.conn.Close(websocket.StatusGoingAway, reason)
+		}()
+	}
+
+	done := make(chan struct{})
+	go func() {
+		h.active.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+	}
+
+	h.logger.Warn("drain timed out, closing remaining connections")
+	for _, c := range conns {
+		// CloseNow waits for a close handshake already in progress, so do not
+		// block on it here.
+		go c.conn.CloseNow()
+	}
+	h.closeWebRTCPeers()
+	return ctx.Err()
+}
+
+// isDraining reports whether Shutdown has been called.
+func (h *Handler) isDraining() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.draining
+}
+
+// track registers an accepted connection so Shutdown can reach it. It
+// returns false if the handler is draining.
+func (h *Handler) track(c *clientConn) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.draining {
+		return false
+	}
+	h.conns[c] = struct{}{}
+	h.active.Add(1)
+	return true
+}
+
+// untrack removes a connection registered by track once its handler is done.
+func (h *Handler) untrack(c *clientConn) {
+	h.mu.Lock()
+	delete(h.conns, c)
+	h.mu.Unlock()
+	h.active.Done()
+}
+
+// closeWebRTCPeers closes every remaining PeerConnection and its
+// subscriptions.
+func (h *Handler) closeWebRTCPeers() {
+	h.mu.Lock()
+	peers := h.webrtcPeers
+	h.webrtcPeers = make(map[string]*sfu.WebRTCPeer)
+	h.mu.Unlock()
+
+	for id, wp := range peers {
+		wp.Mu.Lock()
+		for srcID, sub := range wp.Subs {
+			sub.Cancel()
+			delete(wp.Subs, srcID)
+		}
+		wp.Mu.Unlock()
+		if err := wp.PC.Close(); err != nil {
+			h.logger.Debug("close peer connection", zap.String("peer", id), zap.Error(err))
+		}
+	}
+}
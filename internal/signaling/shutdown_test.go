@@ -0,0 +1,96 @@
+package signaling
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coder/websocket"
+	"github.com/coder/websocket/wsjson"
+
+	"voxlink/internal/sfu"
+)
+
+func TestHandler_Shutdown(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// One client in a room, one still in the lobby.
+	alice := dialWS(t, ctx, srv, "")
+	if resp := roundTrip(t, ctx, alice, MsgCreateRoom, CreateRoomPayload{Name: "Alice"}); resp.Type != MsgRoomCreated {
+		t.Fatalf("create: got %q", resp.Type)
+	}
+	lobby := dialWS(t, ctx, srv, "")
+
+	shutdownErr := make(chan error, 1)
+	go func() {
+		shutdownErr <- h.Shutdown(ctx, ShutdownPayload{Reason: "maintenance", ReconnectURL: "https://other.example.com", RetryAfterMs: 2000})
+	}()
+
+	for name, conn := range map[string]*websocket.Conn{"alice": alice, "lobby": lobby} {
+		var env Envelope
+		if err := wsjson.Read(ctx, conn, &env); err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		var notice ShutdownPayload
+		json.Unmarshal(env.Payload, &notice)
+		if env.Type != MsgShutdown || notice.ReconnectURL != "https://other.example.com" || notice.RetryAfterMs != 2000 {
+			t.Fatalf("%s: got %s %+v", name, env.Type, notice)
+		}
+		err := wsjson.Read(ctx, conn, &env)
+		if websocket.CloseStatus(err) != websocket.StatusGoingAway {
+			t.Fatalf("%s: close: got %v, want going away", name, err)
+		}
+	}
+
+	if err := <-shutdownErr; err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	for _, room := range s.Rooms() {
+		if n := room.PeerCount(); n != 0 {
+			t.Fatalf("room %s still has %d peers", room.Code, n)
+		}
+	}
+
+	// New connections are refused while draining.
+	resp, err := http.Get(srv.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("status after shutdown: got %d, want 503", resp.StatusCode)
+	}
+}
+
+func TestHandler_ShutdownTimeout(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil)
+	srv := httptest.NewServer(h)
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	// A client that never reads cannot complete the close handshake.
+	conn := dialWS(t, ctx, srv, "")
+
+	drainCtx, drainCancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer drainCancel()
+	if err := h.Shutdown(drainCtx, ShutdownPayload{}); err != context.DeadlineExceeded {
+		t.Fatalf("Shutdown: got %v, want deadline exceeded", err)
+	}
+	conn.CloseNow()
+}
@@ -0,0 +1,31 @@
+package tlsutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRedirectHandler(t *testing.T) {
+	tests := []struct {
+		httpsAddr, host, want string
+	}{
+		{":443", "voxlink.lan", "https://voxlink.lan/room/ABCD?x=1"},
+		{":443", "voxlink.lan:80", "https://voxlink.lan/room/ABCD?x=1"},
+		{":8443", "192.168.1.20:8080", "https://192.168.1.20:8443/room/ABCD?x=1"},
+		{":8443", "[::1]:8080", "https://[::1]:8443/room/ABCD?x=1"},
+		{":443", "[::1]", "https://[::1]/room/ABCD?x=1"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/room/ABCD?x=1", nil)
+		req.Host = tt.host
+		w := httptest.NewRecorder()
+		RedirectHandler(tt.httpsAddr).ServeHTTP(w, req)
+		if w.Code != http.StatusPermanentRedirect {
+			t.Errorf("%s: status %d", tt.host, w.Code)
+		}
+		if got := w.Header().Get("Location"); got != tt.want {
+			t.Errorf("%s via %s: got %q, want %q", tt.host, tt.httpsAddr, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package tlsutil
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCertReloader_Watch(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile, err := EnsureSelfSigned(dir, []string{"localhost"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := NewCertReloader(certFile, keyFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first, _ := r.GetCertificate(nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	// A poll may see the new key before the new certificate is written; that
+	// reload fails and is retried, so errors are not fatal here.
+	go r.Watch(ctx, 10*time.Millisecond, nil)
+
+	// Issue a new certificate by requiring another host, then make sure the
+	// modification time differs even on coarse-grained filesystems.
+	if _, _, err := EnsureSelfSigned(dir, []string{"localhost", "other.lan"}); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	for _, f := range []string{certFile, keyFile} {
+		os.Chtimes(f, later, later)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if cur, _ := r.GetCertificate(nil); cur != first {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("certificate was not reloaded")
+}
+
+func TestNewCertReloader_MissingFiles(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := NewCertReloader(filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")); err == nil {
+		t.Fatal("expected error for missing files")
+	}
+}
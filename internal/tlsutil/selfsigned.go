@@ -0,0 +1,238 @@
+package tlsutil
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Files written by EnsureSelfSigned. CAFile is the certificate to install
+// as trusted on client devices.
+const (
+	CAFile    = "ca.pem"
+	CAKeyFile = "ca-key.pem"
+	CertFile  = "cert.pem"
+	KeyFile   = "key.pem"
+)
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour // below the 398-day limit browsers enforce
+	renewBefore  = 30 * 24 * time.Hour
+)
+
+// EnsureSelfSigned makes sure dir holds a local CA and a server certificate
+// signed by it that is valid for hosts (DNS names or IP addresses). The CA is
+// created once and reused, so clients only have to trust it once; the server
+// certificate is reissued when it is missing, close to expiry, or does not
+// cover all hosts. It returns the paths of the certificate and key files.
+func EnsureSelfSigned(dir string, hosts []string) (certFile, keyFile string, err error) {
+	if len(hosts) == 0 {
+		return "", "", errors.New("self-signed certificate: no hosts")
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", "", fmt.Errorf("create tls dir: %w", err)
+	}
+	ca, caKey, err := loadOrCreateCA(dir)
+	if err != nil {
+		return "", "", err
+	}
+	certFile = filepath.Join(dir, CertFile)
+	keyFile = filepath.Join(dir, KeyFile)
+	if certCovers(certFile, ca, hosts, time.Now()) {
+		return certFile, keyFile, nil
+	}
+	if err := issueCert(certFile, keyFile, ca, caKey, hosts); err != nil {
+		return "", "", err
+	}
+	return certFile, keyFile, nil
+}
+
+// DefaultHosts returns the names a LAN server is likely reached by: localhost,
+// the machine's hostname and the addresses of its network interfaces.
+func DefaultHosts() []string {
+	hosts := []string{"localhost", "127.0.0.1", "::1"}
+	if name, err := os.Hostname(); err == nil && name != "" {
+		hosts = append(hosts, name)
+	}
+	addrs, _ := net.InterfaceAddrs()
+	for _, a := range addrs {
+		if ipnet, ok := a.(*net.IPNet); ok && !ipnet.IP.IsLoopback() && !ipnet.IP.IsLinkLocalUnicast() {
+			hosts = append(hosts, ipnet.IP.String())
+		}
+	}
+	return hosts
+}
+
+func loadOrCreateCA(dir string) (*x509.Certificate, crypto.Signer, error) {
+	certPath := filepath.Join(dir, CAFile)
+	keyPath := filepath.Join(dir, CAKeyFile)
+	if _, err := os.Stat(certPath); err == nil {
+		pair, err := loadPair(certPath, keyPath)
+		if err != nil {
+			return nil, nil, fmt.Errorf("load local CA: %w", err)
+		}
+		return pair.cert, pair.key, nil
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          randomSerial(),
+		Subject:               pkix.Name{Organization: []string{"VoxLink"}, CommonName: "VoxLink Local CA"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("create local CA: %w", err)
+	}
+	if err := writePair(certPath, keyPath, der, key); err != nil {
+		return nil, nil, err
+	}
+	ca, err := x509.ParseCertificate(der)
+	return ca, key, err
+}
+
+func issueCert(certPath, keyPath string, ca *x509.Certificate, caKey crypto.Signer, hosts []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: randomSerial(),
+		Subject:      pkix.Name{Organization: []string{"VoxLink"}, CommonName: hosts[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(certValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return fmt.Errorf("issue certificate: %w", err)
+	}
+	return writePair(certPath, keyPath, der, key)
+}
+
+// certCovers reports whether the certificate at path was issued by ca, stays
+// valid for longer than renewBefore, and covers every host.
+func certCovers(path string, ca *x509.Certificate, hosts []string, now time.Time) bool {
+	cert, err := readCert(path)
+	if err != nil || cert.CheckSignatureFrom(ca) != nil {
+		return false
+	}
+	if now.Add(renewBefore).After(cert.NotAfter) {
+		return false
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			if !slices.ContainsFunc(cert.IPAddresses, ip.Equal) {
+				return false
+			}
+		} else if !slices.Contains(cert.DNSNames, h) {
+			return false
+		}
+	}
+	return true
+}
+
+type pair struct {
+	cert *x509.Certificate
+	key  crypto.Signer
+}
+
+func loadPair(certPath, keyPath string) (pair, error) {
+	cert, err := readCert(certPath)
+	if err != nil {
+		return pair{}, err
+	}
+	data, err := os.ReadFile(keyPath)
+	if err != nil {
+		return pair{}, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return pair{}, fmt.Errorf("%s: no PEM block", keyPath)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return pair{}, fmt.Errorf("%s: %w", keyPath, err)
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return pair{}, fmt.Errorf("%s: unsupported key type %T", keyPath, key)
+	}
+	return pair{cert: cert, key: signer}, nil
+}
+
+func readCert(path string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s: no certificate PEM block", path)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// writePair writes the key before the certificate, so a CertReloader watching
+// the files never pairs a new certificate with an old key for long.
+func writePair(certPath, keyPath string, der []byte, key crypto.Signer) error {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := writePEM(keyPath, "PRIVATE KEY", keyDER, 0o600); err != nil {
+		return err
+	}
+	return writePEM(certPath, "CERTIFICATE", der, 0o644)
+}
+
+func writePEM(path, blockType string, der []byte, perm os.FileMode) error {
+	tmp := path + ".tmp"
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return nil
+}
+
+func randomSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		panic(err) // crypto/rand does not fail on supported platforms
+	}
+	return serial
+}
@@ -0,0 +1,70 @@
+package tlsutil
+
+import (
+	"bytes"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEnsureSelfSigned(t *testing.T) {
+	dir := t.TempDir()
+
+	certFile, keyFile, err := EnsureSelfSigned(dir, []string{"localhost", "127.0.0.1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ca, err := readCert(filepath.Join(dir, CAFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ca.IsCA {
+		t.Fatal("CA certificate is not a CA")
+	}
+	cert, err := readCert(certFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca)
+	for _, host := range []string{"localhost", "127.0.0.1"} {
+		if _, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: roots}); err != nil {
+			t.Errorf("verify for %s: %v", host, err)
+		}
+	}
+	if fi, err := os.Stat(keyFile); err != nil || fi.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode: %v, %v", fi.Mode().Perm(), err)
+	}
+
+	// A second call with the same hosts keeps the certificate.
+	before, _ := os.ReadFile(certFile)
+	if _, _, err := EnsureSelfSigned(dir, []string{"localhost"}); err != nil {
+		t.Fatal(err)
+	}
+	after, _ := os.ReadFile(certFile)
+	if !bytes.Equal(before, after) {
+		t.Error("certificate reissued although it covers the hosts")
+	}
+}
+
+func TestEnsureSelfSigned_NewHostKeepsCA(t *testing.T) {
+	dir := t.TempDir()
+	if _, _, err := EnsureSelfSigned(dir, []string{"localhost"}); err != nil {
+		t.Fatal(err)
+	}
+	caBefore, _ := os.ReadFile(filepath.Join(dir, CAFile))
+
+	certFile, _, err := EnsureSelfSigned(dir, []string{"localhost", "voxlink.lan", "192.168.1.20"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	caAfter, _ := os.ReadFile(filepath.Join(dir, CAFile))
+	if !bytes.Equal(caBefore, caAfter) {
+		t.Fatal("local CA was replaced")
+	}
+	ca, _ := readCert(filepath.Join(dir, CAFile))
+	if !certCovers(certFile, ca, []string{"voxlink.lan", "192.168.1.20"}, ca.NotBefore) {
+		t.Fatal("reissued certificate does not cover the new hosts")
+	}
+}
@@ -0,0 +1,249 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sort"
+	"time"
+
+	"voxlink/internal/auth"
+	"voxlink/internal/sfu"
+)
+
+// RoomAdmin performs administrative actions that involve connected clients.
+// It is implemented by *signaling.Handler.
+type RoomAdmin interface {
+	CloseRoom(code, reason string) bool
+	KickPeer(code, peerID, reason string) bool
+	PeerConnectionState(peerID string) string
+	// Ban returns the zero time if bans are not enabled.
+	Ban(ip string, d time.Duration) time.Time
+	Unban(ip string) bool
+	Bans() map[string]time.Time
+}
+
+// WithAdmin enables the /api/admin/* endpoints, which require the rooms:admin
+// scope. They stay disabled unless an authenticator is also configured.
+func WithAdmin(admin RoomAdmin) Option {
+	return func(o *options) {
+		o.admin = admin
+	}
+}
+
+// AdminPeer describes a peer in admin API responses.
+type AdminPeer struct {
+	ID              string `json:"id"`
+	Name            string `json:"name"`
+	Muted           bool   `json:"muted"`
+	Role            string `json:"role"`
+	UserID          string `json:"userId,omitempty"`
+	ConnectionState string `json:"connectionState,omitempty"`
+}
+
+// AdminRoom describes a room in admin API responses.
+type AdminRoom struct {
+	Code       string    `json:"code"`
+	CreatedAt  time.Time `json:"createdAt"`
+	Persistent bool      `json:"persistent,omitempty"`
+	sfu.RoomMeta
+	Options sfu.RoomOptions `json:"options"`
+	Peers   []AdminPeer     `json:"peers"`
+}
+
+// AdminBan describes a banned client IP in admin API responses.
+type AdminBan struct {
+	IP    string    `json:"ip"`
+	Until time.Time `json:"until"`
+}
+
+// createRoomRequest is the body of POST /api/admin/rooms. The metadata
+// applies to persistent rooms only.
+type createRoomRequest struct {
+	sfu.RoomOptions
+	sfu.RoomMeta
+	Persistent bool   `json:"persistent"`
+	Code       string `json:"code"`
+}
+
+func registerAdmin(mux *http.ServeMux, s *sfu.SFU, admin RoomAdmin, a auth.Authenticator) {
+	describe := func(room *sfu.Room) AdminRoom {
+		peers := room.PeerList()
+		sort.Slice(peers, func(i, j int) bool { return peers[i].Name < peers[j].Name })
+		out := AdminRoom{
+			Code:       room.Code,
+			CreatedAt:  room.CreatedAt(),
+			Persistent: room.Persistent,
+			RoomMeta:   room.Meta,
+			Options:    room.Options,
+			Peers:      make([]AdminPeer, 0, len(peers)),
+		}
+		for _, p := range peers {
+			out.Peers = append(out.Peers, AdminPeer{
+				ID:              p.ID,
+				Name:            p.Name,
+				Muted:           p.Muted,
+				Role:            p.Role,
+				UserID:          p.UserID,
+				ConnectionState: admin.PeerConnectionState(p.ID),
+			})
+		}
+		return out
+	}
+
+	handle := func(pattern string, fn http.HandlerFunc) {
+		mux.Handle(pattern, auth.Require(a, auth.ScopeRoomsAdmin, fn))
+	}
+
+	// GET /api/admin/rooms
+	handle("GET /api/admin/rooms", func(w http.ResponseWriter, r *http.Request) {
+		rooms := s.Rooms()
+		sort.Slice(rooms, func(i, j int) bool { return rooms[i].CreatedAt().Before(rooms[j].CreatedAt()) })
+		list := make([]AdminRoom, 0, len(rooms))
+		for _, room := range rooms {
+			list = append(list, describe(room))
+		}
+		writeJSON(w, http.StatusOK, map[string]any{"rooms": list})
+	})
+
+	// POST /api/admin/rooms
+	handle("POST /api/admin/rooms", func(w http.ResponseWriter, r *http.Request) {
+		var req createRoomRequest
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid room options", http.StatusBadRequest)
+				return
+			}
+		}
+		if req.MaxPeers < 0 {
+			http.Error(w, "maxPeers must not be negative", http.StatusBadRequest)
+			return
+		}
+		if req.ChatHistory < 0 {
+			http.Error(w, "chatHistory must not be negative", http.StatusBadRequest)
+			return
+		}
+		if !req.Persistent {
+			if req.RoomMeta != (sfu.RoomMeta{}) {
+				http.Error(w, "title, description and owner require a persistent room", http.StatusBadRequest)
+				return
+			}
+			code, err := s.CreateRoomWithCode(r.Context(), req.Code, req.RoomOptions)
+			if err != nil {
+				createRoomError(w, err)
+				return
+			}
+			room, _ := s.GetRoom(code)
+			writeJSON(w, http.StatusCreated, describe(room))
+			return
+		}
+
+		if req.Owner == "" {
+			if p, ok := auth.FromContext(r.Context()); ok {
+				req.Owner = p.Subject
+			}
+		}
+		room, err := s.CreatePersistentRoom(r.Context(), sfu.RoomRecord{
+			Code:     req.Code,
+			RoomMeta: req.RoomMeta,
+			Options:  req.RoomOptions,
+		})
+		if err != nil {
+			createRoomError(w, err)
+			return
+		}
+		writeJSON(w, http.StatusCreated, describe(room))
+	})
+
+	// GET /api/admin/rooms/:code
+	handle("GET /api/admin/rooms/{code}", func(w http.ResponseWriter, r *http.Request) {
+		room, ok := s.GetRoom(r.PathValue("code"))
+		if !ok {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, describe(room))
+	})
+
+	// DELETE /api/admin/rooms/:code?reason=...
+	handle("DELETE /api/admin/rooms/{code}", func(w http.ResponseWriter, r *http.Request) {
+		if !admin.CloseRoom(r.PathValue("code"), r.URL.Query().Get("reason")) {
+			http.Error(w, "room not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// DELETE /api/admin/rooms/:code/peers/:id?reason=...
+	handle("DELETE /api/admin/rooms/{code}/peers/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if !admin.KickPeer(r.PathValue("code"), r.PathValue("id"), r.URL.Query().Get("reason")) {
+			http.Error(w, "peer not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// GET /api/admin/bans
+	handle("GET /api/admin/bans", func(w http.ResponseWriter, r *http.Request) {
+		bans := admin.Bans()
+		list := make([]AdminBan, 0, len(bans))
+		for ip, until := range bans {
+			list = append(list, AdminBan{IP: ip, Until: until})
+		}
+		sort.Slice(list, func(i, j int) bool { return list[i].IP < list[j].IP })
+		writeJSON(w, http.StatusOK, map[string]any{"bans": list})
+	})
+
+	// PUT /api/admin/bans/:ip?duration=1h (default: the configured ban duration)
+	handle("PUT /api/admin/bans/{ip}", func(w http.ResponseWriter, r *http.Request) {
+		ip := r.PathValue("ip")
+		if net.ParseIP(ip) == nil {
+			http.Error(w, "invalid IP address", http.StatusBadRequest)
+			return
+		}
+		var d time.Duration
+		if v := r.URL.Query().Get("duration"); v != "" {
+			var err error
+			if d, err = time.ParseDuration(v); err != nil || d <= 0 {
+				http.Error(w, "duration must be a positive Go duration, e.g. 1h", http.StatusBadRequest)
+				return
+			}
+		}
+		until := admin.Ban(ip, d)
+		if until.IsZero() {
+			http.Error(w, "rate limits are not enabled", http.StatusNotImplemented)
+			return
+		}
+		writeJSON(w, http.StatusOK, AdminBan{IP: ip, Until: until})
+	})
+
+	// DELETE /api/admin/bans/:ip
+	handle("DELETE /api/admin/bans/{ip}", func(w http.ResponseWriter, r *http.Request) {
+		if !admin.Unban(r.PathValue("ip")) {
+			http.Error(w, "ban not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
+
+// createRoomError reports a failed room creation.
+func createRoomError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, sfu.ErrInvalidCode):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, sfu.ErrCodeTaken):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, sfu.ErrNoStore):
+		http.Error(w, "persistent rooms are not enabled", http.StatusNotImplemented)
+	default:
+		http.Error(w, "failed to create room", http.StatusServiceUnavailable)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v) //nolint:errcheck
+}
@@ -0,0 +1,258 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"voxlink/internal/auth"
+	"voxlink/internal/roomstore"
+	"voxlink/internal/sfu"
+)
+
+type fakeAdmin struct {
+	s      *sfu.SFU
+	closed []string
+	kicked []string
+	bans   map[string]time.Time
+}
+
+func (f *fakeAdmin) CloseRoom(code, reason string) bool {
+	f.closed = append(f.closed, code+":"+reason)
+	return f.s.CloseRoom(code)
+}
+
+func (f *fakeAdmin) KickPeer(code, peerID, reason string) bool {
+	room, ok := f.s.GetRoom(code)
+	if !ok {
+		return false
+	}
+	if _, ok := room.GetPeer(peerID); !ok {
+		return false
+	}
+	room.RemovePeer(peerID)
+	f.kicked = append(f.kicked, peerID+":"+reason)
+	return true
+}
+
+func (f *fakeAdmin) PeerConnectionState(string) string { return "connected" }
+
+func (f *fakeAdmin) Ban(ip string, d time.Duration) time.Time {
+	if f.bans == nil {
+		f.bans = make(map[string]time.Time)
+	}
+	if d == 0 {
+		d = 10 * time.Minute
+	}
+	f.bans[ip] = time.Now().Add(d)
+	return f.bans[ip]
+}
+
+func (f *fakeAdmin) Unban(ip string) bool {
+	_, ok := f.bans[ip]
+	delete(f.bans, ip)
+	return ok
+}
+
+func (f *fakeAdmin) Bans() map[string]time.Time { return f.bans }
+
+func testAuthenticator(t *testing.T) auth.Authenticator {
+	t.Helper()
+	keys, err := auth.NewStaticKeys([]auth.APIKey{
+		{Name: "admin", Key: "secret", Scopes: auth.AllScopes},
+		{Name: "reader", Key: "reader", Scopes: []string{auth.ScopeRoomsRead}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys
+}
+
+func adminRequest(h http.Handler, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer secret")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w
+}
+
+func TestAdmin_Unauthorized(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil, WithAdmin(&fakeAdmin{s: s}), WithAuthenticator(testAuthenticator(t)))
+
+	req := httptest.NewRequest("GET", "/api/admin/rooms", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusUnauthorized {
+		t.Fatalf("status: got %d, want %d", w.Code, http.StatusUnauthorized)
+	}
+
+	req.Header.Set("Authorization", "Bearer reader")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusForbidden {
+		t.Fatalf("status without rooms:admin: got %d, want %d", w.Code, http.StatusForbidden)
+	}
+}
+
+func TestAdmin_DisabledWithoutAuthenticator(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil, WithAdmin(&fakeAdmin{s: s}))
+	w := adminRequest(h, "GET", "/api/admin/rooms", "")
+	if w.Code == http.StatusOK {
+		t.Fatal("admin API should be disabled without a token")
+	}
+}
+
+func TestAdmin_RoomLifecycle(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	admin := &fakeAdmin{s: s}
+	h := NewHandler(s, nil, WithAdmin(admin), WithAuthenticator(testAuthenticator(t)))
+
+	// Create.
+	w := adminRequest(h, "POST", "/api/admin/rooms", `{"maxPeers":3}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status: got %d, want %d", w.Code, http.StatusCreated)
+	}
+	var created AdminRoom
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Code == "" || created.Options.MaxPeers != 3 {
+		t.Fatalf("created room: %+v", created)
+	}
+
+	room, _ := s.GetRoom(created.Code)
+	alice := room.AddPeerWithRole("Alice", sfu.RoleHost)
+
+	// List.
+	w = adminRequest(h, "GET", "/api/admin/rooms", "")
+	var list struct {
+		Rooms []AdminRoom `json:"rooms"`
+	}
+	json.NewDecoder(w.Body).Decode(&list)
+	if len(list.Rooms) != 1 || len(list.Rooms[0].Peers) != 1 {
+		t.Fatalf("list: %+v", list)
+	}
+
+	// Inspect.
+	w = adminRequest(h, "GET", "/api/admin/rooms/"+created.Code, "")
+	var info AdminRoom
+	json.NewDecoder(w.Body).Decode(&info)
+	p := info.Peers[0]
+	if p.Name != "Alice" || p.Role != sfu.RoleHost || p.ConnectionState != "connected" {
+		t.Fatalf("peer: %+v", p)
+	}
+
+	// Kick.
+	w = adminRequest(h, "DELETE", "/api/admin/rooms/"+created.Code+"/peers/"+alice.ID+"?reason=bye", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("kick status: got %d, want %d", w.Code, http.StatusNoContent)
+	}
+	if len(admin.kicked) != 1 || admin.kicked[0] != alice.ID+":bye" {
+		t.Fatalf("kicked: %v", admin.kicked)
+	}
+
+	// Close.
+	w = adminRequest(h, "DELETE", "/api/admin/rooms/"+created.Code+"?reason=done", "")
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("close status: got %d, want %d", w.Code, http.StatusNoContent)
+	}
+	w = adminRequest(h, "GET", "/api/admin/rooms/"+created.Code, "")
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("inspect closed room: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestAdmin_PersistentRoom(t *testing.T) {
+	store := roomstore.NewJSONFile(filepath.Join(t.TempDir(), "rooms.json"))
+	s := sfu.NewWithConfig(sfu.Config{GracePeriod: time.Minute, GCInterval: time.Minute, Store: store})
+	defer s.Close()
+	h := NewHandler(s, nil, WithAdmin(&fakeAdmin{s: s}), WithAuthenticator(testAuthenticator(t)))
+
+	w := adminRequest(h, "POST", "/api/admin/rooms", `{"persistent":true,"code":"standup","title":"Daily standup","maxPeers":10}`)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create status: got %d: %s", w.Code, w.Body)
+	}
+	var created AdminRoom
+	json.NewDecoder(w.Body).Decode(&created)
+	if created.Code != "STANDUP" || !created.Persistent || created.Title != "Daily standup" ||
+		created.Owner != "admin" || created.Options.MaxPeers != 10 {
+		t.Fatalf("created room: %+v", created)
+	}
+	if records, _ := store.Load(context.Background()); len(records) != 1 || records[0].Owner != "admin" {
+		t.Fatalf("stored: %+v", records)
+	}
+
+	for body, want := range map[string]int{
+		`{"persistent":true,"code":"STANDUP"}`: http.StatusConflict,
+		`{"persistent":true,"code":"no"}`:      http.StatusBadRequest,
+		`{"code":"st@ndup"}`:                   http.StatusBadRequest,
+		`{"code":"stand-up-2"}`:                http.StatusCreated,
+		`{"code":"STANDUP"}`:                   http.StatusConflict,
+		`{"title":"Not persistent"}`:           http.StatusBadRequest,
+	} {
+		if w := adminRequest(h, "POST", "/api/admin/rooms", body); w.Code != want {
+			t.Errorf("%s: got %d, want %d", body, w.Code, want)
+		}
+	}
+
+	if w := adminRequest(h, "DELETE", "/api/admin/rooms/STANDUP", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("delete status: got %d", w.Code)
+	}
+	if records, _ := store.Load(context.Background()); len(records) != 0 {
+		t.Fatalf("closed room still stored: %+v", records)
+	}
+}
+
+func TestAdmin_PersistentRoomWithoutStore(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	h := NewHandler(s, nil, WithAdmin(&fakeAdmin{s: s}), WithAuthenticator(testAuthenticator(t)))
+	if w := adminRequest(h, "POST", "/api/admin/rooms", `{"persistent":true}`); w.Code != http.StatusNotImplemented {
+		t.Fatalf("status: got %d, want %d", w.Code, http.StatusNotImplemented)
+	}
+}
+
+func TestAdmin_Bans(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+	admin := &fakeAdmin{s: s}
+	h := NewHandler(s, nil, WithAdmin(admin), WithAuthenticator(testAuthenticator(t)))
+
+	if w := adminRequest(h, "PUT", "/api/admin/bans/not-an-ip", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("ban invalid IP: got %d", w.Code)
+	}
+	if w := adminRequest(h, "PUT", "/api/admin/bans/203.0.113.7?duration=soon", ""); w.Code != http.StatusBadRequest {
+		t.Fatalf("ban with invalid duration: got %d", w.Code)
+	}
+
+	w := adminRequest(h, "PUT", "/api/admin/bans/203.0.113.7?duration=1h", "")
+	var ban AdminBan
+	if w.Code != http.StatusOK || json.Unmarshal(w.Body.Bytes(), &ban) != nil || time.Until(ban.Until) < 59*time.Minute {
+		t.Fatalf("ban: got %d %s", w.Code, w.Body)
+	}
+
+	w = adminRequest(h, "GET", "/api/admin/bans", "")
+	var list struct{ Bans []AdminBan }
+	if err := json.Unmarshal(w.Body.Bytes(), &list); err != nil || len(list.Bans) != 1 || list.Bans[0].IP != "203.0.113.7" {
+		t.Fatalf("list: got %d %s", w.Code, w.Body)
+	}
+
+	if w := adminRequest(h, "DELETE", "/api/admin/bans/203.0.113.7", ""); w.Code != http.StatusNoContent {
+		t.Fatalf("unban: got %d", w.Code)
+	}
+	if w := adminRequest(h, "DELETE", "/api/admin/bans/203.0.113.7", ""); w.Code != http.StatusNotFound {
+		t.Fatalf("unban twice: got %d", w.Code)
+	}
+}
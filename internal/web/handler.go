@@ -7,6 +7,9 @@ import (
 	"net/http"
 	"strings"
 
+	"voxlink/internal/audio/modes"
+	"voxlink/internal/auth"
+	"voxlink/internal/origin"
 	"voxlink/internal/sfu"
 )
 
@@ -25,15 +28,77 @@ type AudioController interface {
 	SetMute(muted bool) error
 	SetVolume(peerID string, volume float64) error
 	SetDenoise(enabled bool) error
+	SetAGC(enabled bool) error
+	SetAGCLevel(targetDBFS, maxGainDB float64) error
+	SetTransmitMode(mode string) error // one of modes.Transmit
+	SetPushToTalk(pressed bool) error
+	SetSpatial(mode string) error // one of modes.Spatial
+	SetPosition(peerID string, azimuth, distance float64) error
+	SetSidetone(enabled bool) error
+	SetSidetoneLevel(db float64) error // relative to the capture
 	ListDevices() (inputs, outputs []AudioDevice, err error)
 	SelectDevice(inputID, outputID string) error
 }
 
+// StatsProvider reports connection statistics for peers. It is implemented by
+// *signaling.Handler.
+type StatsProvider interface {
+	PeerStats(roomCode, peerID string) (sfu.PeerStats, bool)
+}
+
+// Option configures optional handler dependencies.
+type Option func(*options)
+
+type options struct {
+	stats   StatsProvider
+	admin   RoomAdmin
+	auth    auth.Authenticator
+	origins *origin.Policy
+}
+
+// WithAuthenticator protects the /api/* endpoints (except /api/health) with
+// the given Authenticator, requiring the rooms:read scope for room endpoints
+// and audio:control for audio endpoints. Without an authenticator the API is
+// open and the admin endpoints are disabled.
+func WithAuthenticator(a auth.Authenticator) Option {
+	return func(o *options) {
+		o.auth = a
+	}
+}
+
+// WithOriginPolicy applies p as the CORS policy of the /api/* endpoints.
+// Without it no CORS headers are sent, so only same-origin pages can read
+// API responses.
+func WithOriginPolicy(p *origin.Policy) Option {
+	return func(o *options) {
+		o.origins = p
+	}
+}
+
+// WithStatsProvider enables GET /api/room/{code}/peers/{id}/stats.
+func WithStatsProvider(p StatsProvider) Option {
+	return func(o *options) {
+		o.stats = p
+	}
+}
+
 // NewHandler creates the HTTP handler that serves the web UI and API endpoints.
 // audioCtrl may be nil; all audio endpoints become graceful no-ops in that case.
-func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
+func NewHandler(s *sfu.SFU, audioCtrl AudioController, opts ...Option) http.Handler {
+	var o options
+	for _, opt := range opts {
+		opt(&o)
+	}
+
 	mux := http.NewServeMux()
 
+	protect := func(scope string, h http.HandlerFunc) http.Handler {
+		if o.auth == nil {
+			return h
+		}
+		return auth.Require(o.auth, scope, h)
+	}
+
 	staticFS, _ := fs.Sub(staticFiles, "static")
 	fileServer := http.FileServer(http.FS(staticFS))
 
@@ -60,7 +125,7 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 	})
 
 	// GET /api/room/:code
-	mux.HandleFunc("/api/room/", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/room/", protect(auth.ScopeRoomsRead, func(w http.ResponseWriter, r *http.Request) {
 		code := strings.TrimPrefix(r.URL.Path, "/api/room/")
 		w.Header().Set("Content-Type", "application/json")
 		room, ok := s.GetRoom(code)
@@ -69,10 +134,29 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 			return
 		}
 		json.NewEncoder(w).Encode(map[string]any{"exists": true, "peers": room.PeerCount()}) //nolint:errcheck
-	})
+	}))
+
+	// GET /api/room/:code/peers/:id/stats
+	mux.Handle("GET /api/room/{code}/peers/{id}/stats", protect(auth.ScopeRoomsRead, func(w http.ResponseWriter, r *http.Request) {
+		if o.stats == nil {
+			http.Error(w, "stats unavailable", http.StatusNotFound)
+			return
+		}
+		stats, ok := o.stats.PeerStats(r.PathValue("code"), r.PathValue("id"))
+		if !ok {
+			http.Error(w, "peer not found", http.StatusNotFound)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats) //nolint:errcheck
+	}))
+
+	if o.admin != nil && o.auth != nil {
+		registerAdmin(mux, s, o.admin, o.auth)
+	}
 
 	// POST /api/audio/mute
-	mux.HandleFunc("/api/audio/mute", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/audio/mute", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -86,10 +170,10 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
-	})
+	}))
 
 	// POST /api/audio/volume
-	mux.HandleFunc("/api/audio/volume", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/audio/volume", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -104,10 +188,10 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
-	})
+	}))
 
 	// POST /api/audio/denoise
-	mux.HandleFunc("/api/audio/denoise", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/audio/denoise", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -121,10 +205,160 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
-	})
+	}))
+
+	// POST /api/audio/agc
+	mux.Handle("/api/audio/agc", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Enabled     *bool    `json:"enabled"`
+			TargetLevel *float64 `json:"targetLevel"` // dBFS
+			MaxGain     *float64 `json:"maxGain"`     // dB
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if (req.TargetLevel == nil) != (req.MaxGain == nil) {
+			http.Error(w, "targetLevel and maxGain must be set together", http.StatusBadRequest)
+			return
+		}
+		if req.TargetLevel != nil && (*req.TargetLevel < -60 || *req.TargetLevel >= 0 || *req.MaxGain < 0 || *req.MaxGain > 60) {
+			http.Error(w, "targetLevel must be in [-60, 0) dBFS and maxGain in [0, 60] dB", http.StatusBadRequest)
+			return
+		}
+		if audioCtrl != nil {
+			if req.TargetLevel != nil {
+				audioCtrl.SetAGCLevel(*req.TargetLevel, *req.MaxGain) //nolint:errcheck
+			}
+			if req.Enabled != nil {
+				audioCtrl.SetAGC(*req.Enabled) //nolint:errcheck
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	}))
+
+	// POST /api/audio/transmit
+	mux.Handle("/api/audio/transmit", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Mode string `json:"mode"`
+		}
+		json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+		if !modes.IsTransmit(req.Mode) {
+			http.Error(w, "mode must be one of "+strings.Join(modes.Transmit(), ", "), http.StatusBadRequest)
+			return
+		}
+		if audioCtrl != nil {
+			audioCtrl.SetTransmitMode(req.Mode) //nolint:errcheck
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	}))
+
+	// POST /api/audio/ptt
+	mux.Handle("/api/audio/ptt", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		if audioCtrl != nil {
+			var req struct {
+				Pressed bool `json:"pressed"`
+			}
+			json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+			audioCtrl.SetPushToTalk(req.Pressed) //nolint:errcheck
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	}))
+
+	// POST /api/audio/spatial
+	mux.Handle("/api/audio/spatial", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Mode string `json:"mode"`
+		}
+		json.NewDecoder(r.Body).Decode(&req) //nolint:errcheck
+		if !modes.IsSpatial(req.Mode) {
+			http.Error(w, "mode must be one of "+strings.Join(modes.Spatial(), ", "), http.StatusBadRequest)
+			return
+		}
+		if audioCtrl != nil {
+			audioCtrl.SetSpatial(req.Mode) //nolint:errcheck
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	}))
+
+	// POST /api/audio/position
+	mux.Handle("/api/audio/position", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			PeerID   string  `json:"peerId"`
+			Azimuth  float64 `json:"azimuth"`  // degrees clockwise from straight ahead
+			Distance float64 `json:"distance"` // 1 is a seat at the virtual table
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PeerID == "" {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Azimuth < -180 || req.Azimuth > 180 || req.Distance <= 0 || req.Distance > 100 {
+			http.Error(w, "azimuth must be in [-180, 180] degrees and distance in (0, 100]", http.StatusBadRequest)
+			return
+		}
+		if audioCtrl != nil {
+			audioCtrl.SetPosition(req.PeerID, req.Azimuth, req.Distance) //nolint:errcheck
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	}))
+
+	// POST /api/audio/sidetone
+	mux.Handle("/api/audio/sidetone", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		var req struct {
+			Enabled *bool    `json:"enabled"`
+			Level   *float64 `json:"level"` // dB relative to the capture
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+		if req.Level != nil && (*req.Level < -60 || *req.Level > 0) {
+			http.Error(w, "level must be in [-60, 0] dB", http.StatusBadRequest)
+			return
+		}
+		if audioCtrl != nil {
+			if req.Level != nil {
+				audioCtrl.SetSidetoneLevel(*req.Level) //nolint:errcheck
+			}
+			if req.Enabled != nil {
+				audioCtrl.SetSidetone(*req.Enabled) //nolint:errcheck
+			}
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
+	}))
 
 	// GET /api/audio/devices
-	mux.HandleFunc("/api/audio/devices", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/audio/devices", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
 		if audioCtrl == nil {
 			json.NewEncoder(w).Encode(map[string]any{ //nolint:errcheck
@@ -141,10 +375,10 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 			outputs = []AudioDevice{}
 		}
 		json.NewEncoder(w).Encode(map[string]any{"inputs": inputs, "outputs": outputs}) //nolint:errcheck
-	})
+	}))
 
 	// POST /api/audio/device
-	mux.HandleFunc("/api/audio/device", func(w http.ResponseWriter, r *http.Request) {
+	mux.Handle("/api/audio/device", protect(auth.ScopeAudioControl, func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 			return
@@ -159,7 +393,14 @@ func NewHandler(s *sfu.SFU, audioCtrl AudioController) http.Handler {
 		}
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]any{"ok": true}) //nolint:errcheck
-	})
+	}))
 
-	return mux
+	api := o.origins.CORS(mux)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			api.ServeHTTP(w, r)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
 }
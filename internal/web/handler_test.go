@@ -4,8 +4,10 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
+	"voxlink/internal/origin"
 	"voxlink/internal/sfu"
 )
 
@@ -94,3 +96,249 @@ func TestHandler_StaticFiles(t *testing.T) {
 		t.Fatalf("status: got %d, want %d", w.Code, http.StatusOK)
 	}
 }
+
+type fakeStats map[string]sfu.PeerStats
+
+func (f fakeStats) PeerStats(roomCode, peerID string) (sfu.PeerStats, bool) {
+	s, ok := f[roomCode+"/"+peerID]
+	return s, ok
+}
+
+func TestHandler_PeerStats(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	provider := fakeStats{
+		"ABCD-EFGH/peer-1": {PeerID: "peer-1", ConnectionState: "connected", RTT: 0.025},
+	}
+	h := NewHandler(s, nil, WithStatsProvider(provider))
+
+	req := httptest.NewRequest("GET", "/api/room/ABCD-EFGH/peers/peer-1/stats", nil)
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("status: got %d, want %d", w.Code, http.StatusOK)
+	}
+	var resp sfu.PeerStats
+	json.NewDecoder(w.Body).Decode(&resp)
+	if resp.PeerID != "peer-1" || resp.ConnectionState != "connected" || resp.RTT != 0.025 {
+		t.Fatalf("stats: got %+v", resp)
+	}
+
+	req = httptest.NewRequest("GET", "/api/room/ABCD-EFGH/peers/nobody/stats", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status: got %d, want %d", w.Code, http.StatusNotFound)
+	}
+}
+
+func TestHandler_AuthScopes(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	h := NewHandler(s, nil, WithAuthenticator(testAuthenticator(t)))
+
+	tests := []struct {
+		method, path, key string
+		want              int
+	}{
+		{"GET", "/api/health", "", http.StatusOK},
+		{"GET", "/", "", http.StatusOK},
+		{"GET", "/api/room/NOPE-NOPE", "", http.StatusUnauthorized},
+		{"GET", "/api/room/NOPE-NOPE", "reader", http.StatusOK},
+		{"POST", "/api/audio/mute", "reader", http.StatusForbidden},
+		{"POST", "/api/audio/mute", "secret", http.StatusOK},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.path, nil)
+		if tt.key != "" {
+			req.Header.Set("Authorization", "Bearer "+tt.key)
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s %s (key %q): got %d, want %d", tt.method, tt.path, tt.key, w.Code, tt.want)
+		}
+	}
+}
+
+func TestHandler_CORS(t *testing.T) {
+	s := sfu.New()
+	defer s.Close()
+
+	p, _ := origin.NewPolicy([]string{"app.example.com"})
+	h := NewHandler(s, nil, WithOriginPolicy(p), WithAuthenticator(testAuthenticator(t)))
+
+	// Preflights are answered before authentication.
+	req := httptest.NewRequest(http.MethodOptions, "/api/room/ABCD-EFGH", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Access-Control-Request-Method", "GET")
+	req.Header.Set("Access-Control-Request-Headers", "authorization")
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusNoContent {
+		t.Fatalf("preflight status: got %d, want %d", w.Code, http.StatusNoContent)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/room/ABCD-EFGH", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	req.Header.Set("Authorization", "Bearer reader")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" {
+		t.Fatalf("got %d, allow-origin %q", w.Code, w.Header().Get("Access-Control-Allow-Origin"))
+	}
+
+	// Static assets carry no CORS headers.
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://app.example.com")
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	if got := w.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("static allow-origin: got %q, want none", got)
+	}
+}
+
+// fakeAudio records the settings made through the audio endpoints.
+type fakeAudio struct {
+	agc                bool
+	agcTarget, agcGain float64
+	mode               string
+	ptt                bool
+	spatial            string
+	position           [2]float64 // azimuth, distance
+	positioned         string     // peer ID
+	sidetone           bool
+	sidetoneLevel      float64
+}
+
+func (f *fakeAudio) SetMute(bool) error                         { return nil }
+func (f *fakeAudio) SetVolume(string, float64) error            { return nil }
+func (f *fakeAudio) SetDenoise(bool) error                      { return nil }
+func (f *fakeAudio) ListDevices() (_, _ []AudioDevice, _ error) { return nil, nil, nil }
+func (f *fakeAudio) SelectDevice(string, string) error          { return nil }
+func (f *fakeAudio) SetAGC(enabled bool) error                  { f.agc = enabled; return nil }
+func (f *fakeAudio) SetTransmitMode(mode string) error          { f.mode = mode; return nil }
+func (f *fakeAudio) SetPushToTalk(pressed bool) error           { f.ptt = pressed; return nil }
+func (f *fakeAudio) SetSpatial(mode string) error               { f.spatial = mode; return nil }
+func (f *fakeAudio) SetSidetone(enabled bool) error             { f.sidetone = enabled; return nil }
+func (f *fakeAudio) SetSidetoneLevel(db float64) error          { f.sidetoneLevel = db; return nil }
+
+func (f *fakeAudio) SetPosition(peerID string, azimuth, distance float64) error {
+	f.positioned, f.position = peerID, [2]float64{azimuth, distance}
+	return nil
+}
+
+func (f *fakeAudio) SetAGCLevel(target, maxGain float64) error {
+	f.agcTarget, f.agcGain = target, maxGain
+	return nil
+}
+
+// newAudioHandler returns a handler controlling a fakeAudio.
+func newAudioHandler(t *testing.T) (http.Handler, *fakeAudio) {
+	t.Helper()
+	s := sfu.New()
+	t.Cleanup(s.Close)
+	audio := &fakeAudio{}
+	return NewHandler(s, audio), audio
+}
+
+// postJSON posts body to path and returns the response status.
+func postJSON(t *testing.T, h http.Handler, path, body string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	return w.Code
+}
+
+func TestHandler_AGC(t *testing.T) {
+	h, audio := newAudioHandler(t)
+
+	if code := postJSON(t, h, "/api/audio/agc", `{"enabled":true,"targetLevel":-20,"maxGain":18}`); code != http.StatusOK {
+		t.Fatalf("status: got %d", code)
+	}
+	if !audio.agc || audio.agcTarget != -20 || audio.agcGain != 18 {
+		t.Fatalf("settings: got %+v", audio)
+	}
+
+	// Toggling alone leaves the levels alone.
+	if code := postJSON(t, h, "/api/audio/agc", `{"enabled":false}`); code != http.StatusOK || audio.agc || audio.agcTarget != -20 {
+		t.Fatalf("toggle: status %d, settings %+v", code, audio)
+	}
+
+	for _, body := range []string{
+		`{"targetLevel":-20}`,
+		`{"targetLevel":3,"maxGain":10}`,
+		`{"targetLevel":-20,"maxGain":-1}`,
+		`not json`,
+	} {
+		if code := postJSON(t, h, "/api/audio/agc", body); code != http.StatusBadRequest {
+			t.Errorf("%s: got %d, want 400", body, code)
+		}
+	}
+}
+
+func TestHandler_Transmit(t *testing.T) {
+	h, audio := newAudioHandler(t)
+
+	if code := postJSON(t, h, "/api/audio/transmit", `{"mode":"vad"}`); code != http.StatusOK || audio.mode != "vad" {
+		t.Fatalf("transmit: status %d, mode %q", code, audio.mode)
+	}
+	if code := postJSON(t, h, "/api/audio/transmit", `{"mode":"always"}`); code != http.StatusBadRequest || audio.mode != "vad" {
+		t.Fatalf("unknown mode: status %d, mode %q", code, audio.mode)
+	}
+	if code := postJSON(t, h, "/api/audio/ptt", `{"pressed":true}`); code != http.StatusOK || !audio.ptt {
+		t.Fatalf("ptt: status %d, pressed %v", code, audio.ptt)
+	}
+}
+
+func TestHandler_Spatial(t *testing.T) {
+	h, audio := newAudioHandler(t)
+
+	if code := postJSON(t, h, "/api/audio/spatial", `{"mode":"binaural"}`); code != http.StatusOK || audio.spatial != "binaural" {
+		t.Fatalf("spatial: status %d, mode %q", code, audio.spatial)
+	}
+	if code := postJSON(t, h, "/api/audio/spatial", `{"mode":"surround"}`); code != http.StatusBadRequest || audio.spatial != "binaural" {
+		t.Fatalf("unknown mode: status %d, mode %q", code, audio.spatial)
+	}
+
+	if code := postJSON(t, h, "/api/audio/position", `{"peerId":"p1","azimuth":-60,"distance":1.5}`); code != http.StatusOK {
+		t.Fatalf("position: status %d", code)
+	}
+	if audio.positioned != "p1" || audio.position != [2]float64{-60, 1.5} {
+		t.Fatalf("position: got %q at %v", audio.positioned, audio.position)
+	}
+	for _, body := range []string{
+		`{"azimuth":0,"distance":1}`,
+		`{"peerId":"p1","azimuth":270,"distance":1}`,
+		`{"peerId":"p1","azimuth":0,"distance":0}`,
+		`not json`,
+	} {
+		if code := postJSON(t, h, "/api/audio/position", body); code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", body, code)
+		}
+	}
+}
+
+func TestHandler_Sidetone(t *testing.T) {
+	h, audio := newAudioHandler(t)
+
+	if code := postJSON(t, h, "/api/audio/sidetone", `{"enabled":true,"level":-25}`); code != http.StatusOK || !audio.sidetone || audio.sidetoneLevel != -25 {
+		t.Fatalf("status %d, settings %+v", code, audio)
+	}
+	// Toggling alone leaves the level alone.
+	if code := postJSON(t, h, "/api/audio/sidetone", `{"enabled":false}`); code != http.StatusOK || audio.sidetone || audio.sidetoneLevel != -25 {
+		t.Fatalf("toggle: status %d, settings %+v", code, audio)
+	}
+	for _, body := range []string{`{"level":6}`, `{"level":-80}`, `not json`} {
+		if code := postJSON(t, h, "/api/audio/sidetone", body); code != http.StatusBadRequest {
+			t.Fatalf("%s: status %d, want 400", body, code)
+		}
+	}
+	if audio.sidetoneLevel != -25 {
+		t.Fatalf("rejected request changed the level to %v", audio.sidetoneLevel)
+	}
+}
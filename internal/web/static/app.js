@@ -6,22 +6,33 @@ let myName = '';
 let myID = '';
 let roomCode = '';
 let muted = false;
+let isHost = false; // created the room, so may delete any chat message
+let stereo = false; // the room carries stereo audio (music) instead of voice
+let redirects = 0; // room-directory redirects followed for the current join
 
 // ===== WebRTC State =====
 let pc = null;
 let localStream = null;
+let dataChannels = {}; // label ('data' or 'data-unordered') -> RTCDataChannel
 
 // ===== WebSocket =====
 
-function connect(onOpen) {
-  const proto = location.protocol === 'https:' ? 'wss:' : 'ws:';
-  ws = new WebSocket(`${proto}//${location.host}/ws`);
-
-  ws.addEventListener('open', () => {
+// connect opens the signaling socket on this page's server, or on the node at
+// baseURL (e.g. https://node2.example.com) when following a redirect.
+function connect(onOpen, baseURL) {
+  const base = new URL(baseURL || location.href);
+  const proto = base.protocol === 'https:' ? 'wss:' : 'ws:';
+  // A join token issued by an embedding application is passed through from the page URL.
+  const token = new URLSearchParams(location.search).get('token');
+  const query = token ? `?token=${encodeURIComponent(token)}` : '';
+  const socket = new WebSocket(`${proto}//${base.host}/ws${query}`);
+  ws = socket;
+
+  socket.addEventListener('open', () => {
     if (onOpen) onOpen();
   });
 
-  ws.addEventListener('message', (evt) => {
+  socket.addEventListener('message', (evt) => {
     let msg;
     try {
       msg = JSON.parse(evt.data);
@@ -31,14 +42,16 @@ function connect(onOpen) {
     handleMessage(msg);
   });
 
-  ws.addEventListener('close', () => {
+  socket.addEventListener('close', () => {
+    if (ws !== socket) return; // replaced after a redirect
     ws = null;
     if (roomCode) {
       showError('Connection lost. Please rejoin.');
     }
   });
 
-  ws.addEventListener('error', () => {
+  socket.addEventListener('error', () => {
+    if (ws !== socket) return;
     showError('WebSocket error. Check your connection.');
   });
 }
@@ -56,21 +69,31 @@ function send(type, payload) {
 function handleMessage(msg) {
   const p = msg.payload || {};
   switch (msg.type) {
+    case 'auth-ok':
+      if (p.name) myName = p.name;
+      break;
+
     case 'room-created':
       roomCode = p.code;
       myID = p.peerId;
+      isHost = true;
+      stereo = !!p.stereo;
       showScreen('screen-room');
       setRoomCode(p.code);
+      clearChat();
       break;
 
     case 'room-joined':
       roomCode = p.code;
       myID = p.peerId;
+      stereo = !!p.stereo;
       showScreen('screen-room');
-      setRoomCode(p.code);
+      setRoomCode(p.code, p.title);
       if (Array.isArray(p.peers)) {
         p.peers.forEach((peer) => addPeer(peer.id, peer.name, peer.muted));
       }
+      clearChat();
+      (p.chat || []).forEach(showChatMessage);
       break;
 
     case 'peer-joined':
@@ -85,6 +108,14 @@ function handleMessage(msg) {
       updatePeerMute(p.id, p.muted);
       break;
 
+    case 'chat-message':
+      showChatMessage(p);
+      break;
+
+    case 'chat-deleted':
+      removeChatMessage(p.id);
+      break;
+
     case 'offer':
       handleOffer(p);
       break;
@@ -93,6 +124,31 @@ function handleMessage(msg) {
       handleRemoteICECandidate(p);
       break;
 
+    case 'room-closed':
+      endSession(p.reason ? `The room was closed: ${p.reason}` : 'The room was closed.');
+      break;
+
+    case 'kicked':
+      endSession(p.reason ? `You were removed from the room: ${p.reason}` : 'You were removed from the room.');
+      break;
+
+    case 'server-shutdown':
+      endSession('The server is restarting. Please rejoin in a moment.');
+      if (p.reconnectUrl) {
+        setTimeout(() => { location.href = p.reconnectUrl; }, p.retryAfterMs || 0);
+      }
+      break;
+
+    case 'redirect':
+      // The room lives on another node; repeat the join there.
+      if (redirects++ >= 3) {
+        showError('Could not reach the server hosting this room.');
+        break;
+      }
+      ws.close();
+      connect(() => send('join-room', { name: myName, code: p.code }), p.url);
+      break;
+
     case 'error':
       showError(p.message || 'An unknown error occurred.');
       break;
@@ -110,7 +166,8 @@ function createRoom() {
     showError('Please enter your name.');
     return;
   }
-  connect(() => send('create-room', { name: myName }));
+  const wantStereo = document.getElementById('input-stereo').checked;
+  connect(() => send('create-room', { name: myName, stereo: wantStereo }));
 }
 
 function joinRoom() {
@@ -124,6 +181,8 @@ function joinRoom() {
     showError('Please enter a room code.');
     return;
   }
+  redirects = 0;
+  isHost = false;
   connect(() => send('join-room', { name: myName, code }));
 }
 
@@ -138,8 +197,25 @@ function leaveRoom() {
   myID = '';
   muted = false;
   clearPeerList();
+  clearChat();
+  resetMuteButton();
+  showScreen('screen-lobby');
+}
+
+/**
+ * Return to the lobby after the server ended our session (room closed or kicked).
+ * The server closes the WebSocket itself, so no leave message is sent.
+ */
+function endSession(reason) {
+  cleanupWebRTC();
+  roomCode = '';
+  myID = '';
+  muted = false;
+  clearPeerList();
+  clearChat();
   resetMuteButton();
   showScreen('screen-lobby');
+  showError(reason);
 }
 
 function toggleMute() {
@@ -176,6 +252,32 @@ const rtcConfig = {
   iceServers: [{ urls: 'stun:stun.l.google.com:19302' }],
 };
 
+/**
+ * Microphone constraints for the current room. Stereo rooms ask for two
+ * channels and turn off the browser's voice processing, which would
+ * downmix to mono and treat music as noise.
+ */
+function audioConstraints() {
+  if (!stereo) return true;
+  return {
+    channelCount: 2,
+    echoCancellation: false,
+    noiseSuppression: false,
+    autoGainControl: false,
+  };
+}
+
+/**
+ * Add stereo=1 to the Opus format parameters of an SDP, which browsers
+ * need in their own description before they play stereo.
+ */
+function withStereo(sdp) {
+  const m = sdp.match(/a=rtpmap:(\d+) opus\/48000\/2/i);
+  if (!m) return sdp;
+  return sdp.replace(new RegExp(`a=fmtp:${m[1]} (.*)`), (line, params) =>
+    /stereo=1/.test(params) ? line : `${line};stereo=1;sprop-stereo=1`);
+}
+
 /**
  * Ensure we have a PeerConnection and local microphone stream.
  * Re-uses the existing pc/localStream if they are still alive.
@@ -184,7 +286,7 @@ async function ensurePeerConnection() {
   // Acquire microphone if we haven't yet.
   if (!localStream) {
     try {
-      localStream = await navigator.mediaDevices.getUserMedia({ audio: true });
+      localStream = await navigator.mediaDevices.getUserMedia({ audio: audioConstraints() });
     } catch (err) {
       showError('Microphone access denied. Voice will not work.');
       console.error('getUserMedia error:', err);
@@ -218,12 +320,45 @@ async function ensurePeerConnection() {
       playRemoteStream(stream);
     };
 
+    // The SFU opens the data channels; their messages are re-dispatched on
+    // window as 'voxlink-data' events for the embedding page.
+    pc.ondatachannel = (event) => {
+      const channel = event.channel;
+      dataChannels[channel.label] = channel;
+      channel.onmessage = (evt) => {
+        let msg;
+        try {
+          msg = JSON.parse(evt.data);
+        } catch (_) {
+          return;
+        }
+        window.dispatchEvent(new CustomEvent('voxlink-data', {
+          detail: { from: msg.from, payload: msg.payload, ordered: channel.ordered },
+        }));
+      };
+    };
+
     pc.onconnectionstatechange = () => {
       console.log('WebRTC connection state:', pc.connectionState);
     };
   }
 }
 
+/**
+ * Send an application message (a reaction, a cursor position, game state)
+ * to the other peers in the room over the WebRTC data channel. `to` lists
+ * recipient peer IDs (default: everyone else); `ordered: false` trades
+ * reliability for latency. Returns false if the channel is not open yet.
+ */
+function sendData(payload, { to, ordered = true } = {}) {
+  const channel = dataChannels[ordered ? 'data' : 'data-unordered'];
+  if (!channel || channel.readyState !== 'open') return false;
+  channel.send(JSON.stringify(to && to.length ? { to, payload } : { payload }));
+  return true;
+}
+
+window.voxlink = { sendData };
+
 /**
  * Handle an SDP offer from the server.
  * Creates (or re-uses) a PeerConnection, sets the remote description,
@@ -238,6 +373,7 @@ async function handleOffer(payload) {
     await pc.setRemoteDescription(offer);
 
     const answer = await pc.createAnswer();
+    if (stereo) answer.sdp = withStereo(answer.sdp);
     await pc.setLocalDescription(answer);
 
     send('answer', { sdp: answer.sdp });
@@ -294,6 +430,7 @@ function cleanupWebRTC() {
     pc.close();
     pc = null;
   }
+  dataChannels = {};
   if (localStream) {
     localStream.getTracks().forEach((track) => track.stop());
     localStream = null;
@@ -359,6 +496,76 @@ function clearPeerList() {
   while (list.firstChild) list.removeChild(list.firstChild);
 }
 
+// ===== Chat DOM (safe — no innerHTML with user data) =====
+
+function sendChat() {
+  const input = document.getElementById('input-chat');
+  const text = input.value;
+  if (!text.trim()) return;
+  send('chat-send', { text });
+  input.value = '';
+}
+
+// showChatMessage appends a message, or replaces it in place after an edit.
+function showChatMessage(m) {
+  const log = document.getElementById('chat-log');
+  const li = document.createElement('li');
+  li.className = 'chat-msg';
+  li.dataset.chatId = m.id;
+  li.title = new Date(m.sentAt).toLocaleTimeString();
+
+  const author = document.createElement('span');
+  author.className = 'chat-author';
+  author.textContent = m.name;
+  const text = document.createElement('span');
+  text.textContent = m.text;
+  li.appendChild(author);
+  li.appendChild(text);
+
+  if (m.editedAt) {
+    const edited = document.createElement('span');
+    edited.className = 'chat-edited';
+    edited.textContent = '(edited)';
+    li.appendChild(edited);
+  }
+  if (m.peerId === myID) {
+    li.appendChild(chatAction('edit', () => {
+      const updated = prompt('Edit message', m.text);
+      if (updated !== null && updated.trim()) send('chat-edit', { id: m.id, text: updated });
+    }));
+  }
+  if (m.peerId === myID || isHost) {
+    li.appendChild(chatAction('delete', () => send('chat-delete', { id: m.id })));
+  }
+
+  const existing = log.querySelector(`[data-chat-id="${CSS.escape(m.id)}"]`);
+  if (existing) {
+    existing.replaceWith(li);
+  } else {
+    log.appendChild(li);
+    log.scrollTop = log.scrollHeight;
+  }
+}
+
+function chatAction(label, onClick) {
+  const btn = document.createElement('button');
+  btn.className = 'chat-action';
+  btn.textContent = label;
+  btn.addEventListener('click', onClick);
+  return btn;
+}
+
+function removeChatMessage(id) {
+  const log = document.getElementById('chat-log');
+  const existing = log.querySelector(`[data-chat-id="${CSS.escape(id)}"]`);
+  if (existing) existing.remove();
+}
+
+function clearChat() {
+  const log = document.getElementById('chat-log');
+  while (log.firstChild) log.removeChild(log.firstChild);
+}
+
 // ===== Screen Management =====
 
 function showScreen(id) {
@@ -367,8 +574,9 @@ function showScreen(id) {
   });
 }
 
-function setRoomCode(code) {
+function setRoomCode(code, title) {
   document.getElementById('display-code').textContent = code;
+  document.getElementById('room-label').textContent = title || 'Room';
 }
 
 // ===== Error Overlay =====
@@ -393,6 +601,10 @@ document.addEventListener('DOMContentLoaded', () => {
   document.getElementById('btn-mute').addEventListener('click', toggleMute);
   document.getElementById('btn-copy').addEventListener('click', copyCode);
   document.getElementById('btn-dismiss').addEventListener('click', dismissError);
+  document.getElementById('btn-chat').addEventListener('click', sendChat);
+  document.getElementById('input-chat').addEventListener('keydown', (e) => {
+    if (e.key === 'Enter') sendChat();
+  });
 
   // Allow pressing Enter in code input to trigger join
   document.getElementById('input-code').addEventListener('keydown', (e) => {